@@ -2,17 +2,23 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/wenka/backend/internal/config"
 	"github.com/wenka/backend/internal/database"
 	"github.com/wenka/backend/internal/handlers"
 	"github.com/wenka/backend/internal/middleware"
+	"github.com/wenka/backend/internal/models"
 	"github.com/wenka/backend/internal/repositories"
 	"github.com/wenka/backend/internal/services"
+	"github.com/wenka/backend/internal/utils"
 )
 
 func main() {
@@ -23,6 +29,11 @@ func main() {
 		log.Fatalf("Error al conectar a la base de datos: %v", err)
 	}
 
+	keys, err := loadKeys(cfg)
+	if err != nil {
+		log.Fatalf("Error al cargar las llaves JWT: %v", err)
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		log.Fatalf("Error al obtener SQL DB: %v", err)
@@ -31,36 +42,185 @@ func main() {
 
 	// Inicializar repositorios
 	userRepo := repositories.NewUserRepository(db)
-	appointmentRepo := repositories.NewAppointmentRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	resetRepo := repositories.NewPasswordResetRepository(db)
+	verificationRepo := repositories.NewEmailVerificationRepository(db)
+	attemptRepo := repositories.NewLoginAttemptRepository(db)
+	recoveryRepo := repositories.NewRecoveryCodeRepository(db)
+	patientRepo := repositories.NewPatientRepository(db)
+	reminderRepo := repositories.NewReminderRepository(db)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	scheduleRepo := repositories.NewScheduleRepository(db)
+	appointmentRepo := repositories.NewAppointmentRepository(db, scheduleRepo)
+
+	linkSecret, err := loadSecret(cfg, "LINK_SECRET", cfg.LinkSecret)
+	if err != nil {
+		log.Fatalf("Error al cargar el secreto de los enlaces: %v", err)
+	}
+
+	// Los enlaces de verificación usan su propio secreto: no comparten llave con los de citas
+	verifySecret, err := loadSecret(cfg, "EMAIL_VERIFY_SECRET", cfg.EmailVerifySecret)
+	if err != nil {
+		log.Fatalf("Error al cargar el secreto de verificación de email: %v", err)
+	}
+
+	// Solo se acepta X-Forwarded-For de estos proxies; sin lista se usa la IP de la conexión
+	trustedProxies, err := utils.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Error al cargar TRUSTED_PROXIES: %v", err)
+	}
+
+	mailer, err := loadMailer(cfg)
+	if err != nil {
+		log.Fatalf("Error al configurar el envío de emails: %v", err)
+	}
 
 	// Inicializar servicios
-	authService := services.NewAuthService(userRepo, cfg.JWTSecret)
-	emailService := services.NewEmailService()
-	appointmentService := services.NewAppointmentService(appointmentRepo, emailService)
+	emailService := services.NewEmailService(outboxRepo, mailer)
+	authService := services.NewAuthService(
+		userRepo,
+		sessionRepo,
+		resetRepo,
+		verificationRepo,
+		attemptRepo,
+		recoveryRepo,
+		emailService,
+		keys,
+		verifySecret,
+		time.Duration(cfg.AccessTokenTTLMin)*time.Minute,
+		time.Duration(cfg.RefreshTokenTTLDay)*24*time.Hour,
+		time.Duration(cfg.PasswordResetTTLMin)*time.Minute,
+		time.Duration(cfg.EmailVerifyTTLHours)*time.Hour,
+	)
+	appointmentService := services.NewAppointmentService(
+		appointmentRepo,
+		patientRepo,
+		scheduleRepo,
+		emailService,
+		services.ParseAssignmentStrategy(cfg.AssignmentStrategy),
+		linkSecret,
+		time.Duration(cfg.LinkTTLHours)*time.Hour,
+	)
+	scheduleService := services.NewScheduleService(scheduleRepo)
+	patientService := services.NewPatientService(patientRepo)
+	reminderScheduler := services.NewReminderScheduler(
+		reminderRepo,
+		appointmentRepo,
+		emailService,
+		cfg.ReminderOffsets,
+		time.Duration(cfg.ReminderIntervalSec)*time.Second,
+	)
+	emailOutbox := services.NewEmailOutbox(
+		outboxRepo,
+		emailService,
+		time.Duration(cfg.OutboxIntervalSec)*time.Second,
+		cfg.OutboxMaxAttempts,
+	)
 
 	// Inicializar handlers
-	authHandler := handlers.NewAuthHandler(authService, cfg.JWTSecret)
-	appointmentHandler := handlers.NewAppointmentHandler(appointmentService, cfg.JWTSecret)
+	authHandler := handlers.NewAuthHandler(authService, keys, trustedProxies)
+	appointmentHandler := handlers.NewAppointmentHandler(appointmentService, keys)
+	scheduleHandler := handlers.NewScheduleHandler(scheduleService, keys)
+	patientHandler := handlers.NewPatientHandler(patientService)
+	emailOutboxHandler := handlers.NewEmailOutboxHandler(emailOutbox)
 
 	// Configurar rutas
 	router := mux.NewRouter()
 
+	requireAuth := middleware.RequireAuth(keys, authService)
+	optionalAuth := middleware.OptionalAuth(keys, authService)
+	requireVerified := middleware.RequireVerifiedEmail(authService)
+
 	// Rutas de autenticación (públicas)
 	authRouter := router.PathPrefix("/api/auth").Subrouter()
 	authRouter.HandleFunc("/register", authHandler.Register).Methods("POST", "OPTIONS")
 	authRouter.HandleFunc("/login", authHandler.Login).Methods("POST", "OPTIONS")
-	authRouter.HandleFunc("/me", authHandler.Me).Methods("GET", "OPTIONS")
+	authRouter.HandleFunc("/refresh", authHandler.Refresh).Methods("POST", "OPTIONS")
+	authRouter.HandleFunc("/forgot-password", authHandler.ForgotPassword).Methods("POST", "OPTIONS")
+	authRouter.HandleFunc("/reset-password", authHandler.ResetPassword).Methods("POST", "OPTIONS")
+	authRouter.HandleFunc("/verify-email", authHandler.VerifyEmail).Methods("POST", "OPTIONS")
+
+	// Rutas de la sesión actual (requieren autenticación)
+	authRouter.Handle("/me", requireAuth(http.HandlerFunc(authHandler.Me))).Methods("GET", "OPTIONS")
+	authRouter.Handle("/me", requireAuth(http.HandlerFunc(authHandler.UpdateMe))).Methods("PATCH")
+	authRouter.Handle("/me", requireAuth(http.HandlerFunc(authHandler.DeleteMe))).Methods("DELETE")
+	authRouter.Handle("/change-password", requireAuth(http.HandlerFunc(authHandler.ChangePassword))).Methods("POST", "OPTIONS")
+	authRouter.Handle("/logout", requireAuth(http.HandlerFunc(authHandler.Logout))).Methods("POST", "OPTIONS")
+	authRouter.Handle("/logout-all", requireAuth(http.HandlerFunc(authHandler.LogoutAll))).Methods("POST", "OPTIONS")
+	authRouter.Handle("/sessions", requireAuth(http.HandlerFunc(authHandler.Sessions))).Methods("GET", "OPTIONS")
+	authRouter.Handle("/resend-verification", requireAuth(http.HandlerFunc(authHandler.ResendVerification))).Methods("POST", "OPTIONS")
+
+	// Verificación en dos pasos. setup y enable aceptan la sesión o, cuando el personal la
+	// activa durante el login, el token parcial (mfa_token) que entregó la contraseña
+	authRouter.HandleFunc("/2fa/verify", authHandler.VerifyMFA).Methods("POST", "OPTIONS")
+	authRouter.Handle("/2fa/setup", optionalAuth(http.HandlerFunc(authHandler.SetupMFA))).Methods("POST", "OPTIONS")
+	authRouter.Handle("/2fa/enable", optionalAuth(http.HandlerFunc(authHandler.EnableMFA))).Methods("POST", "OPTIONS")
+	authRouter.Handle("/2fa/disable", requireAuth(http.HandlerFunc(authHandler.DisableMFA))).Methods("POST", "OPTIONS")
 
-	// RUTA PÚBLICA para confirmar cita (especialistas desde email)
+	// RUTA PÚBLICA para confirmar cita (especialistas desde email). El enlace abre la página del
+	// frontend, que envía el token firmado por POST: un GET no debe cambiar el estado de la cita
 	// IMPORTANTE: Esta debe ir ANTES de las rutas protegidas
-	router.HandleFunc("/api/appointments/{id:[0-9]+}/confirm", appointmentHandler.ConfirmAppointment).Methods("GET", "POST", "PUT", "OPTIONS")
+	router.HandleFunc("/api/appointments/{id:[0-9]+}/confirm", appointmentHandler.ConfirmAppointment).Methods("POST", "PUT", "OPTIONS")
+
+	// RUTAS PÚBLICAS con enlace firmado: el especialista rechaza o propone otros horarios
+	// y el paciente acepta uno de los horarios propuestos. Los enlaces del email abren páginas
+	// del frontend que envían el token por POST
+	router.HandleFunc("/api/appointments/{id:[0-9]+}/respond", appointmentHandler.RespondToAppointment).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/appointments/{id:[0-9]+}/proposals/{pid:[0-9]+}/accept", appointmentHandler.AcceptProposal).Methods("POST", "OPTIONS")
+
+	// Horarios disponibles por especialista (pública, no expone datos de pacientes)
+	router.HandleFunc("/api/availability", appointmentHandler.GetAvailability).Methods("GET", "OPTIONS")
+
+	staff := middleware.RequireRole(models.RolEspecialista, models.RolRecepcion, models.RolAdmin)
+	clinicians := middleware.RequireRole(models.RolEspecialista, models.RolAdmin)
 
 	// Rutas de citas (requieren autenticación)
 	appointmentsRouter := router.PathPrefix("/api/appointments").Subrouter()
+	appointmentsRouter.Use(requireAuth)
 	appointmentsRouter.HandleFunc("", appointmentHandler.GetAppointments).Methods("GET", "OPTIONS")
-	appointmentsRouter.HandleFunc("", appointmentHandler.CreateAppointment).Methods("POST", "OPTIONS")
+	appointmentsRouter.Handle("", requireVerified(http.HandlerFunc(appointmentHandler.CreateAppointment))).Methods("POST", "OPTIONS")
 	appointmentsRouter.HandleFunc("/{id:[0-9]+}", appointmentHandler.GetAppointmentByID).Methods("GET", "OPTIONS")
 	appointmentsRouter.HandleFunc("/{id:[0-9]+}", appointmentHandler.CancelAppointment).Methods("DELETE", "OPTIONS")
+	appointmentsRouter.HandleFunc("/{id:[0-9]+}/reschedule", appointmentHandler.RescheduleAppointment).Methods("PATCH", "OPTIONS")
+	appointmentsRouter.Handle("/{id:[0-9]+}/history", staff(http.HandlerFunc(appointmentHandler.GetAppointmentHistory))).Methods("GET", "OPTIONS")
+
+	// Acciones del personal durante la consulta; un especialista solo sobre sus propias citas
+	appointmentsRouter.Handle("/{id:[0-9]+}/check-in", staff(http.HandlerFunc(appointmentHandler.CheckInAppointment))).Methods("POST", "OPTIONS")
+	appointmentsRouter.Handle("/{id:[0-9]+}/complete", clinicians(http.HandlerFunc(appointmentHandler.CompleteAppointment))).Methods("POST", "OPTIONS")
+	appointmentsRouter.Handle("/{id:[0-9]+}/no-show", staff(http.HandlerFunc(appointmentHandler.MarkNoShow))).Methods("POST", "OPTIONS")
+
+	// Expedientes de la cuenta: el propio y los de sus dependientes (hijos, padres...). Requiere
+	// email verificado porque la cuenta adopta el expediente que recepción registró con su email
+	patientsRouter := router.PathPrefix("/api/patients").Subrouter()
+	patientsRouter.Use(requireAuth, requireVerified, middleware.RequireRole(models.RolPaciente))
+	patientsRouter.HandleFunc("", patientHandler.ListPatients).Methods("GET", "OPTIONS")
+	patientsRouter.HandleFunc("", patientHandler.CreatePatient).Methods("POST", "OPTIONS")
+	patientsRouter.HandleFunc("/{id:[0-9]+}", patientHandler.UpdatePatient).Methods("PUT", "OPTIONS")
+	patientsRouter.HandleFunc("/{id:[0-9]+}", patientHandler.DeletePatient).Methods("DELETE", "OPTIONS")
+
+	// Rutas de administración (solo administradores y recepción)
+	adminRouter := router.PathPrefix("/api/admin").Subrouter()
+	adminRouter.Use(requireAuth, middleware.RequireRole(models.RolAdmin, models.RolRecepcion))
+
+	// Vacaciones, incapacidades y días feriados
+	adminRouter.HandleFunc("/time-off", scheduleHandler.ListTimeOff).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/time-off", scheduleHandler.CreateTimeOff).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/time-off/{id:[0-9]+}", scheduleHandler.UpdateTimeOff).Methods("PUT", "OPTIONS")
+	adminRouter.HandleFunc("/time-off/{id:[0-9]+}", scheduleHandler.DeleteTimeOff).Methods("DELETE", "OPTIONS")
+
+	// Roles de usuario (solo administradores)
+	adminRouter.Handle("/users/{id:[0-9]+}/role", middleware.RequireRole(models.RolAdmin)(http.HandlerFunc(authHandler.UpdateUserRole))).Methods("PUT", "OPTIONS")
+
+	// Desbloqueo de cuentas bloqueadas por intentos fallidos de inicio de sesión (solo administradores)
+	adminOnly := middleware.RequireRole(models.RolAdmin)
+	adminRouter.Handle("/users/{id:[0-9]+}/unlock", adminOnly(http.HandlerFunc(authHandler.UnlockUser))).Methods("POST", "OPTIONS")
+
+	// Bandeja de salida de emails: revisar y reenviar los fallidos (solo administradores)
+	adminRouter.Handle("/emails", adminOnly(http.HandlerFunc(emailOutboxHandler.ListEmails))).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/emails/{id:[0-9]+}/resend", adminOnly(http.HandlerFunc(emailOutboxHandler.ResendEmail))).Methods("POST", "OPTIONS")
+
+	// Llaves públicas para que otros servicios validen los tokens de acceso
+	router.HandleFunc("/.well-known/jwks.json", authHandler.JWKS).Methods("GET", "OPTIONS")
 
 	// Health check
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -75,18 +235,67 @@ func main() {
 	serverAddr := ":" + cfg.ServerPort
 
 	// Obtener variables de entorno directamente
-	smtpUser := os.Getenv("SMTP_USERNAME")
-	if smtpUser == "" {
-		smtpUser = "modo desarrollo"
-	}
-
 	frontendURL := os.Getenv("FRONTEND_URL")
 	if frontendURL == "" {
 		frontendURL = "http://localhost:3000"
 	}
 
+	// Recordatorios de citas y entrega de emails en segundo plano
+	go reminderScheduler.Start(context.Background())
+	go emailOutbox.Start(context.Background())
+
 	log.Printf(" Servidor iniciado en http://localhost%s", serverAddr)
-	log.Printf(" Email configurado para: %s", smtpUser)
 	log.Printf(" Frontend URL: %s", frontendURL)
 	log.Fatal(http.ListenAndServe(serverAddr, handler))
 }
+
+// loadMailer crea el transporte de emails: SMTP o, en desarrollo, un Maildir local donde se
+// pueden revisar los mensajes completos
+func loadMailer(cfg *config.Config) (services.Mailer, error) {
+	switch cfg.MailTransport {
+	case "smtp":
+		log.Printf(" Emails por SMTP (%s) en %s:%s como %s", cfg.SMTPSecurity, cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername)
+		return services.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPSecurity)
+	case "maildir":
+		log.Printf(" Emails guardados en el Maildir %s", cfg.MailDir)
+		return services.NewMaildirMailer(cfg.MailDir)
+	default:
+		return nil, fmt.Errorf("MAIL_TRANSPORT inválido: %q. Opciones: smtp, maildir", cfg.MailTransport)
+	}
+}
+
+// minSecretLength longitud mínima de un secreto de firma fuera de desarrollo
+const minSecretLength = 32
+
+// loadSecret valida un secreto HMAC de la configuración. Fuera de desarrollo es obligatorio:
+// un valor por omisión conocido permitiría falsificar enlaces. En desarrollo, si falta, se
+// genera uno por proceso y los enlaces emitidos dejan de valer al reiniciar
+func loadSecret(cfg *config.Config, name, value string) (string, error) {
+	if value == "" && cfg.IsDevelopment() {
+		log.Printf(" %s no configurado; se usa un secreto temporal de desarrollo", name)
+		return utils.GenerateSecret()
+	}
+
+	if value == "" {
+		return "", fmt.Errorf("%s es obligatorio fuera de desarrollo", name)
+	}
+
+	if len(value) < minSecretLength && !cfg.IsDevelopment() {
+		return "", fmt.Errorf("%s debe tener al menos %d caracteres", name, minSecretLength)
+	}
+
+	return value, nil
+}
+
+// loadKeys carga la llave de firma de los tokens y las llaves anteriores que siguen siendo
+// válidas durante una rotación. En desarrollo genera una llave si no existe
+func loadKeys(cfg *config.Config) (*utils.KeySet, error) {
+	if _, err := os.Stat(cfg.JWTPrivateKeyFile); errors.Is(err, os.ErrNotExist) && cfg.IsDevelopment() {
+		log.Printf(" Generando llave JWT de desarrollo en %s", cfg.JWTPrivateKeyFile)
+		if err := utils.GenerateDevKey(cfg.JWTPrivateKeyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return utils.LoadKeySet(cfg.JWTPrivateKeyFile, cfg.JWTPublicKeyFiles)
+}
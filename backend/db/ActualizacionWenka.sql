@@ -0,0 +1,114 @@
+-- =====================================================
+-- CLINICA WENKA - ACTUALIZACION DE UNA BASE EXISTENTE
+-- =====================================================
+-- TablasWenka.sql usa CREATE TABLE IF NOT EXISTS: en una base creada con una versión
+-- anterior no agrega las columnas nuevas de las tablas que ya existen. Este script las
+-- agrega y puede ejecutarse varias veces. Orden:
+--   mysql wenka_db < ActualizacionWenka.sql
+--   mysql wenka_db < TablasWenka.sql   (crea las tablas nuevas)
+-- =====================================================
+
+DROP PROCEDURE IF EXISTS wenka_agregar_columna;
+DROP PROCEDURE IF EXISTS wenka_agregar_indice;
+DROP PROCEDURE IF EXISTS wenka_agregar_fk;
+
+DELIMITER //
+
+-- Agrega una columna si la tabla todavía no la tiene
+CREATE PROCEDURE wenka_agregar_columna(IN tabla VARCHAR(64), IN columna VARCHAR(64), IN definicion TEXT)
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.COLUMNS
+                   WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = tabla AND COLUMN_NAME = columna) THEN
+        SET @sql = CONCAT('ALTER TABLE ', tabla, ' ADD COLUMN ', columna, ' ', definicion);
+        PREPARE stmt FROM @sql;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END //
+
+-- Agrega un índice si la tabla todavía no lo tiene
+CREATE PROCEDURE wenka_agregar_indice(IN tabla VARCHAR(64), IN indice VARCHAR(64), IN definicion TEXT)
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.STATISTICS
+                   WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = tabla AND INDEX_NAME = indice) THEN
+        SET @sql = CONCAT('ALTER TABLE ', tabla, ' ADD INDEX ', indice, ' ', definicion);
+        PREPARE stmt FROM @sql;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END //
+
+-- Agrega una llave foránea si la tabla todavía no la tiene
+CREATE PROCEDURE wenka_agregar_fk(IN tabla VARCHAR(64), IN nombre VARCHAR(64), IN definicion TEXT)
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.TABLE_CONSTRAINTS
+                   WHERE CONSTRAINT_SCHEMA = DATABASE() AND TABLE_NAME = tabla
+                     AND CONSTRAINT_NAME = nombre AND CONSTRAINT_TYPE = 'FOREIGN KEY') THEN
+        SET @sql = CONCAT('ALTER TABLE ', tabla, ' ADD CONSTRAINT ', nombre, ' FOREIGN KEY ', definicion);
+        PREPARE stmt FROM @sql;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END //
+
+DELIMITER ;
+
+-- =====================================================
+-- 1. ROLES DE USUARIO
+-- =====================================================
+-- Las cuentas existentes quedan como paciente; el personal se asigna desde
+-- PUT /api/admin/users/{id}/role
+CALL wenka_agregar_columna('usuarios', 'rol',
+    "VARCHAR(20) NOT NULL DEFAULT 'paciente' CHECK (rol IN ('paciente', 'especialista', 'recepcion', 'admin'))");
+CALL wenka_agregar_columna('usuarios', 'especialista_id', 'INT NULL UNIQUE');
+CALL wenka_agregar_indice('usuarios', 'idx_rol', '(rol)');
+CALL wenka_agregar_fk('usuarios', 'fk_usuarios_especialista',
+    '(especialista_id) REFERENCES especialistas(id) ON DELETE SET NULL');
+
+-- =====================================================
+-- 2. VERIFICACION DE EMAIL
+-- =====================================================
+-- Las cuentas existentes quedan sin verificar y deben verificarse con
+-- POST /api/auth/resend-verification antes de agendar citas
+CALL wenka_agregar_columna('usuarios', 'email_verificado', 'BOOLEAN NOT NULL DEFAULT FALSE');
+
+-- =====================================================
+-- 3. VERIFICACION EN DOS PASOS (TOTP)
+-- =====================================================
+-- Las cuentas existentes quedan sin segundo factor; el personal debe activarlo
+-- en su próximo inicio de sesión
+CALL wenka_agregar_columna('usuarios', 'totp_secreto', 'VARCHAR(64) NULL');
+CALL wenka_agregar_columna('usuarios', 'totp_activo', 'BOOLEAN NOT NULL DEFAULT FALSE');
+CALL wenka_agregar_columna('usuarios', 'totp_ultimo_paso', 'BIGINT NOT NULL DEFAULT 0');
+
+-- =====================================================
+-- 4. PACIENTES DEPENDIENTES
+-- =====================================================
+-- Cada cuenta apunta a su propio expediente y cada expediente a la cuenta que lo administra
+CALL wenka_agregar_columna('usuarios', 'paciente_id', 'INT NULL UNIQUE');
+CALL wenka_agregar_columna('pacientes', 'usuario_id', 'INT NULL');
+CALL wenka_agregar_columna('pacientes', 'parentesco',
+    "VARCHAR(20) NOT NULL DEFAULT 'titular' CHECK (parentesco IN ('titular', 'hijo', 'padre', 'madre', 'pareja', 'otro'))");
+CALL wenka_agregar_indice('pacientes', 'idx_usuario', '(usuario_id)');
+CALL wenka_agregar_fk('usuarios', 'fk_usuarios_paciente',
+    '(paciente_id) REFERENCES pacientes(id) ON DELETE SET NULL');
+CALL wenka_agregar_fk('pacientes', 'fk_pacientes_usuario',
+    '(usuario_id) REFERENCES usuarios(id) ON DELETE SET NULL');
+
+-- Antes las citas se ligaban a la cuenta comparando emails; liga cada cuenta con el
+-- expediente de su mismo email
+UPDATE usuarios u
+JOIN pacientes p ON p.email = u.email AND p.usuario_id IS NULL
+SET u.paciente_id = p.id, p.usuario_id = u.id, p.parentesco = 'titular'
+WHERE u.paciente_id IS NULL;
+
+-- =====================================================
+-- 5. IDIOMA DE LOS EMAILS
+-- =====================================================
+-- Las cuentas existentes reciben los emails en español hasta que elijan otro idioma
+CALL wenka_agregar_columna('usuarios', 'idioma',
+    "VARCHAR(5) NOT NULL DEFAULT 'es' CHECK (idioma IN ('es', 'en'))");
+
+DROP PROCEDURE wenka_agregar_columna;
+DROP PROCEDURE wenka_agregar_indice;
+DROP PROCEDURE wenka_agregar_fk;
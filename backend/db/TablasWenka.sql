@@ -2,6 +2,8 @@
 -- CLINICA WENKA - DATABASE SCHEMA
 -- =====================================================
 -- Fixed and optimized version - MySQL 8.0 compatible
+-- Crea una base nueva y puede ejecutarse de nuevo sin errores. Una base creada con una
+-- versión anterior necesita primero ActualizacionWenka.sql
 -- =====================================================
 
 -- =====================================================
@@ -14,9 +16,20 @@ CREATE TABLE IF NOT EXISTS usuarios (
     email VARCHAR(100) NOT NULL UNIQUE,
     password VARCHAR(255) NOT NULL,
     telefono VARCHAR(20),
+    rol VARCHAR(20) NOT NULL DEFAULT 'paciente'
+        CHECK (rol IN ('paciente', 'especialista', 'recepcion', 'admin')),
+    especialista_id INT NULL UNIQUE,
+    paciente_id INT NULL UNIQUE,
+    email_verificado BOOLEAN NOT NULL DEFAULT FALSE,
+    idioma VARCHAR(5) NOT NULL DEFAULT 'es'
+        CHECK (idioma IN ('es', 'en')),
+    totp_secreto VARCHAR(64) NULL,
+    totp_activo BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_ultimo_paso BIGINT NOT NULL DEFAULT 0,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    INDEX idx_email (email)
+    INDEX idx_email (email),
+    INDEX idx_rol (rol)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- =====================================================
@@ -52,6 +65,19 @@ CREATE TABLE IF NOT EXISTS especialistas (
     INDEX idx_activo (activo)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- Cuentas de usuario con rol especialista vinculadas a su registro. usuarios se crea antes
+-- que especialistas, por eso la llave se agrega aquí; solo si no existe, para que el script
+-- pueda ejecutarse de nuevo
+SET @existe_fk := (SELECT COUNT(*) FROM information_schema.TABLE_CONSTRAINTS
+    WHERE CONSTRAINT_SCHEMA = DATABASE() AND TABLE_NAME = 'usuarios'
+      AND CONSTRAINT_NAME = 'fk_usuarios_especialista');
+SET @sql := IF(@existe_fk = 0,
+    'ALTER TABLE usuarios ADD CONSTRAINT fk_usuarios_especialista FOREIGN KEY (especialista_id) REFERENCES especialistas(id) ON DELETE SET NULL',
+    'DO 0');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
 -- =====================================================
 -- 4. TABLA: PACIENTES
 -- =====================================================
@@ -72,13 +98,30 @@ CREATE TABLE IF NOT EXISTS pacientes (
     enfermedades_cronicas TEXT,
     contacto_emergencia_nombre VARCHAR(150),
     contacto_emergencia_telefono VARCHAR(20),
+    usuario_id INT NULL,
+    parentesco VARCHAR(20) NOT NULL DEFAULT 'titular'
+        CHECK (parentesco IN ('titular', 'hijo', 'padre', 'madre', 'pareja', 'otro')),
     activo BOOLEAN DEFAULT TRUE,
     fecha_registro TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_pacientes_usuario FOREIGN KEY (usuario_id) REFERENCES usuarios(id) ON DELETE SET NULL,
     INDEX idx_email (email),
     INDEX idx_telefono (telefono),
+    INDEX idx_usuario (usuario_id),
     INDEX idx_activo (activo)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- Expediente propio de cada cuenta; los dependientes (hijos, padres...) solo se ligan
+-- a la cuenta que los administra mediante pacientes.usuario_id
+SET @existe_fk := (SELECT COUNT(*) FROM information_schema.TABLE_CONSTRAINTS
+    WHERE CONSTRAINT_SCHEMA = DATABASE() AND TABLE_NAME = 'usuarios'
+      AND CONSTRAINT_NAME = 'fk_usuarios_paciente');
+SET @sql := IF(@existe_fk = 0,
+    'ALTER TABLE usuarios ADD CONSTRAINT fk_usuarios_paciente FOREIGN KEY (paciente_id) REFERENCES pacientes(id) ON DELETE SET NULL',
+    'DO 0');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
 -- =====================================================
 -- 5. TABLA: TRATAMIENTOS
 -- =====================================================
@@ -171,3 +214,217 @@ CREATE TABLE IF NOT EXISTS pagos (
     INDEX idx_estado (estado)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- =====================================================
+-- 9. TABLA: HORARIOS_ESPECIALISTAS
+-- =====================================================
+-- dia_semana: 0 = domingo ... 6 = sabado
+CREATE TABLE IF NOT EXISTS horarios_especialistas (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    especialista_id INT NOT NULL,
+    dia_semana TINYINT NOT NULL CHECK (dia_semana BETWEEN 0 AND 6),
+    hora_inicio TIME NOT NULL,
+    hora_fin TIME NOT NULL,
+    descanso_inicio TIME,
+    descanso_fin TIME,
+    activo BOOLEAN DEFAULT TRUE,
+    FOREIGN KEY (especialista_id) REFERENCES especialistas(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_especialista_dia (especialista_id, dia_semana),
+    INDEX idx_activo (activo)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 10. TABLA: BLOQUEOS_AGENDA
+-- =====================================================
+-- especialista_id NULL = cierre de toda la clinica
+CREATE TABLE IF NOT EXISTS bloqueos_agenda (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    especialista_id INT NULL,
+    tipo VARCHAR(20) NOT NULL
+        CHECK (tipo IN ('vacaciones', 'incapacidad', 'feriado', 'cierre', 'otro')),
+    fecha_inicio DATETIME NOT NULL,
+    fecha_fin DATETIME NOT NULL,
+    motivo VARCHAR(255),
+    fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (especialista_id) REFERENCES especialistas(id) ON DELETE CASCADE,
+    INDEX idx_especialista (especialista_id),
+    INDEX idx_rango (fecha_inicio, fecha_fin)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 11. TABLA: CITAS_CAMBIOS_ESTADO
+-- =====================================================
+CREATE TABLE IF NOT EXISTS citas_cambios_estado (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cita_id INT NOT NULL,
+    estado_anterior VARCHAR(20) NOT NULL,
+    estado_nuevo VARCHAR(20) NOT NULL,
+    actor VARCHAR(20) NOT NULL,
+    usuario_id INT NULL,
+    notas TEXT,
+    fecha TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cita_id) REFERENCES citas(id) ON DELETE CASCADE,
+    FOREIGN KEY (usuario_id) REFERENCES usuarios(id) ON DELETE SET NULL,
+    INDEX idx_cita (cita_id),
+    INDEX idx_fecha (fecha)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 12. TABLA: ENLACES_CITA
+-- =====================================================
+-- Enlaces firmados enviados por email; usado_en marca el uso unico
+CREATE TABLE IF NOT EXISTS enlaces_cita (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    jti VARCHAR(64) NOT NULL UNIQUE,
+    cita_id INT NOT NULL,
+    especialista_id INT NOT NULL,
+    accion VARCHAR(20) NOT NULL,
+    propuesta_id INT NULL,
+    expira_en DATETIME NOT NULL,
+    usado_en DATETIME NULL,
+    fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cita_id) REFERENCES citas(id) ON DELETE CASCADE,
+    INDEX idx_cita (cita_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 13. TABLA: PROPUESTAS_HORARIO
+-- =====================================================
+-- Horarios alternativos que el especialista ofrece al paciente
+CREATE TABLE IF NOT EXISTS propuestas_horario (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cita_id INT NOT NULL,
+    fecha_hora DATETIME NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+        CHECK (estado IN ('pendiente', 'aceptada', 'descartada')),
+    fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cita_id) REFERENCES citas(id) ON DELETE CASCADE,
+    INDEX idx_cita_estado (cita_id, estado)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+
+-- =====================================================
+-- 14. TABLA: SESIONES
+-- =====================================================
+-- Refresh tokens emitidos; cada rotación agrega una fila a la misma familia (sesión)
+CREATE TABLE IF NOT EXISTS sesiones (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    usuario_id INT NOT NULL,
+    familia_id VARCHAR(64) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    dispositivo VARCHAR(255) NULL,
+    ip VARCHAR(45) NULL,
+    expira_en DATETIME NOT NULL,
+    usado_en DATETIME NULL,
+    revocado_en DATETIME NULL,
+    fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (usuario_id) REFERENCES usuarios(id) ON DELETE CASCADE,
+    INDEX idx_familia (familia_id),
+    INDEX idx_usuario (usuario_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 15. TABLA: RESTABLECIMIENTOS_PASSWORD
+-- =====================================================
+-- Tokens de un solo uso para restablecer la contraseña; solo se guarda su hash
+CREATE TABLE IF NOT EXISTS restablecimientos_password (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    usuario_id INT NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expira_en DATETIME NOT NULL,
+    usado_en DATETIME NULL,
+    fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (usuario_id) REFERENCES usuarios(id) ON DELETE CASCADE,
+    INDEX idx_usuario (usuario_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 16. TABLA: ENVIOS_VERIFICACION
+-- =====================================================
+-- Emails de verificación enviados; limita los reenvíos por usuario
+CREATE TABLE IF NOT EXISTS envios_verificacion (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    usuario_id INT NOT NULL,
+    fecha_envio TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (usuario_id) REFERENCES usuarios(id) ON DELETE CASCADE,
+    INDEX idx_usuario_fecha (usuario_id, fecha_envio)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 17. TABLA: INTENTOS_LOGIN
+-- =====================================================
+-- Intentos fallidos de inicio de sesión por email e IP; se limpian al entrar o desbloquear
+CREATE TABLE IF NOT EXISTS intentos_login (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    ip VARCHAR(45) NULL,
+    fecha TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_email_fecha (email, fecha),
+    INDEX idx_ip_fecha (ip, fecha)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 18. TABLA: CODIGOS_RECUPERACION
+-- =====================================================
+-- Códigos de un solo uso para la verificación en dos pasos; solo se guarda su hash
+CREATE TABLE IF NOT EXISTS codigos_recuperacion (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    usuario_id INT NOT NULL,
+    codigo_hash CHAR(64) NOT NULL,
+    usado_en DATETIME NULL,
+    fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (usuario_id) REFERENCES usuarios(id) ON DELETE CASCADE,
+    INDEX idx_usuario_codigo (usuario_id, codigo_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 19. TABLA: RECORDATORIOS_CITA
+-- =====================================================
+-- Recordatorios enviados antes de cada cita. La clave única permite que una sola réplica
+-- reclame cada envío; fecha_cita distingue los recordatorios de una cita reagendada
+CREATE TABLE IF NOT EXISTS recordatorios_cita (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cita_id INT NOT NULL,
+    anticipacion_minutos INT NOT NULL,
+    fecha_cita DATETIME NOT NULL,
+    reclamado_en DATETIME NOT NULL,
+    enviado_en DATETIME NULL,
+    FOREIGN KEY (cita_id) REFERENCES citas(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_recordatorio (cita_id, anticipacion_minutos, fecha_cita)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 20. TABLA: EMAILS_SALIENTES
+-- =====================================================
+-- Bandeja de salida: los emails se guardan en la misma transacción que el cambio que los
+-- origina y un proceso en segundo plano los entrega con reintentos. Los que agotan los
+-- intentos quedan como fallidos para revisarlos y reenviarlos
+CREATE TABLE IF NOT EXISTS emails_salientes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    destinatario VARCHAR(255) NOT NULL,
+    asunto VARCHAR(255) NOT NULL,
+    cuerpo MEDIUMTEXT NOT NULL,
+    cuerpo_texto MEDIUMTEXT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+        CHECK (estado IN ('pendiente', 'enviado', 'fallido')),
+    intentos INT NOT NULL DEFAULT 0,
+    proximo_intento DATETIME NOT NULL,
+    reclamado_hasta DATETIME NULL,
+    ultimo_error TEXT NULL,
+    fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    enviado_en DATETIME NULL,
+    INDEX idx_emails_pendientes (estado, proximo_intento)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- =====================================================
+-- 21. TABLA: EMAILS_ADJUNTOS
+-- =====================================================
+-- Archivos adjuntos de los emails de la bandeja de salida, como las invitaciones de
+-- calendario (.ics) de las citas
+CREATE TABLE IF NOT EXISTS emails_adjuntos (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    email_id INT NOT NULL,
+    nombre VARCHAR(255) NOT NULL,
+    tipo_contenido VARCHAR(255) NOT NULL,
+    contenido MEDIUMBLOB NOT NULL,
+    FOREIGN KEY (email_id) REFERENCES emails_salientes(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -20,6 +20,23 @@ INSERT INTO especialistas (nombre, apellido_paterno, apellido_materno, especiali
     ('Emiliano', 'Hernández', 'Jaramillo', 5, '1612067', '5555678901', 'uyo577292@gmail.com', TRUE),
     ('José', 'Hernández', 'Ramírez', 6, '2003478', '5556789012', 'ramosv.ed.3iv12@gmail.com', TRUE);
 
+-- Insertar horarios de especialistas (lunes a viernes con descanso de comida, sábado medio día)
+INSERT INTO horarios_especialistas (especialista_id, dia_semana, hora_inicio, hora_fin, descanso_inicio, descanso_fin)
+SELECT e.id, d.dia, '09:00:00', '18:00:00', '14:00:00', '15:00:00'
+FROM especialistas e
+CROSS JOIN (SELECT 1 AS dia UNION ALL SELECT 2 UNION ALL SELECT 3 UNION ALL SELECT 4 UNION ALL SELECT 5) d;
+
+INSERT INTO horarios_especialistas (especialista_id, dia_semana, hora_inicio, hora_fin)
+SELECT e.id, 6, '09:00:00', '14:00:00' FROM especialistas e;
+
+-- Roles: todo registro nuevo es paciente. Para dar de alta al primer administrador:
+--   UPDATE usuarios SET rol = 'admin', email_verificado = TRUE WHERE email = 'admin@wenka.com';
+-- Después el administrador asigna roles con PUT /api/admin/users/{id}/role;
+-- las cuentas de especialista se vinculan con su fila de especialistas (especialista_id),
+-- el email de la cuenta no basta para actuar sobre las citas.
+-- El personal (especialista, recepcion, admin) debe activar la verificación en dos pasos
+-- en su siguiente inicio de sesión
+
 -- Insertar pacientes de ejemplo
 INSERT INTO pacientes (nombre, apellido_paterno, apellido_materno, fecha_nacimiento, sexo, telefono, email, direccion, ciudad, codigo_postal, tipo_sangre, contacto_emergencia_nombre, contacto_emergencia_telefono) VALUES 
     ('Juan', 'Pérez', 'Ramírez', '1985-03-15', 'M', '5559876543', 'juan.perez@email.com', 'Av. Insurgentes 123', 'Ciudad de México', '06700', 'O+', 'María Pérez', '5559876544'),
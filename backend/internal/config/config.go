@@ -4,30 +4,97 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	JWTSecret  string
-	ServerPort string
+	DBHost              string
+	DBPort              string
+	DBUser              string
+	DBPassword          string
+	DBName              string
+	Environment         string
+	JWTPrivateKeyFile   string
+	JWTPublicKeyFiles   []string
+	ServerPort          string
+	TrustedProxies      []string
+	AssignmentStrategy  string
+	LinkSecret          string
+	LinkTTLHours        int
+	AccessTokenTTLMin   int
+	RefreshTokenTTLDay  int
+	PasswordResetTTLMin int
+	EmailVerifySecret   string
+	EmailVerifyTTLHours int
+	ReminderOffsets     []time.Duration
+	ReminderIntervalSec int
+	OutboxIntervalSec   int
+	OutboxMaxAttempts   int
+	MailTransport       string
+	MailDir             string
+	SMTPHost            string
+	SMTPPort            string
+	SMTPUsername        string
+	SMTPPassword        string
+	SMTPSecurity        string
 }
 
 func LoadConfig() *Config {
+	smtpUsername := getEnv("SMTP_USERNAME", "")
+	smtpPort := getEnv("SMTP_PORT", "587")
+
+	// Sin credenciales SMTP los emails se guardan en un Maildir local
+	mailTransport := "smtp"
+	if smtpUsername == "" {
+		mailTransport = "maildir"
+	}
+
+	// El puerto 465 usa TLS implícito; el resto STARTTLS
+	smtpSecurity := "starttls"
+	if smtpPort == "465" {
+		smtpSecurity = "tls"
+	}
+
 	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "3306"),
-		DBUser:     getEnv("DB_USER", "wenka_user"),
-		DBPassword: getEnv("DB_PASSWORD", "wenka_secret"),
-		DBName:     getEnv("DB_NAME", "wenka_db"),
-		JWTSecret:  getEnv("JWT_SECRET", "secreto-clinica-wenka-dev"),
-		ServerPort: getEnv("SERVER_PORT", "8080"),
+		DBHost:              getEnv("DB_HOST", "localhost"),
+		DBPort:              getEnv("DB_PORT", "3306"),
+		DBUser:              getEnv("DB_USER", "wenka_user"),
+		DBPassword:          getEnv("DB_PASSWORD", "wenka_secret"),
+		DBName:              getEnv("DB_NAME", "wenka_db"),
+		Environment:         getEnv("ENVIRONMENT", "production"),
+		JWTPrivateKeyFile:   getEnv("JWT_PRIVATE_KEY_FILE", "keys/jwt_private.pem"),
+		JWTPublicKeyFiles:   getEnvList("JWT_PUBLIC_KEY_FILES"),
+		ServerPort:          getEnv("SERVER_PORT", "8080"),
+		TrustedProxies:      getEnvList("TRUSTED_PROXIES"),
+		AssignmentStrategy:  getEnv("ASSIGNMENT_STRATEGY", "least_booked"),
+		LinkSecret:          getEnv("LINK_SECRET", ""),
+		LinkTTLHours:        getEnvInt("LINK_TTL_HOURS", 72),
+		AccessTokenTTLMin:   getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTokenTTLDay:  getEnvInt("REFRESH_TOKEN_TTL_DAYS", 30),
+		PasswordResetTTLMin: getEnvInt("PASSWORD_RESET_TTL_MINUTES", 60),
+		EmailVerifySecret:   getEnv("EMAIL_VERIFY_SECRET", ""),
+		EmailVerifyTTLHours: getEnvInt("EMAIL_VERIFY_TTL_HOURS", 48),
+		ReminderOffsets:     getEnvDurations("REMINDER_OFFSETS", []time.Duration{24 * time.Hour, 2 * time.Hour}),
+		ReminderIntervalSec: getEnvInt("REMINDER_INTERVAL_SECONDS", 60),
+		OutboxIntervalSec:   getEnvInt("OUTBOX_INTERVAL_SECONDS", 10),
+		OutboxMaxAttempts:   getEnvInt("OUTBOX_MAX_ATTEMPTS", 8),
+		MailTransport:       getEnv("MAIL_TRANSPORT", mailTransport),
+		MailDir:             getEnv("MAIL_DIR", "mail"),
+		SMTPHost:            getEnv("SMTP_HOST", "smtp.gmail.com"),
+		SMTPPort:            smtpPort,
+		SMTPUsername:        smtpUsername,
+		SMTPPassword:        getEnv("SMTP_PASSWORD", ""),
+		SMTPSecurity:        getEnv("SMTP_SECURITY", smtpSecurity),
 	}
 }
 
+// IsDevelopment indica si el servidor corre en desarrollo
+func (c *Config) IsDevelopment() bool {
+	return c.Environment == "development"
+}
+
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=true&loc=Local",
 		c.DBUser,
@@ -38,6 +105,46 @@ func (c *Config) GetDSN() string {
 	)
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList lee una lista separada por comas; vacía si la variable no existe
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvDurations lee una lista de duraciones separadas por comas ("24h,2h"). Las entradas
+// inválidas se ignoran; "off" desactiva la lista
+func getEnvDurations(key string, defaultValue []time.Duration) []time.Duration {
+	values := getEnvList(key)
+	if len(values) == 0 {
+		return defaultValue
+	}
+
+	var durations []time.Duration
+	for _, value := range values {
+		if value == "off" {
+			return nil
+		}
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			durations = append(durations, parsed)
+		}
+	}
+	return durations
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -3,14 +3,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/wenka/backend/internal/middleware"
 	"github.com/wenka/backend/internal/models"
 	"github.com/wenka/backend/internal/services"
 	"github.com/wenka/backend/internal/utils"
@@ -18,25 +19,24 @@ import (
 
 type AppointmentHandler struct {
 	appointmentService *services.AppointmentService
-	jwtSecret          string
+	keys               *utils.KeySet
 }
 
-func NewAppointmentHandler(appointmentService *services.AppointmentService, jwtSecret string) *AppointmentHandler {
+func NewAppointmentHandler(appointmentService *services.AppointmentService, keys *utils.KeySet) *AppointmentHandler {
 	return &AppointmentHandler{
 		appointmentService: appointmentService,
-		jwtSecret:          jwtSecret,
+		keys:               keys,
 	}
 }
 
 func (h *AppointmentHandler) CreateAppointment(w http.ResponseWriter, r *http.Request) {
-	userID, err := h.getUserIDFromToken(r)
-	if err != nil {
-		log.Printf("Error de autenticación: %v", err)
+	by, ok := changedByFromRequest(r)
+	if !ok {
 		respondWithError(w, http.StatusUnauthorized, "No autorizado")
 		return
 	}
 
-	log.Printf("Usuario autenticado: %d", userID)
+	log.Printf("Usuario autenticado: %d", by.UsuarioID)
 
 	var req models.CreateAppointmentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -47,14 +47,20 @@ func (h *AppointmentHandler) CreateAppointment(w http.ResponseWriter, r *http.Re
 
 	log.Printf("Request recibido: %+v", req)
 
-	appointment, err := h.appointmentService.CreateAppointment(userID, &req)
+	appointment, err := h.appointmentService.CreateAppointment(by, &req)
 	if err != nil {
 		log.Printf("Error al crear cita: %v", err)
 
+		if errors.Is(err, services.ErrPacienteNotFound) {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
 		if strings.Contains(err.Error(), "disponible") ||
 			strings.Contains(err.Error(), "requerido") ||
 			strings.Contains(err.Error(), "inválido") ||
 			strings.Contains(err.Error(), "pasado") ||
+			strings.Contains(err.Error(), "no atiende") ||
 			strings.Contains(err.Error(), "no se encontró especialista") {
 			respondWithError(w, http.StatusBadRequest, err.Error())
 			return
@@ -89,8 +95,14 @@ func (h *AppointmentHandler) GetAppointments(w http.ResponseWriter, r *http.Requ
 	respondWithJSON(w, http.StatusOK, appointments)
 }
 
+// GetAppointmentByID obtiene el detalle de una cita a la que el usuario tiene acceso
 func (h *AppointmentHandler) GetAppointmentByID(w http.ResponseWriter, r *http.Request) {
-	// NO requiere autenticación para que el especialista pueda ver los detalles
+	by, ok := changedByFromRequest(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := strconv.Atoi(idStr)
@@ -99,12 +111,16 @@ func (h *AppointmentHandler) GetAppointmentByID(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	appointment, err := h.appointmentService.GetAppointmentByID(id)
+	appointment, err := h.appointmentService.GetAppointmentByID(id, by)
 	if err != nil {
 		if strings.Contains(err.Error(), "no encontrada") {
 			respondWithError(w, http.StatusNotFound, "Cita no encontrada")
 			return
 		}
+		if strings.Contains(err.Error(), "acceso denegado") {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Error al obtener la cita")
 		return
 	}
@@ -112,6 +128,31 @@ func (h *AppointmentHandler) GetAppointmentByID(w http.ResponseWriter, r *http.R
 	respondWithJSON(w, http.StatusOK, appointment)
 }
 
+// GetAvailability obtiene los horarios libres por especialista para un servicio
+func (h *AppointmentHandler) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	servicio := query.Get("servicio")
+	from := query.Get("from")
+	to := query.Get("to")
+
+	availability, err := h.appointmentService.GetAvailability(servicio, from, to)
+	if err != nil {
+		log.Printf("Error al obtener disponibilidad: %v", err)
+
+		if strings.Contains(err.Error(), "requerid") ||
+			strings.Contains(err.Error(), "inválido") ||
+			strings.Contains(err.Error(), "no se encontró especialista") {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener disponibilidad")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, availability)
+}
+
 // ConfirmAppointment confirma una cita (usado por especialistas desde email)
 func (h *AppointmentHandler) ConfirmAppointment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -124,16 +165,31 @@ func (h *AppointmentHandler) ConfirmAppointment(w http.ResponseWriter, r *http.R
 
 	log.Printf("Confirmando cita ID: %d", id)
 
+	// El token firmado del email lo reenvía la página de confirmación en el cuerpo de la petición
+	var req models.ConfirmAppointmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
 	// Confirmar cita
-	err = h.appointmentService.ConfirmAppointment(id)
+	err = h.appointmentService.ConfirmAppointment(id, req.Token)
 	if err != nil {
 		log.Printf("Error al confirmar cita %d: %v", id, err)
 
-		// Si es GET (desde email), redirigir con error
-		if r.Method == http.MethodGet {
-			frontendURL := getEnvOrDefault("FRONTEND_URL", "http://localhost:3000")
-			// 👇 CAMBIO AQUÍ - Nueva ruta
-			http.Redirect(w, r, fmt.Sprintf("%s/confirm-appointment/confirm/%d?error=true", frontendURL, id), http.StatusSeeOther)
+		if errors.Is(err, utils.ErrLinkTokenInvalid) ||
+			errors.Is(err, utils.ErrLinkTokenExpired) ||
+			errors.Is(err, services.ErrLinkTokenUsed) {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if strings.Contains(err.Error(), "no encontrada") {
+			respondWithError(w, http.StatusNotFound, "Cita no encontrada")
+			return
+		}
+
+		if respondWithTransitionError(w, err) {
 			return
 		}
 
@@ -143,23 +199,181 @@ func (h *AppointmentHandler) ConfirmAppointment(w http.ResponseWriter, r *http.R
 
 	log.Printf("Cita %d confirmada exitosamente", id)
 
-	// Si es una petición GET (desde el email), redirigir al frontend
-	if r.Method == http.MethodGet {
-		frontendURL := getEnvOrDefault("FRONTEND_URL", "http://localhost:3000")
-		// 👇 CAMBIO AQUÍ - Nueva ruta
-		http.Redirect(w, r, fmt.Sprintf("%s/confirm-appointment/confirm/%d", frontendURL, id), http.StatusSeeOther)
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Cita confirmada exitosamente",
+	})
+}
+
+// RespondToAppointment permite al especialista rechazar una cita o proponer otros horarios
+// con el enlace firmado que recibió por email
+func (h *AppointmentHandler) RespondToAppointment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	var req models.RespondAppointmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
 		return
 	}
 
-	// Si es POST/PUT (desde la API), retornar JSON
+	err = h.appointmentService.RespondToAppointment(id, &req)
+	if err != nil {
+		log.Printf("Error al responder cita %d: %v", id, err)
+
+		if errors.Is(err, utils.ErrLinkTokenInvalid) ||
+			errors.Is(err, utils.ErrLinkTokenExpired) ||
+			errors.Is(err, services.ErrLinkTokenUsed) {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if strings.Contains(err.Error(), "no encontrada") {
+			respondWithError(w, http.StatusNotFound, "Cita no encontrada")
+			return
+		}
+
+		if respondWithTransitionError(w, err) {
+			return
+		}
+
+		if strings.Contains(err.Error(), "error al") {
+			respondWithError(w, http.StatusInternalServerError, "Error al responder la cita")
+			return
+		}
+
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	message := "Cita rechazada. Se avisó al paciente"
+	if req.Accion == models.RespuestaProponer {
+		message = "Horarios propuestos enviados al paciente"
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]string{
-		"message": "Cita confirmada exitosamente",
+		"message": message,
 	})
 }
 
-func (h *AppointmentHandler) CancelAppointment(w http.ResponseWriter, r *http.Request) {
-	_, err := h.getUserIDFromToken(r)
+// AcceptProposal reagenda y confirma la cita en el horario propuesto que eligió el paciente
+// con el enlace firmado que recibió por email
+func (h *AppointmentHandler) AcceptProposal(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	propuestaID, err := strconv.Atoi(vars["pid"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de propuesta inválido")
+		return
+	}
+
+	var req models.AcceptProposalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	err = h.appointmentService.AcceptProposal(id, propuestaID, req.Token)
+	if err != nil {
+		log.Printf("Error al aceptar propuesta %d de la cita %d: %v", propuestaID, id, err)
+
+		if errors.Is(err, utils.ErrLinkTokenInvalid) ||
+			errors.Is(err, utils.ErrLinkTokenExpired) ||
+			errors.Is(err, services.ErrLinkTokenUsed) {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if errors.Is(err, services.ErrProposalUnavailable) {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		if strings.Contains(err.Error(), "no encontrada") {
+			respondWithError(w, http.StatusNotFound, "Cita no encontrada")
+			return
+		}
+
+		if respondWithTransitionError(w, err) {
+			return
+		}
+
+		respondWithError(w, http.StatusInternalServerError, "Error al aceptar el horario propuesto")
+		return
+	}
+
+	log.Printf("Cita %d reagendada a la propuesta %d", id, propuestaID)
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Cita reagendada y confirmada en el horario elegido",
+	})
+}
+
+// RescheduleAppointment cambia la fecha y hora de una cita existente
+func (h *AppointmentHandler) RescheduleAppointment(w http.ResponseWriter, r *http.Request) {
+	by, ok := changedByFromRequest(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	var req models.RescheduleAppointmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	appointment, err := h.appointmentService.RescheduleAppointment(id, &req, by)
+	if err != nil {
+		log.Printf("Error al reagendar cita %d: %v", id, err)
+
+		if strings.Contains(err.Error(), "no encontrada") {
+			respondWithError(w, http.StatusNotFound, "Cita no encontrada")
+			return
+		}
+
+		if strings.Contains(err.Error(), "acceso denegado") {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if respondWithTransitionError(w, err) {
+			return
+		}
+
+		if strings.Contains(err.Error(), "disponible") ||
+			strings.Contains(err.Error(), "requerida") ||
+			strings.Contains(err.Error(), "inválido") ||
+			strings.Contains(err.Error(), "pasado") {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		respondWithError(w, http.StatusInternalServerError, "Error al reagendar la cita")
+		return
+	}
+
+	log.Printf("Cita %d reagendada exitosamente", id)
+	respondWithJSON(w, http.StatusOK, appointment)
+}
+
+func (h *AppointmentHandler) CancelAppointment(w http.ResponseWriter, r *http.Request) {
+	by, ok := changedByFromRequest(r)
+	if !ok {
 		respondWithError(w, http.StatusUnauthorized, "No autorizado")
 		return
 	}
@@ -172,9 +386,24 @@ func (h *AppointmentHandler) CancelAppointment(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = h.appointmentService.CancelAppointment(id)
+	err = h.appointmentService.CancelAppointment(id, by)
 	if err != nil {
 		log.Printf("Error al cancelar cita %d: %v", id, err)
+
+		if strings.Contains(err.Error(), "no encontrada") {
+			respondWithError(w, http.StatusNotFound, "Cita no encontrada")
+			return
+		}
+
+		if strings.Contains(err.Error(), "acceso denegado") {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if respondWithTransitionError(w, err) {
+			return
+		}
+
 		respondWithError(w, http.StatusInternalServerError, "Error al cancelar la cita")
 		return
 	}
@@ -185,7 +414,125 @@ func (h *AppointmentHandler) CancelAppointment(w http.ResponseWriter, r *http.Re
 	})
 }
 
+// CheckInAppointment registra la llegada del paciente (especialista asignado)
+func (h *AppointmentHandler) CheckInAppointment(w http.ResponseWriter, r *http.Request) {
+	h.handleSpecialistAction(w, r, "Llegada del paciente registrada", func(id int, by services.ChangedBy) error {
+		return h.appointmentService.CheckInAppointment(id, by)
+	})
+}
+
+// CompleteAppointment cierra la consulta con las notas del especialista
+func (h *AppointmentHandler) CompleteAppointment(w http.ResponseWriter, r *http.Request) {
+	var req models.CompleteAppointmentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+			return
+		}
+	}
+
+	h.handleSpecialistAction(w, r, "Consulta completada", func(id int, by services.ChangedBy) error {
+		return h.appointmentService.CompleteAppointment(id, by, req.Notas)
+	})
+}
+
+// MarkNoShow marca la inasistencia del paciente
+func (h *AppointmentHandler) MarkNoShow(w http.ResponseWriter, r *http.Request) {
+	h.handleSpecialistAction(w, r, "Inasistencia registrada", func(id int, by services.ChangedBy) error {
+		return h.appointmentService.MarkNoShow(id, by)
+	})
+}
+
+// GetAppointmentHistory obtiene el historial de cambios de estado de una cita
+func (h *AppointmentHandler) GetAppointmentHistory(w http.ResponseWriter, r *http.Request) {
+	by, ok := changedByFromRequest(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	history, err := h.appointmentService.GetAppointmentHistory(id, by)
+	if err != nil {
+		if strings.Contains(err.Error(), "no encontrada") {
+			respondWithError(w, http.StatusNotFound, "Cita no encontrada")
+			return
+		}
+		if strings.Contains(err.Error(), "acceso denegado") {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener el historial")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, history)
+}
+
+// handleSpecialistAction resuelve usuario e ID de la cita y traduce los errores comunes
+// de las acciones del especialista sobre una cita
+func (h *AppointmentHandler) handleSpecialistAction(w http.ResponseWriter, r *http.Request, successMessage string, action func(id int, by services.ChangedBy) error) {
+	by, ok := changedByFromRequest(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	if err := action(id, by); err != nil {
+		log.Printf("Error en acción del especialista sobre cita %d: %v", id, err)
+
+		switch {
+		case strings.Contains(err.Error(), "no encontrada"):
+			respondWithError(w, http.StatusNotFound, "Cita no encontrada")
+		case strings.Contains(err.Error(), "acceso denegado"):
+			respondWithError(w, http.StatusForbidden, err.Error())
+		case strings.Contains(err.Error(), "no se puede"):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case respondWithTransitionError(w, err):
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error al actualizar la cita")
+		}
+		return
+	}
+
+	log.Printf("Cita %d: %s", id, successMessage)
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": successMessage,
+	})
+}
+
+// respondWithTransitionError responde 409 si err es un cambio de estado no permitido
+func respondWithTransitionError(w http.ResponseWriter, err error) bool {
+	var transitionErr *services.TransitionError
+	if errors.As(err, &transitionErr) {
+		respondWithError(w, http.StatusConflict, transitionErr.Error())
+		return true
+	}
+	return false
+}
+
 func (h *AppointmentHandler) getUserIDFromToken(r *http.Request) (int, error) {
+	return getUserIDFromRequest(r, h.keys)
+}
+
+// getUserIDFromRequest obtiene el usuario autenticado. Usa los claims que dejó el middleware
+// RequireAuth y, si la ruta no lo usa, extrae y valida el token Bearer de la petición
+func getUserIDFromRequest(r *http.Request, keys *utils.KeySet) (int, error) {
+	if claims, ok := middleware.ClaimsFromContext(r.Context()); ok {
+		return claims.UserID, nil
+	}
+
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
 		return 0, fmt.Errorf("no authorization header")
@@ -196,7 +543,7 @@ func (h *AppointmentHandler) getUserIDFromToken(r *http.Request) (int, error) {
 		return 0, fmt.Errorf("invalid authorization format")
 	}
 
-	claims, err := utils.ValidateToken(tokenParts[1], h.jwtSecret)
+	claims, err := utils.ValidateToken(tokenParts[1], keys)
 	if err != nil {
 		return 0, err
 	}
@@ -204,9 +551,13 @@ func (h *AppointmentHandler) getUserIDFromToken(r *http.Request) (int, error) {
 	return claims.UserID, nil
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// changedByFromRequest identifica quién realiza un cambio de estado según el rol del token.
+// Requiere que la ruta use el middleware RequireAuth
+func changedByFromRequest(r *http.Request) (services.ChangedBy, bool) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		return services.ChangedBy{}, false
 	}
-	return defaultValue
+
+	return services.ChangedBy{Actor: services.Actor(claims.Rol), UsuarioID: claims.UserID}, true
 }
@@ -3,24 +3,32 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/gorilla/mux"
+	"github.com/wenka/backend/internal/middleware"
 	"github.com/wenka/backend/internal/models"
 	"github.com/wenka/backend/internal/services"
 	"github.com/wenka/backend/internal/utils"
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
-	jwtSecret   string
+	authService    *services.AuthService
+	keys           *utils.KeySet
+	trustedProxies utils.TrustedProxies
 }
 
 // NewAuthHandler crea una nueva instancia del handler
-func NewAuthHandler(authService *services.AuthService, jwtSecret string) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, keys *utils.KeySet, trustedProxies utils.TrustedProxies) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		jwtSecret:   jwtSecret,
+		authService:    authService,
+		keys:           keys,
+		trustedProxies: trustedProxies,
 	}
 }
 
@@ -45,7 +53,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Registrar usuario
-	authResponse, err := h.authService.Register(&req)
+	authResponse, err := h.authService.Register(&req, h.deviceFromRequest(r))
 	if err != nil {
 		if strings.Contains(err.Error(), "ya está registrado") {
 			respondWithError(w, http.StatusConflict, err.Error())
@@ -74,8 +82,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Autenticar usuario
-	authResponse, err := h.authService.Login(&req)
+	authResponse, challenge, err := h.authService.Login(&req, h.deviceFromRequest(r))
 	if err != nil {
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			respondWithError(w, http.StatusTooManyRequests, throttled.Error())
+			return
+		}
 		if strings.Contains(err.Error(), "credenciales inválidas") {
 			respondWithError(w, http.StatusUnauthorized, "Email o contraseña incorrectos")
 			return
@@ -84,42 +98,508 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// La cuenta requiere segundo factor: el cliente continúa en /api/auth/2fa
+	if challenge != nil {
+		respondWithJSON(w, http.StatusOK, challenge)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, authResponse)
 }
 
 // Me obtiene la información del usuario autenticado
 func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
-	// Obtener token del header
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		respondWithError(w, http.StatusUnauthorized, "Token no proporcionado")
+	userID, err := getUserIDFromRequest(r, h.keys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Token inválido o expirado")
+		return
+	}
+
+	// Obtener usuario
+	user, err := h.authService.GetUserByID(userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Usuario no encontrado")
 		return
 	}
 
-	// Extraer token (formato: "Bearer TOKEN")
-	tokenParts := strings.Split(authHeader, " ")
-	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-		respondWithError(w, http.StatusUnauthorized, "Formato de token inválido")
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// UpdateMe actualiza el perfil del usuario autenticado
+func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
 		return
 	}
 
-	// Validar token
-	claims, err := utils.ValidateToken(tokenParts[1], h.jwtSecret)
+	var req models.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	user, err := h.authService.UpdateProfile(claims.UserID, &req, h.deviceFromRequest(r))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Token inválido o expirado")
+		respondWithAccountError(w, err)
 		return
 	}
 
-	// Obtener usuario
-	user, err := h.authService.GetUserByID(claims.UserID)
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// ChangePassword cambia la contraseña y cierra las demás sesiones del usuario
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	if len(req.PasswordNuevo) < 6 {
+		respondWithError(w, http.StatusBadRequest, "La contraseña debe tener al menos 6 caracteres")
+		return
+	}
+
+	if err := h.authService.ChangePassword(claims.UserID, claims.SessionID, &req, h.deviceFromRequest(r)); err != nil {
+		respondWithAccountError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Contraseña actualizada. Se cerró la sesión en los demás dispositivos",
+	})
+}
+
+// DeleteMe elimina la cuenta del usuario autenticado
+func (h *AuthHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	if err := h.authService.DeleteAccount(claims.UserID, &req, h.deviceFromRequest(r)); err != nil {
+		respondWithAccountError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Cuenta eliminada",
+	})
+}
+
+// respondWithAccountError traduce los errores de autogestión de la cuenta
+func respondWithAccountError(w http.ResponseWriter, err error) {
+	var throttled *services.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		respondWithError(w, http.StatusTooManyRequests, throttled.Error())
+	case errors.Is(err, services.ErrPasswordIncorrect), errors.Is(err, services.ErrStaffAccountDelete):
+		// 403 y no 401: la sesión es válida, el cliente no debe intentar renovarla
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrEmailInUse):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrProfileFieldInvalid), strings.Contains(err.Error(), "inválido"):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case strings.Contains(err.Error(), "no encontrado"):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Printf("Error al actualizar la cuenta: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al actualizar la cuenta")
+	}
+}
+
+// Refresh rota el refresh token y entrega un nuevo token de acceso
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	authResponse, err := h.authService.Refresh(req.RefreshToken, h.deviceFromRequest(r))
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Usuario no encontrado")
+		if errors.Is(err, services.ErrRefreshTokenInvalid) || errors.Is(err, services.ErrRefreshTokenReused) {
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		log.Printf("Error al renovar sesión: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al renovar la sesión")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, authResponse)
+}
+
+// Logout cierra la sesión del token actual
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	if err := h.authService.Logout(claims.UserID, claims.SessionID); err != nil {
+		log.Printf("Error al cerrar sesión: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al cerrar sesión")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Sesión cerrada",
+	})
+}
+
+// LogoutAll cierra la sesión del usuario en todos sus dispositivos
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	if err := h.authService.LogoutAll(claims.UserID); err != nil {
+		log.Printf("Error al cerrar sesiones: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al cerrar sesiones")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Se cerró la sesión en todos los dispositivos",
+	})
+}
+
+// Sessions lista las sesiones activas del usuario
+func (h *AuthHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r, h.keys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	sesiones, err := h.authService.ListSessions(userID)
+	if err != nil {
+		log.Printf("Error al obtener sesiones: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener sesiones")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sesiones)
+}
+
+// ForgotPassword solicita el email para restablecer la contraseña. Responde igual exista
+// o no la cuenta, para no revelar qué emails están registrados
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	if req.Email == "" {
+		respondWithError(w, http.StatusBadRequest, "El email es requerido")
+		return
+	}
+
+	if err := h.authService.ForgotPassword(req.Email); err != nil {
+		log.Printf("Error al solicitar restablecimiento de contraseña: %v", err)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Si el email está registrado, recibirás un enlace para restablecer tu contraseña",
+	})
+}
+
+// ResetPassword fija una nueva contraseña con el token recibido por email
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
 		return
 	}
 
+	if len(req.Password) < 6 {
+		respondWithError(w, http.StatusBadRequest, "La contraseña debe tener al menos 6 caracteres")
+		return
+	}
+
+	if err := h.authService.ResetPassword(&req); err != nil {
+		if errors.Is(err, services.ErrResetTokenInvalid) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error al restablecer contraseña: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al restablecer la contraseña")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Contraseña actualizada. Inicia sesión con tu nueva contraseña",
+	})
+}
+
+// VerifyEmail verifica el email con el token del enlace enviado al registrarse
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	if err := h.authService.VerifyEmail(req.Token); err != nil {
+		switch {
+		case errors.Is(err, utils.ErrLinkTokenExpired):
+			respondWithError(w, http.StatusBadRequest, "El enlace de verificación expiró; solicita uno nuevo")
+		case errors.Is(err, utils.ErrLinkTokenInvalid):
+			respondWithError(w, http.StatusBadRequest, "El enlace de verificación no es válido")
+		default:
+			log.Printf("Error al verificar email: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Error al verificar el email")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Email verificado. Ya puedes agendar citas",
+	})
+}
+
+// ResendVerification reenvía el email de verificación al usuario autenticado
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	if err := h.authService.ResendVerification(claims.UserID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmailAlreadyVerified):
+			respondWithError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrVerificationRateLimited):
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		default:
+			log.Printf("Error al reenviar verificación: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Error al reenviar el email de verificación")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Te enviamos un nuevo enlace de verificación",
+	})
+}
+
+// UpdateUserRole permite a un administrador cambiar el rol de un usuario
+func (h *AuthHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	user, err := h.authService.UpdateUserRole(id, &req)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "no encontrado"):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case strings.Contains(err.Error(), "ya está vinculado"):
+			respondWithError(w, http.StatusConflict, err.Error())
+		case strings.Contains(err.Error(), "error al"):
+			respondWithError(w, http.StatusInternalServerError, "Error al actualizar el rol")
+		default:
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	log.Printf("Rol del usuario %d cambiado a %s", user.ID, user.Rol)
 	respondWithJSON(w, http.StatusOK, user)
 }
 
+// UnlockUser levanta el bloqueo por intentos fallidos de una cuenta
+func (h *AuthHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	if err := h.authService.UnlockUser(id); err != nil {
+		if strings.Contains(err.Error(), "no encontrado") {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("Error al desbloquear usuario: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al desbloquear la cuenta")
+		return
+	}
+
+	log.Printf("Cuenta del usuario %d desbloqueada", id)
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Cuenta desbloqueada",
+	})
+}
+
+// VerifyMFA completa el login con el segundo factor
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req models.MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	authResponse, err := h.authService.VerifyMFA(&req, h.deviceFromRequest(r))
+	if err != nil {
+		h.respondWithMFAError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, authResponse)
+}
+
+// SetupMFA genera el secreto TOTP y el URI otpauth para la app de autenticación
+func (h *AuthHandler) SetupMFA(w http.ResponseWriter, r *http.Request) {
+	var req models.MFASetupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	userID, _, err := h.mfaUserFromRequest(r, req.MFAToken)
+	if err != nil {
+		h.respondWithMFAError(w, err)
+		return
+	}
+
+	setup, err := h.authService.SetupMFA(userID)
+	if err != nil {
+		h.respondWithMFAError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, setup)
+}
+
+// EnableMFA confirma el primer código y activa 2FA. Si se activó durante el login del
+// personal, la respuesta incluye la sesión
+func (h *AuthHandler) EnableMFA(w http.ResponseWriter, r *http.Request) {
+	var req models.MFAEnableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	userID, fromLogin, err := h.mfaUserFromRequest(r, req.MFAToken)
+	if err != nil {
+		h.respondWithMFAError(w, err)
+		return
+	}
+
+	response, err := h.authService.EnableMFA(userID, req.Code, fromLogin, h.deviceFromRequest(r))
+	if err != nil {
+		h.respondWithMFAError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// DisableMFA desactiva 2FA de una cuenta de paciente
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	var req models.MFADisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	if err := h.authService.DisableMFA(claims.UserID, req.Code); err != nil {
+		h.respondWithMFAError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Verificación en dos pasos desactivada",
+	})
+}
+
+// mfaUserFromRequest identifica al usuario por su sesión o, durante el login, por el
+// token parcial. Retorna true en el segundo caso
+func (h *AuthHandler) mfaUserFromRequest(r *http.Request, mfaToken string) (int, bool, error) {
+	if claims, ok := middleware.ClaimsFromContext(r.Context()); ok {
+		return claims.UserID, false, nil
+	}
+
+	userID, err := h.authService.UserIDFromMFAToken(mfaToken)
+	if err != nil {
+		return 0, false, err
+	}
+	return userID, true, nil
+}
+
+// respondWithMFAError traduce los errores de la verificación en dos pasos
+func (h *AuthHandler) respondWithMFAError(w http.ResponseWriter, err error) {
+	var throttled *services.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		respondWithError(w, http.StatusTooManyRequests, throttled.Error())
+	case errors.Is(err, utils.ErrMFATokenInvalid), errors.Is(err, services.ErrMFACodeInvalid):
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotEnabled),
+		errors.Is(err, services.ErrMFANotPending), errors.Is(err, services.ErrMFASetupRequired):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrMFARequiredForRole):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case strings.Contains(err.Error(), "no encontrado"):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Printf("Error en verificación en dos pasos: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error en la verificación en dos pasos")
+	}
+}
+
+// JWKS publica las llaves públicas con las que se validan los tokens de acceso
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Caché corta: tras una rotación los demás servicios ven la llave nueva en minutos
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, h.keys.JWKS())
+}
+
+// deviceFromRequest obtiene el dispositivo y la IP de origen de la petición
+func (h *AuthHandler) deviceFromRequest(r *http.Request) models.DeviceInfo {
+	return models.DeviceInfo{
+		UserAgent: r.UserAgent(),
+		IP:        utils.ClientIP(r, h.trustedProxies),
+	}
+}
+
 // Funciones auxiliares para respuestas JSON
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
@@ -0,0 +1,65 @@
+// backend/internal/handlers/email_outbox_handler.go
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/wenka/backend/internal/services"
+)
+
+type EmailOutboxHandler struct {
+	emailOutbox *services.EmailOutbox
+}
+
+// NewEmailOutboxHandler crea una nueva instancia del handler
+func NewEmailOutboxHandler(emailOutbox *services.EmailOutbox) *EmailOutboxHandler {
+	return &EmailOutboxHandler{emailOutbox: emailOutbox}
+}
+
+// ListEmails lista los emails de la bandeja de salida; ?estado= filtra, por omisión los fallidos.
+// No incluye el cuerpo porque puede llevar enlaces de un solo uso
+func (h *EmailOutboxHandler) ListEmails(w http.ResponseWriter, r *http.Request) {
+	emails, err := h.emailOutbox.ListEmails(r.URL.Query().Get("estado"))
+	if err != nil {
+		if strings.Contains(err.Error(), "inválido") {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error al obtener emails: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener emails")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, emails)
+}
+
+// ResendEmail vuelve a encolar un email fallido
+func (h *EmailOutboxHandler) ResendEmail(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	email, err := h.emailOutbox.ResendEmail(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmailNotFailed):
+			respondWithError(w, http.StatusConflict, err.Error())
+		case strings.Contains(err.Error(), "no encontrado"):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		default:
+			log.Printf("Error al reenviar email: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Error al reenviar el email")
+		}
+		return
+	}
+
+	log.Printf("Email %d reencolado", id)
+	respondWithJSON(w, http.StatusOK, email)
+}
@@ -0,0 +1,139 @@
+// backend/internal/handlers/patient_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/wenka/backend/internal/middleware"
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/services"
+)
+
+type PatientHandler struct {
+	patientService *services.PatientService
+}
+
+// NewPatientHandler crea una nueva instancia del handler
+func NewPatientHandler(patientService *services.PatientService) *PatientHandler {
+	return &PatientHandler{
+		patientService: patientService,
+	}
+}
+
+// ListPatients lista el expediente del titular y los de sus dependientes
+func (h *PatientHandler) ListPatients(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	pacientes, err := h.patientService.ListForUser(claims.UserID)
+	if err != nil {
+		respondWithPatientError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, pacientes)
+}
+
+// CreatePatient registra un dependiente de la cuenta
+func (h *PatientHandler) CreatePatient(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	var req models.PacienteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	paciente, err := h.patientService.CreateDependant(claims.UserID, &req)
+	if err != nil {
+		respondWithPatientError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, paciente)
+}
+
+// UpdatePatient edita un expediente de la cuenta
+func (h *PatientHandler) UpdatePatient(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de paciente inválido")
+		return
+	}
+
+	var req models.PacienteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	paciente, err := h.patientService.UpdatePatient(claims.UserID, id, &req)
+	if err != nil {
+		respondWithPatientError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, paciente)
+}
+
+// DeletePatient quita un dependiente de la cuenta
+func (h *PatientHandler) DeletePatient(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de paciente inválido")
+		return
+	}
+
+	if err := h.patientService.RemoveDependant(claims.UserID, id); err != nil {
+		respondWithPatientError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Paciente eliminado",
+	})
+}
+
+// respondWithPatientError traduce los errores de la gestión de expedientes
+func respondWithPatientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrPacienteNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrTitularNotRemoved):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case strings.Contains(err.Error(), "inválido"),
+		strings.Contains(err.Error(), "requeridos"),
+		strings.Contains(err.Error(), "futura"):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case strings.Contains(err.Error(), "otro paciente"):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Error al gestionar pacientes: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al gestionar pacientes")
+	}
+}
@@ -0,0 +1,147 @@
+// backend/internal/handlers/schedule_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/services"
+	"github.com/wenka/backend/internal/utils"
+)
+
+type ScheduleHandler struct {
+	scheduleService *services.ScheduleService
+	keys            *utils.KeySet
+}
+
+// NewScheduleHandler crea una nueva instancia del handler
+func NewScheduleHandler(scheduleService *services.ScheduleService, keys *utils.KeySet) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: scheduleService,
+		keys:            keys,
+	}
+}
+
+// ListTimeOff lista vacaciones, incapacidades y cierres de la clínica
+func (h *ScheduleHandler) ListTimeOff(w http.ResponseWriter, r *http.Request) {
+	if _, err := getUserIDFromRequest(r, h.keys); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	query := r.URL.Query()
+	especialistaID := 0
+	if value := query.Get("especialista_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "ID de especialista inválido")
+			return
+		}
+		especialistaID = id
+	}
+
+	bloqueos, err := h.scheduleService.ListTimeOff(especialistaID, query.Get("from"), query.Get("to"))
+	if err != nil {
+		if strings.Contains(err.Error(), "inválido") {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error al obtener bloqueos: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener bloqueos")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, bloqueos)
+}
+
+// CreateTimeOff registra un nuevo bloqueo de agenda
+func (h *ScheduleHandler) CreateTimeOff(w http.ResponseWriter, r *http.Request) {
+	if _, err := getUserIDFromRequest(r, h.keys); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	var req models.BloqueoAgendaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	bloqueo, err := h.scheduleService.CreateTimeOff(&req)
+	if err != nil {
+		h.respondWithTimeOffError(w, err)
+		return
+	}
+
+	log.Printf("Bloqueo de agenda %d creado", bloqueo.ID)
+	respondWithJSON(w, http.StatusCreated, bloqueo)
+}
+
+// UpdateTimeOff modifica un bloqueo de agenda
+func (h *ScheduleHandler) UpdateTimeOff(w http.ResponseWriter, r *http.Request) {
+	if _, err := getUserIDFromRequest(r, h.keys); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	var req models.BloqueoAgendaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Datos inválidos")
+		return
+	}
+
+	bloqueo, err := h.scheduleService.UpdateTimeOff(id, &req)
+	if err != nil {
+		h.respondWithTimeOffError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, bloqueo)
+}
+
+// DeleteTimeOff elimina un bloqueo de agenda
+func (h *ScheduleHandler) DeleteTimeOff(w http.ResponseWriter, r *http.Request) {
+	if _, err := getUserIDFromRequest(r, h.keys); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No autorizado")
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	if err := h.scheduleService.DeleteTimeOff(id); err != nil {
+		h.respondWithTimeOffError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Bloqueo eliminado exitosamente",
+	})
+}
+
+func (h *ScheduleHandler) respondWithTimeOffError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "no encontrado"):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case strings.Contains(err.Error(), "requerid") ||
+		strings.Contains(err.Error(), "inválido"):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("Error en bloqueo de agenda: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Error al procesar el bloqueo")
+	}
+}
@@ -0,0 +1,150 @@
+// backend/internal/middleware/auth.go
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/wenka/backend/internal/utils"
+)
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// SessionValidator verifica que la sesión de un token de acceso no haya sido cerrada
+type SessionValidator interface {
+	IsSessionActive(sessionID string) (bool, error)
+}
+
+// RequireAuth valida el token Bearer, verifica que su sesión siga activa y guarda
+// sus claims en el contexto de la petición
+func RequireAuth(keys *utils.KeySet, sessions SessionValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, code, message := authenticate(r, keys, sessions)
+			if claims == nil {
+				respondWithError(w, code, message)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalAuth se comporta como RequireAuth cuando la petición trae token y la deja pasar
+// sin claims cuando no lo trae; el handler decide cómo autenticarla
+func OptionalAuth(keys *utils.KeySet, sessions SessionValidator) func(http.Handler) http.Handler {
+	requireAuth := RequireAuth(keys, sessions)
+	return func(next http.Handler) http.Handler {
+		withAuth := requireAuth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withAuth.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticate valida el token Bearer y su sesión. Si falla retorna claims nil junto con
+// el código y el mensaje de error
+func authenticate(r *http.Request, keys *utils.KeySet, sessions SessionValidator) (*utils.Claims, int, string) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, http.StatusUnauthorized, "Token no proporcionado"
+	}
+
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		return nil, http.StatusUnauthorized, "Formato de token inválido"
+	}
+
+	claims, err := utils.ValidateToken(tokenParts[1], keys)
+	if err != nil {
+		return nil, http.StatusUnauthorized, "Token inválido o expirado"
+	}
+
+	active, err := sessions.IsSessionActive(claims.SessionID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Error al verificar la sesión"
+	}
+
+	if !active {
+		return nil, http.StatusUnauthorized, "Sesión cerrada o expirada"
+	}
+
+	return claims, 0, ""
+}
+
+// EmailVerifier indica si un usuario ya verificó su email
+type EmailVerifier interface {
+	IsEmailVerified(userID int) (bool, error)
+}
+
+// RequireVerifiedEmail bloquea la ruta a usuarios que no han verificado su email.
+// Debe ir después de RequireAuth
+func RequireVerifiedEmail(verifier EmailVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				respondWithError(w, http.StatusUnauthorized, "No autorizado")
+				return
+			}
+
+			// Un error de base de datos no invalida la sesión: 401 haría que el cliente cierre sesión
+			verified, err := verifier.IsEmailVerified(claims.UserID)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Error al verificar el email")
+				return
+			}
+
+			if !verified {
+				respondWithError(w, http.StatusForbidden, "Email no verificado: revisa tu correo y abre el enlace de verificación para continuar")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole permite el acceso solo a los roles indicados. Debe ir después de RequireAuth
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				respondWithError(w, http.StatusUnauthorized, "No autorizado")
+				return
+			}
+
+			for _, rol := range roles {
+				if claims.Rol == rol {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			respondWithError(w, http.StatusForbidden, "No tienes permiso para realizar esta acción")
+		})
+	}
+}
+
+// ClaimsFromContext obtiene los claims que guardó RequireAuth
+func ClaimsFromContext(ctx context.Context) (*utils.Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*utils.Claims)
+	return claims, ok
+}
+
+func respondWithError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
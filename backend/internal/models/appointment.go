@@ -3,6 +3,16 @@ package models
 
 import "time"
 
+// Estados posibles de una cita (columna citas.estado)
+const (
+	EstadoProgramada = "programada"
+	EstadoConfirmada = "confirmada"
+	EstadoEnCurso    = "en_curso"
+	EstadoCompletada = "completada"
+	EstadoCancelada  = "cancelada"
+	EstadoNoAsistio  = "no_asistio"
+)
+
 // Appointment representa una cita médica en el sistema
 type Appointment struct {
 	ID              int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
@@ -22,17 +32,81 @@ func (Appointment) TableName() string {
 	return "citas"
 }
 
-// Paciente modelo para la tabla pacientes
+// CambioEstadoCita registro de auditoría de cada cambio de estado de una cita
+type CambioEstadoCita struct {
+	ID             int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	CitaID         int       `json:"cita_id" gorm:"column:cita_id;not null"`
+	EstadoAnterior string    `json:"estado_anterior" gorm:"column:estado_anterior;type:varchar(20);not null"`
+	EstadoNuevo    string    `json:"estado_nuevo" gorm:"column:estado_nuevo;type:varchar(20);not null"`
+	Actor          string    `json:"actor" gorm:"column:actor;type:varchar(20);not null"`
+	UsuarioID      *int      `json:"usuario_id,omitempty" gorm:"column:usuario_id"` // nil si vino de un enlace de email
+	Notas          string    `json:"notas,omitempty" gorm:"column:notas;type:text"`
+	CreatedAt      time.Time `json:"fecha" gorm:"column:fecha;autoCreateTime"`
+}
+
+func (CambioEstadoCita) TableName() string {
+	return "citas_cambios_estado"
+}
+
+// Acciones que pueden ejecutarse desde un enlace firmado enviado por email
+const (
+	AccionConfirmar        = "confirmar"
+	AccionResponder        = "responder"         // rechazar o proponer otro horario (especialista)
+	AccionAceptarPropuesta = "aceptar_propuesta" // aceptar un horario propuesto (paciente)
+)
+
+// EnlaceCita registro de un enlace firmado enviado por email; permite usarlo una sola vez
+type EnlaceCita struct {
+	ID             int        `gorm:"column:id;primaryKey;autoIncrement"`
+	JTI            string     `gorm:"column:jti;type:varchar(64);uniqueIndex;not null"`
+	CitaID         int        `gorm:"column:cita_id;not null"`
+	EspecialistaID int        `gorm:"column:especialista_id;not null"`
+	Accion         string     `gorm:"column:accion;type:varchar(20);not null"`
+	PropuestaID    *int       `gorm:"column:propuesta_id"`
+	ExpiraEn       time.Time  `gorm:"column:expira_en;not null"`
+	UsadoEn        *time.Time `gorm:"column:usado_en"`
+	CreatedAt      time.Time  `gorm:"column:fecha_creacion;autoCreateTime"`
+}
+
+func (EnlaceCita) TableName() string {
+	return "enlaces_cita"
+}
+
+// Estados de un horario propuesto por el especialista
+const (
+	PropuestaPendiente  = "pendiente"
+	PropuestaAceptada   = "aceptada"
+	PropuestaDescartada = "descartada"
+)
+
+// PropuestaHorario horario alternativo que el especialista ofrece al paciente
+type PropuestaHorario struct {
+	ID        int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	CitaID    int       `json:"cita_id" gorm:"column:cita_id;not null"`
+	FechaHora time.Time `json:"fecha_hora" gorm:"column:fecha_hora;not null"`
+	Estado    string    `json:"estado" gorm:"column:estado;type:varchar(20);default:pendiente"`
+	CreatedAt time.Time `json:"fecha_creacion" gorm:"column:fecha_creacion;autoCreateTime"`
+}
+
+func (PropuestaHorario) TableName() string {
+	return "propuestas_horario"
+}
+
+// Paciente modelo para la tabla pacientes. UsuarioID es la cuenta que administra el
+// expediente: la del propio paciente (parentesco titular) o la de un familiar
 type Paciente struct {
-	ID              int       `gorm:"column:id;primaryKey;autoIncrement"`
-	Nombre          string    `gorm:"column:nombre;type:varchar(100);not null"`
-	ApellidoPaterno string    `gorm:"column:apellido_paterno;type:varchar(100);not null"`
-	ApellidoMaterno string    `gorm:"column:apellido_materno;type:varchar(100)"`
-	Email           string    `gorm:"column:email;type:varchar(255);uniqueIndex;not null"`
-	Telefono        string    `gorm:"column:telefono;type:varchar(20)"`
-	FechaNacimiento time.Time `gorm:"column:fecha_nacimiento"`
-	Sexo            string    `gorm:"column:sexo;type:char(1)"`
-	FechaRegistro   time.Time `gorm:"column:fecha_registro;autoCreateTime"`
+	ID              int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Nombre          string    `json:"nombre" gorm:"column:nombre;type:varchar(100);not null"`
+	ApellidoPaterno string    `json:"apellido_paterno" gorm:"column:apellido_paterno;type:varchar(100);not null"`
+	ApellidoMaterno string    `json:"apellido_materno" gorm:"column:apellido_materno;type:varchar(100)"`
+	Email           *string   `json:"email,omitempty" gorm:"column:email;type:varchar(100);uniqueIndex"`
+	Telefono        string    `json:"telefono" gorm:"column:telefono;type:varchar(20)"`
+	FechaNacimiento time.Time `json:"fecha_nacimiento" gorm:"column:fecha_nacimiento"`
+	Sexo            string    `json:"sexo" gorm:"column:sexo;type:char(1)"`
+	UsuarioID       *int      `json:"usuario_id,omitempty" gorm:"column:usuario_id"`
+	Parentesco      string    `json:"parentesco" gorm:"column:parentesco;type:varchar(20);default:titular"`
+	Activo          bool      `json:"activo" gorm:"column:activo;default:true"`
+	FechaRegistro   time.Time `json:"fecha_registro" gorm:"column:fecha_registro;autoCreateTime"`
 }
 
 func (Paciente) TableName() string {
@@ -66,8 +140,23 @@ func (Tratamiento) TableName() string {
 	return "tratamientos"
 }
 
+// EspecialistaServicio especialista elegible para un servicio junto con su tratamiento
+type EspecialistaServicio struct {
+	ID              int
+	Nombre          string
+	Email           string
+	EspecialidadID  int
+	TratamientoID   int
+	Tratamiento     string
+	DuracionMinutos int
+}
+
 // CreateAppointmentRequest estructura para crear una cita
 type CreateAppointmentRequest struct {
+	// Paciente de la cita. Un paciente solo puede elegir su expediente o el de un dependiente;
+	// si se omite la cita es para el titular de la cuenta. El personal puede usar cualquier
+	// expediente o dar los datos de un paciente nuevo
+	PacienteID     int    `json:"paciente_id,omitempty"`
 	NombrePaciente string `json:"nombre_paciente"`
 	Telefono       string `json:"telefono"`
 	Email          string `json:"email"`
@@ -75,11 +164,52 @@ type CreateAppointmentRequest struct {
 	FechaCita      string `json:"fecha_cita"` // YYYY-MM-DD
 	HoraCita       string `json:"hora_cita"`  // HH:MM
 	Mensaje        string `json:"mensaje"`
+	// Opcionales: si se omiten se asigna especialista/tratamiento automáticamente a partir de Servicio
+	EspecialistaID int `json:"especialista_id,omitempty"`
+	TratamientoID  int `json:"tratamiento_id,omitempty"`
+}
+
+// RescheduleAppointmentRequest estructura para reagendar una cita
+type RescheduleAppointmentRequest struct {
+	FechaCita string `json:"fecha_cita"` // YYYY-MM-DD
+	HoraCita  string `json:"hora_cita"`  // HH:MM
+}
+
+// CompleteAppointmentRequest estructura para cerrar una consulta
+type CompleteAppointmentRequest struct {
+	Notas string `json:"notas"`
+}
+
+// ConfirmAppointmentRequest cuerpo para confirmar una cita con el enlace firmado del email
+type ConfirmAppointmentRequest struct {
+	Token string `json:"token"`
+}
+
+// Respuestas del especialista a una cita desde el enlace del email
+const (
+	RespuestaRechazar = "rechazar"
+	RespuestaProponer = "proponer"
+)
+
+// RespondAppointmentRequest estructura para que el especialista rechace una cita
+// o proponga hasta tres horarios alternativos
+type RespondAppointmentRequest struct {
+	Token    string          `json:"token"`
+	Accion   string          `json:"accion"` // rechazar | proponer
+	Motivo   string          `json:"motivo"`
+	Horarios []AvailableSlot `json:"horarios"`
+}
+
+// AcceptProposalRequest cuerpo para que el paciente acepte un horario propuesto con el
+// enlace firmado del email
+type AcceptProposalRequest struct {
+	Token string `json:"token"`
 }
 
 // AppointmentResponse respuesta con información completa de la cita
 type AppointmentResponse struct {
 	ID             int       `json:"id"`
+	PacienteID     int       `json:"paciente_id"`
 	NombrePaciente string    `json:"nombre_paciente"`
 	Telefono       string    `json:"telefono"`
 	Email          string    `json:"email"`
@@ -94,6 +224,7 @@ type AppointmentResponse struct {
 // AppointmentWithDetails cita con información de especialista y tratamiento
 type AppointmentWithDetails struct {
 	ID                 int       `json:"id"`
+	EspecialistaID     int       `json:"especialista_id"`
 	NombrePaciente     string    `json:"nombre_paciente"`
 	EmailPaciente      string    `json:"email_paciente"`
 	TelefonoPaciente   string    `json:"telefono_paciente"`
@@ -102,7 +233,26 @@ type AppointmentWithDetails struct {
 	Especialidad       string    `json:"especialidad"`
 	Tratamiento        string    `json:"tratamiento"`
 	FechaHora          time.Time `json:"fecha_hora"`
+	DuracionMinutos    int       `json:"duracion_minutos"`
 	Motivo             string    `json:"motivo"`
 	Estado             string    `json:"estado"`
 	CreatedAt          time.Time `json:"created_at"`
+	IdiomaPaciente     string    `json:"-"` // idioma de los emails de la cuenta del paciente
+	IdiomaEspecialista string    `json:"-"`
+}
+
+// AvailableSlot horario libre en el mismo formato que CreateAppointmentRequest
+type AvailableSlot struct {
+	FechaCita string `json:"fecha_cita"` // YYYY-MM-DD
+	HoraCita  string `json:"hora_cita"`  // HH:MM
+}
+
+// AvailabilityResponse horarios disponibles de un especialista para un servicio
+type AvailabilityResponse struct {
+	EspecialistaID     int             `json:"especialista_id"`
+	NombreEspecialista string          `json:"nombre_especialista"`
+	TratamientoID      int             `json:"tratamiento_id"`
+	Tratamiento        string          `json:"tratamiento"`
+	DuracionMinutos    int             `json:"duracion_minutos"`
+	Horarios           []AvailableSlot `json:"horarios"`
 }
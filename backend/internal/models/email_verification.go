@@ -0,0 +1,21 @@
+// backend/internal/models/email_verification.go
+package models
+
+import "time"
+
+// EnvioVerificacion registro de cada email de verificación enviado, para limitar reenvíos
+type EnvioVerificacion struct {
+	ID        int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	UsuarioID int       `json:"usuario_id" gorm:"column:usuario_id;not null"`
+	CreatedAt time.Time `json:"fecha_envio" gorm:"column:fecha_envio;autoCreateTime"`
+}
+
+// TableName especifica el nombre de la tabla
+func (EnvioVerificacion) TableName() string {
+	return "envios_verificacion"
+}
+
+// VerifyEmailRequest estructura para verificar el email con el token del enlace
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,99 @@
+// backend/internal/models/horario.go
+package models
+
+import "time"
+
+// HorarioEspecialista jornada laboral de un especialista para un día de la semana
+type HorarioEspecialista struct {
+	ID             int     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	EspecialistaID int     `json:"especialista_id" gorm:"column:especialista_id;not null"`
+	DiaSemana      int     `json:"dia_semana" gorm:"column:dia_semana;not null"` // 0 = domingo ... 6 = sábado
+	HoraInicio     string  `json:"hora_inicio" gorm:"column:hora_inicio;type:time;not null"`
+	HoraFin        string  `json:"hora_fin" gorm:"column:hora_fin;type:time;not null"`
+	DescansoInicio *string `json:"descanso_inicio,omitempty" gorm:"column:descanso_inicio;type:time"`
+	DescansoFin    *string `json:"descanso_fin,omitempty" gorm:"column:descanso_fin;type:time"`
+	Activo         bool    `json:"activo" gorm:"column:activo;default:true"`
+}
+
+// TableName especifica el nombre de la tabla
+func (HorarioEspecialista) TableName() string {
+	return "horarios_especialistas"
+}
+
+// Bounds devuelve la hora de entrada y de salida de la jornada en la fecha indicada
+func (h HorarioEspecialista) Bounds(day time.Time) (time.Time, time.Time) {
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	opening := midnight.Add(time.Duration(clockMinutes(h.HoraInicio)) * time.Minute)
+	closing := midnight.Add(time.Duration(clockMinutes(h.HoraFin)) * time.Minute)
+	return opening, closing
+}
+
+// Covers indica si una cita que inicia en start y dura duracionMinutos cabe
+// completa dentro de la jornada sin invadir el descanso
+func (h HorarioEspecialista) Covers(start time.Time, duracionMinutos int) bool {
+	if !h.Activo || int(start.Weekday()) != h.DiaSemana {
+		return false
+	}
+
+	startMin := start.Hour()*60 + start.Minute()
+	endMin := startMin + duracionMinutos
+
+	if startMin < clockMinutes(h.HoraInicio) || endMin > clockMinutes(h.HoraFin) {
+		return false
+	}
+
+	if h.DescansoInicio != nil && h.DescansoFin != nil {
+		breakStart := clockMinutes(*h.DescansoInicio)
+		breakEnd := clockMinutes(*h.DescansoFin)
+		if startMin < breakEnd && endMin > breakStart {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Tipos de bloqueo de agenda válidos
+var TiposBloqueo = []string{"vacaciones", "incapacidad", "feriado", "cierre", "otro"}
+
+// BloqueoAgenda periodo en el que no se pueden agendar citas (vacaciones, incapacidades, días feriados)
+type BloqueoAgenda struct {
+	ID             int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	EspecialistaID *int      `json:"especialista_id" gorm:"column:especialista_id"` // nil = cierre de toda la clínica
+	Tipo           string    `json:"tipo" gorm:"column:tipo;type:varchar(20);not null"`
+	FechaInicio    time.Time `json:"fecha_inicio" gorm:"column:fecha_inicio;not null"`
+	FechaFin       time.Time `json:"fecha_fin" gorm:"column:fecha_fin;not null"`
+	Motivo         string    `json:"motivo" gorm:"column:motivo;type:varchar(255)"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:fecha_creacion;autoCreateTime"`
+}
+
+// TableName especifica el nombre de la tabla
+func (BloqueoAgenda) TableName() string {
+	return "bloqueos_agenda"
+}
+
+// Overlaps indica si el bloqueo se traslapa con el rango [start, end)
+func (b BloqueoAgenda) Overlaps(start, end time.Time) bool {
+	return b.FechaInicio.Before(end) && b.FechaFin.After(start)
+}
+
+// BloqueoAgendaRequest estructura para crear o actualizar un bloqueo.
+// Las fechas aceptan "YYYY-MM-DD" (día completo) o "YYYY-MM-DD HH:MM"
+type BloqueoAgendaRequest struct {
+	EspecialistaID *int   `json:"especialista_id"`
+	Tipo           string `json:"tipo"`
+	FechaInicio    string `json:"fecha_inicio"`
+	FechaFin       string `json:"fecha_fin"`
+	Motivo         string `json:"motivo"`
+}
+
+// clockMinutes convierte una hora "HH:MM:SS" o "HH:MM" en minutos desde la medianoche
+func clockMinutes(value string) int {
+	t, err := time.Parse("15:04:05", value)
+	if err != nil {
+		if t, err = time.Parse("15:04", value); err != nil {
+			return 0
+		}
+	}
+	return t.Hour()*60 + t.Minute()
+}
@@ -0,0 +1,18 @@
+// backend/internal/models/login_attempt.go
+package models
+
+import "time"
+
+// IntentoLogin intento fallido de inicio de sesión. Se registra por email aunque la
+// cuenta no exista, para que el bloqueo no revele qué emails están registrados
+type IntentoLogin struct {
+	ID        int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Email     string    `json:"email" gorm:"column:email;type:varchar(255);not null"`
+	IP        string    `json:"ip" gorm:"column:ip;type:varchar(45)"`
+	CreatedAt time.Time `json:"fecha" gorm:"column:fecha;autoCreateTime"`
+}
+
+// TableName especifica el nombre de la tabla
+func (IntentoLogin) TableName() string {
+	return "intentos_login"
+}
@@ -0,0 +1,64 @@
+// backend/internal/models/mfa.go
+package models
+
+import "time"
+
+// CodigoRecuperacion código de un solo uso para entrar si se pierde la app de autenticación
+type CodigoRecuperacion struct {
+	ID         int        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	UsuarioID  int        `json:"usuario_id" gorm:"column:usuario_id;not null"`
+	CodigoHash string     `json:"-" gorm:"column:codigo_hash;type:varchar(64);not null"`
+	UsadoEn    *time.Time `json:"usado_en,omitempty" gorm:"column:usado_en"`
+	CreatedAt  time.Time  `json:"fecha_creacion" gorm:"column:fecha_creacion;autoCreateTime"`
+}
+
+// TableName especifica el nombre de la tabla
+func (CodigoRecuperacion) TableName() string {
+	return "codigos_recuperacion"
+}
+
+// MFAChallenge respuesta del login cuando la cuenta requiere segundo factor.
+// SetupRequired indica que la cuenta es de personal y aún debe activar 2FA
+type MFAChallenge struct {
+	MFARequired   bool   `json:"mfa_required"`
+	MFAToken      string `json:"mfa_token"`
+	SetupRequired bool   `json:"mfa_setup_required"`
+	ExpiresIn     int    `json:"expires_in"`
+}
+
+// MFAVerifyRequest segundo paso del login: código de la app o código de recuperación
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFASetupRequest inicia la activación de 2FA. MFAToken solo se envía cuando el personal
+// activa 2FA durante el login; con sesión iniciada basta el token de acceso
+type MFASetupRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+// MFASetupResponse secreto y URI otpauth para registrar la cuenta en la app de autenticación
+type MFASetupResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+// MFAEnableRequest confirma la activación con el primer código de la app
+type MFAEnableRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// MFAEnableResponse códigos de recuperación, que solo se muestran una vez. Session viene
+// cuando la activación completó un login
+type MFAEnableResponse struct {
+	RecoveryCodes []string      `json:"recovery_codes"`
+	Session       *AuthResponse `json:"session,omitempty"`
+}
+
+// MFADisableRequest desactiva 2FA confirmando con un código vigente
+type MFADisableRequest struct {
+	Code string `json:"code"`
+}
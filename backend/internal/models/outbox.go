@@ -0,0 +1,46 @@
+// backend/internal/models/outbox.go
+package models
+
+import "time"
+
+// Estados de un email en la bandeja de salida
+const (
+	EmailPendiente = "pendiente"
+	EmailEnviado   = "enviado"
+	EmailFallido   = "fallido" // agotó los reintentos; solo se reenvía a mano
+)
+
+// EmailSaliente email guardado en la bandeja de salida. Se escribe en la misma transacción
+// que el cambio que lo origina y un proceso en segundo plano lo entrega
+type EmailSaliente struct {
+	ID             int            `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Destinatario   string         `json:"destinatario" gorm:"column:destinatario;type:varchar(255);not null"`
+	Asunto         string         `json:"asunto" gorm:"column:asunto;type:varchar(255);not null"`
+	Cuerpo         string         `json:"-" gorm:"column:cuerpo;type:mediumtext;not null"` // puede llevar tokens de un solo uso
+	CuerpoTexto    string         `json:"-" gorm:"column:cuerpo_texto;type:mediumtext"`    // alternativa en texto plano
+	Estado         string         `json:"estado" gorm:"column:estado;type:varchar(20);default:pendiente"`
+	Intentos       int            `json:"intentos" gorm:"column:intentos;default:0"`
+	ProximoIntento time.Time      `json:"proximo_intento" gorm:"column:proximo_intento;not null"`
+	ReclamadoHasta *time.Time     `json:"-" gorm:"column:reclamado_hasta"`
+	UltimoError    *string        `json:"ultimo_error,omitempty" gorm:"column:ultimo_error;type:text"`
+	CreatedAt      time.Time      `json:"fecha_creacion" gorm:"column:fecha_creacion;autoCreateTime"`
+	EnviadoEn      *time.Time     `json:"enviado_en,omitempty" gorm:"column:enviado_en"`
+	Adjuntos       []AdjuntoEmail `json:"adjuntos,omitempty" gorm:"foreignKey:EmailID"`
+}
+
+func (EmailSaliente) TableName() string {
+	return "emails_salientes"
+}
+
+// AdjuntoEmail archivo adjunto de un email de la bandeja de salida, p. ej. una invitación .ics
+type AdjuntoEmail struct {
+	ID            int    `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
+	EmailID       int    `json:"-" gorm:"column:email_id;not null"`
+	Nombre        string `json:"nombre" gorm:"column:nombre;type:varchar(255);not null"`
+	TipoContenido string `json:"tipo_contenido" gorm:"column:tipo_contenido;type:varchar(255);not null"`
+	Contenido     []byte `json:"-" gorm:"column:contenido;type:mediumblob;not null"`
+}
+
+func (AdjuntoEmail) TableName() string {
+	return "emails_adjuntos"
+}
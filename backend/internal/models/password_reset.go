@@ -0,0 +1,31 @@
+// backend/internal/models/password_reset.go
+package models
+
+import "time"
+
+// RestablecimientoPassword token de un solo uso para restablecer la contraseña.
+// Solo se guarda el hash; el token en claro viaja únicamente en el email
+type RestablecimientoPassword struct {
+	ID        int        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	UsuarioID int        `json:"usuario_id" gorm:"column:usuario_id;not null"`
+	TokenHash string     `json:"-" gorm:"column:token_hash;type:varchar(64);uniqueIndex;not null"`
+	ExpiraEn  time.Time  `json:"expira_en" gorm:"column:expira_en;not null"`
+	UsadoEn   *time.Time `json:"usado_en,omitempty" gorm:"column:usado_en"`
+	CreatedAt time.Time  `json:"fecha_creacion" gorm:"column:fecha_creacion;autoCreateTime"`
+}
+
+// TableName especifica el nombre de la tabla
+func (RestablecimientoPassword) TableName() string {
+	return "restablecimientos_password"
+}
+
+// ForgotPasswordRequest estructura para solicitar el restablecimiento de contraseña
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest estructura para fijar una nueva contraseña con el token del email
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,28 @@
+// backend/internal/models/patient.go
+package models
+
+// Parentesco de un expediente con la cuenta que lo administra
+const (
+	ParentescoTitular = "titular"
+	ParentescoHijo    = "hijo"
+	ParentescoPadre   = "padre"
+	ParentescoMadre   = "madre"
+	ParentescoPareja  = "pareja"
+	ParentescoOtro    = "otro"
+)
+
+// ParentescosDependiente parentescos válidos para los dependientes de una cuenta
+var ParentescosDependiente = []string{ParentescoHijo, ParentescoPadre, ParentescoMadre, ParentescoPareja, ParentescoOtro}
+
+// PacienteRequest estructura para registrar o editar un dependiente. En el expediente del
+// titular solo se usan apellido materno, fecha de nacimiento y sexo; el resto se toma de la cuenta
+type PacienteRequest struct {
+	Nombre          string `json:"nombre"`
+	ApellidoPaterno string `json:"apellido_paterno"`
+	ApellidoMaterno string `json:"apellido_materno"`
+	FechaNacimiento string `json:"fecha_nacimiento"` // YYYY-MM-DD
+	Sexo            string `json:"sexo"`             // M | F | O
+	Parentesco      string `json:"parentesco"`
+	Telefono        string `json:"telefono"`
+	Email           string `json:"email"`
+}
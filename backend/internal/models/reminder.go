@@ -0,0 +1,19 @@
+// backend/internal/models/reminder.go
+package models
+
+import "time"
+
+// RecordatorioCita recordatorio de una cita con cierta anticipación. Se reclama antes de
+// enviarlo para que otra réplica o un reinicio no lo envíe de nuevo
+type RecordatorioCita struct {
+	ID                  int        `gorm:"column:id;primaryKey;autoIncrement"`
+	CitaID              int        `gorm:"column:cita_id;not null"`
+	AnticipacionMinutos int        `gorm:"column:anticipacion_minutos;not null"`
+	FechaCita           time.Time  `gorm:"column:fecha_cita;not null"` // fecha_hora de la cita al reclamarlo
+	ReclamadoEn         time.Time  `gorm:"column:reclamado_en;not null"`
+	EnviadoEn           *time.Time `gorm:"column:enviado_en"`
+}
+
+func (RecordatorioCita) TableName() string {
+	return "recordatorios_cita"
+}
@@ -0,0 +1,36 @@
+// backend/internal/models/session.go
+package models
+
+import "time"
+
+// Sesion refresh token emitido a un dispositivo. Cada inicio de sesión abre una familia;
+// al rotar el refresh token se crea un registro nuevo en la misma familia y el anterior
+// queda marcado como usado
+type Sesion struct {
+	ID          int        `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
+	UsuarioID   int        `json:"-" gorm:"column:usuario_id;not null"`
+	FamiliaID   string     `json:"id" gorm:"column:familia_id;type:varchar(64);not null"`
+	TokenHash   string     `json:"-" gorm:"column:token_hash;type:varchar(64);uniqueIndex;not null"`
+	Dispositivo string     `json:"dispositivo" gorm:"column:dispositivo;type:varchar(255)"`
+	IP          string     `json:"ip" gorm:"column:ip;type:varchar(45)"`
+	ExpiraEn    time.Time  `json:"expira_en" gorm:"column:expira_en;not null"`
+	UsadoEn     *time.Time `json:"-" gorm:"column:usado_en"`
+	RevocadoEn  *time.Time `json:"-" gorm:"column:revocado_en"`
+	CreatedAt   time.Time  `json:"ultimo_uso" gorm:"column:fecha_creacion;autoCreateTime"`
+}
+
+// TableName especifica el nombre de la tabla
+func (Sesion) TableName() string {
+	return "sesiones"
+}
+
+// DeviceInfo datos del dispositivo que inicia o renueva una sesión
+type DeviceInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// RefreshRequest estructura para renovar el token de acceso
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -3,14 +3,42 @@ package models
 
 import "time"
 
+// Roles de usuario
+const (
+	RolPaciente     = "paciente"
+	RolEspecialista = "especialista"
+	RolRecepcion    = "recepcion"
+	RolAdmin        = "admin"
+)
+
+// Roles lista de roles válidos
+var Roles = []string{RolPaciente, RolEspecialista, RolRecepcion, RolAdmin}
+
+// Idiomas en que se envían los emails
+const (
+	IdiomaEs = "es"
+	IdiomaEn = "en"
+)
+
+// Idiomas lista de idiomas válidos; el primero es el predeterminado
+var Idiomas = []string{IdiomaEs, IdiomaEn}
+
 type User struct {
-	ID        int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
-	Nombre    string    `json:"nombre" gorm:"column:nombre;type:varchar(100);not null"`
-	Apellido  string    `json:"apellido" gorm:"column:apellido;type:varchar(100);not null"`
-	Email     string    `json:"email" gorm:"column:email;type:varchar(255);uniqueIndex;not null"`
-	Password  string    `json:"-" gorm:"column:password;type:varchar(255);not null"`
-	Telefono  string    `json:"telefono,omitempty" gorm:"column:telefono;type:varchar(20)"`
-	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	ID              int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Nombre          string    `json:"nombre" gorm:"column:nombre;type:varchar(100);not null"`
+	Apellido        string    `json:"apellido" gorm:"column:apellido;type:varchar(100);not null"`
+	Email           string    `json:"email" gorm:"column:email;type:varchar(255);uniqueIndex;not null"`
+	Password        string    `json:"-" gorm:"column:password;type:varchar(255);not null"`
+	Telefono        string    `json:"telefono,omitempty" gorm:"column:telefono;type:varchar(20)"`
+	Rol             string    `json:"rol" gorm:"column:rol;type:varchar(20);default:paciente"`
+	EspecialistaID  *int      `json:"especialista_id,omitempty" gorm:"column:especialista_id"` // solo cuentas con rol especialista
+	PacienteID      *int      `json:"paciente_id,omitempty" gorm:"column:paciente_id"`         // expediente propio de la cuenta
+	EmailVerificado bool      `json:"email_verificado" gorm:"column:email_verificado;default:false"`
+	Idioma          string    `json:"idioma" gorm:"column:idioma;type:varchar(5);default:es"` // idioma de los emails
+	TOTPSecreto     string    `json:"-" gorm:"column:totp_secreto;type:varchar(64)"`
+	TOTPActivo      bool      `json:"totp_activo" gorm:"column:totp_activo;default:false"`
+	TOTPUltimoPaso  int64     `json:"-" gorm:"column:totp_ultimo_paso;default:0"` // último paso TOTP aceptado; evita reutilizar códigos
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
 }
 
 // TableName especifica el nombre de la tabla
@@ -25,6 +53,7 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Telefono string `json:"telefono,omitempty"`
+	Idioma   string `json:"idioma,omitempty"`
 }
 
 // LoginRequest estructura para el login
@@ -33,8 +62,39 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// UpdateRoleRequest estructura para que un administrador cambie el rol de un usuario.
+// EspecialistaID es requerido cuando el rol es especialista
+type UpdateRoleRequest struct {
+	Rol            string `json:"rol"`
+	EspecialistaID *int   `json:"especialista_id"`
+}
+
+// UpdateProfileRequest estructura para que el usuario edite su perfil. Los campos nulos no
+// cambian; cambiar el email requiere la contraseña actual y vuelve a pedir verificación
+type UpdateProfileRequest struct {
+	Nombre         *string `json:"nombre"`
+	Apellido       *string `json:"apellido"`
+	Telefono       *string `json:"telefono"`
+	Email          *string `json:"email"`
+	Idioma         *string `json:"idioma"`
+	PasswordActual string  `json:"password_actual"`
+}
+
+// ChangePasswordRequest estructura para cambiar la contraseña
+type ChangePasswordRequest struct {
+	PasswordActual string `json:"password_actual"`
+	PasswordNuevo  string `json:"password_nuevo"`
+}
+
+// DeleteAccountRequest estructura para eliminar la cuenta; pide la contraseña como confirmación
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
 // AuthResponse respuesta de autenticación
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // segundos de vigencia del token de acceso
+	User         User   `json:"user"`
 }
@@ -2,6 +2,7 @@
 package repositories
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,13 +10,34 @@ import (
 	"gorm.io/gorm"
 )
 
+// errStaleAppointment revierte una transacción cuando la cita cambió de estado mientras se procesaba
+var errStaleAppointment = errors.New("la cita cambió de estado")
+
 type AppointmentRepository struct {
-	db *gorm.DB
+	db           *gorm.DB
+	scheduleRepo *ScheduleRepository
 }
 
 // NewAppointmentRepository crea una nueva instancia del repositorio
-func NewAppointmentRepository(db *gorm.DB) *AppointmentRepository {
-	return &AppointmentRepository{db: db}
+func NewAppointmentRepository(db *gorm.DB, scheduleRepo *ScheduleRepository) *AppointmentRepository {
+	return &AppointmentRepository{
+		db:           db,
+		scheduleRepo: scheduleRepo,
+	}
+}
+
+// Transaction ejecuta fn con una copia del repositorio que opera dentro de una transacción.
+// Si fn regresa error se descartan todos sus cambios, incluidos los emails encolados
+func (r *AppointmentRepository) Transaction(fn func(repo *AppointmentRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&AppointmentRepository{db: tx, scheduleRepo: r.scheduleRepo})
+	})
+}
+
+// EnqueueEmails guarda emails en la bandeja de salida; dentro de Transaction se confirman
+// junto con el cambio de la cita
+func (r *AppointmentRepository) EnqueueEmails(emails ...*models.EmailSaliente) error {
+	return enqueueEmails(r.db, emails)
 }
 
 // Create inserta una nueva cita en la base de datos
@@ -45,19 +67,24 @@ func (r *AppointmentRepository) FindByID(id int) (*models.AppointmentWithDetails
 	err := r.db.Raw(`
 		SELECT 
 			c.id,
+			c.especialista_id,
 			CONCAT(p.nombre, ' ', p.apellido_paterno, ' ', COALESCE(p.apellido_materno, '')) as nombre_paciente,
-			p.email as email_paciente,
-			p.telefono as telefono_paciente,
+			COALESCE(p.email, u.email, '') as email_paciente,
+			COALESCE(p.telefono, u.telefono, '') as telefono_paciente,
 			CONCAT(e.nombre, ' ', e.apellido_paterno) as nombre_especialista,
 			e.email as email_especialista,
 			es.nombre as especialidad,
 			t.nombre as tratamiento,
 			c.fecha_hora,
+			c.duracion_minutos,
 			COALESCE(c.motivo, '') as motivo,
 			c.estado,
-			c.fecha_creacion as created_at
+			c.fecha_creacion as created_at,
+			COALESCE(u.idioma, 'es') as idioma_paciente,
+			COALESCE((SELECT ue.idioma FROM usuarios ue WHERE ue.especialista_id = e.id), 'es') as idioma_especialista
 		FROM citas c
 		JOIN pacientes p ON c.paciente_id = p.id
+		LEFT JOIN usuarios u ON p.usuario_id = u.id -- los dependientes sin email reciben avisos en la cuenta
 		JOIN especialistas e ON c.especialista_id = e.id
 		JOIN especialidades es ON e.especialidad_id = es.id
 		JOIN tratamientos t ON c.tratamiento_id = t.id
@@ -79,22 +106,18 @@ func (r *AppointmentRepository) FindByID(id int) (*models.AppointmentWithDetails
 	return &result, nil
 }
 
-// FindByUserID obtiene todas las citas de un usuario
+// FindByUserID obtiene las citas de los expedientes que administra el usuario: el suyo y
+// los de sus dependientes
 func (r *AppointmentRepository) FindByUserID(userID int) ([]models.AppointmentResponse, error) {
-	// Primero obtenemos el email del usuario
-	var user models.User
-	if err := r.db.First(&user, userID).Error; err != nil {
-		return nil, fmt.Errorf("error al obtener usuario: %v", err)
-	}
-
 	var appointments []models.AppointmentResponse
 
 	err := r.db.Raw(`
 		SELECT 
 			c.id,
+			c.paciente_id,
 			CONCAT(p.nombre, ' ', p.apellido_paterno, ' ', COALESCE(p.apellido_materno, '')) as nombre_paciente,
-			p.telefono,
-			p.email,
+			COALESCE(p.telefono, '') as telefono,
+			COALESCE(p.email, '') as email,
 			t.nombre as servicio,
 			DATE(c.fecha_hora) as fecha_cita,
 			TIME_FORMAT(c.fecha_hora, '%H:%i') as hora_cita,
@@ -104,9 +127,9 @@ func (r *AppointmentRepository) FindByUserID(userID int) ([]models.AppointmentRe
 		FROM citas c
 		JOIN pacientes p ON c.paciente_id = p.id
 		JOIN tratamientos t ON c.tratamiento_id = t.id
-		WHERE p.email = ?
+		WHERE p.usuario_id = ?
 		ORDER BY c.fecha_hora DESC
-	`, user.Email).Scan(&appointments).Error
+	`, userID).Scan(&appointments).Error
 
 	if err != nil {
 		return nil, fmt.Errorf("error al obtener citas: %v", err)
@@ -120,20 +143,59 @@ func (r *AppointmentRepository) FindByUserID(userID int) ([]models.AppointmentRe
 	return appointments, nil
 }
 
+// IsOwnedByUser indica si la cita es de un expediente que administra la cuenta del usuario
+func (r *AppointmentRepository) IsOwnedByUser(citaID, userID int) (bool, error) {
+	var count int64
+
+	err := r.db.Table("citas c").
+		Joins("JOIN pacientes p ON c.paciente_id = p.id").
+		Where("c.id = ? AND p.usuario_id = ?", citaID, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error al verificar la cita: %v", err)
+	}
+
+	return count > 0, nil
+}
+
 // CheckAvailability verifica si un especialista está disponible
 func (r *AppointmentRepository) CheckAvailability(especialistaID int, fechaHora time.Time, duracionMinutos int) (bool, error) {
+	return r.CheckAvailabilityExcluding(especialistaID, fechaHora, duracionMinutos, 0)
+}
+
+// CheckAvailabilityExcluding verifica la disponibilidad ignorando la cita excludeID (al reagendarla)
+func (r *AppointmentRepository) CheckAvailabilityExcluding(especialistaID int, fechaHora time.Time, duracionMinutos int, excludeID int) (bool, error) {
+	// La cita debe caer completa dentro de la jornada del especialista
+	horario, err := r.scheduleRepo.FindByEspecialistaAndDay(especialistaID, fechaHora.Weekday())
+	if err != nil {
+		return false, fmt.Errorf("error al verificar disponibilidad: %v", err)
+	}
+
+	if horario == nil || !horario.Covers(fechaHora, duracionMinutos) {
+		return false, nil
+	}
+
 	// Calcula el rango de tiempo de la cita
 	startTime := fechaHora
 	endTime := fechaHora.Add(time.Duration(duracionMinutos) * time.Minute)
 
-	// Usar una query más simple y efectiva
+	// Vacaciones, incapacidades y cierres de la clínica
+	bloqueos, err := r.scheduleRepo.FindBlocks(especialistaID, startTime, endTime)
+	if err != nil {
+		return false, fmt.Errorf("error al verificar disponibilidad: %v", err)
+	}
+
+	if len(bloqueos) > 0 {
+		return false, nil
+	}
+
 	var count int64
-	err := r.db.Model(&models.Appointment{}).
-		Where("especialista_id = ?", especialistaID).
-		Where("estado NOT IN ?", []string{"cancelada", "completada"}).
-		Where(r.db.Where("fecha_hora < ? AND DATE_ADD(fecha_hora, INTERVAL duracion_minutos MINUTE) > ?", endTime, startTime).
-			Or("fecha_hora >= ? AND fecha_hora < ?", startTime, endTime)).
-		Count(&count).Error
+	query := r.overlappingQuery(especialistaID, startTime, endTime)
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+
+	err = query.Count(&count).Error
 
 	if err != nil {
 		return false, fmt.Errorf("error al verificar disponibilidad: %v", err)
@@ -143,47 +205,243 @@ func (r *AppointmentRepository) CheckAvailability(especialistaID int, fechaHora
 	return count == 0, nil
 }
 
-// UpdateStatus actualiza el estado de una cita
-func (r *AppointmentRepository) UpdateStatus(id int, newStatus string) error {
-	result := r.db.Model(&models.Appointment{}).
-		Where("id = ?", id).
-		Update("estado", newStatus)
+// FindOverlapping obtiene las citas activas de un especialista que se traslapan con un rango
+func (r *AppointmentRepository) FindOverlapping(especialistaID int, startTime, endTime time.Time) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+
+	err := r.overlappingQuery(especialistaID, startTime, endTime).
+		Order("fecha_hora ASC").
+		Find(&appointments).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener citas del especialista: %v", err)
+	}
+
+	return appointments, nil
+}
+
+// overlappingQuery construye la consulta de citas activas que ocupan parte del rango [startTime, endTime)
+func (r *AppointmentRepository) overlappingQuery(especialistaID int, startTime, endTime time.Time) *gorm.DB {
+	return r.db.Model(&models.Appointment{}).
+		Where("especialista_id = ?", especialistaID).
+		Where("estado NOT IN ?", []string{models.EstadoCancelada, models.EstadoCompletada}).
+		Where(r.db.Where("fecha_hora < ? AND DATE_ADD(fecha_hora, INTERVAL duracion_minutos MINUTE) > ?", endTime, startTime).
+			Or("fecha_hora >= ? AND fecha_hora < ?", startTime, endTime))
+}
+
+// FindAppointment obtiene la fila de una cita sin detalles adicionales
+func (r *AppointmentRepository) FindAppointment(id int) (*models.Appointment, error) {
+	var appointment models.Appointment
+	result := r.db.First(&appointment, id)
 
 	if result.Error != nil {
-		return fmt.Errorf("error al actualizar estado: %v", result.Error)
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("cita no encontrada")
+		}
+		return nil, fmt.Errorf("error al buscar cita: %v", result.Error)
+	}
+
+	return &appointment, nil
+}
+
+// Reschedule mueve una cita a una nueva fecha y la regresa a estado programada, solo si
+// sigue en el estado anterior del cambio. Retorna false si la cita cambió de estado mientras tanto
+func (r *AppointmentRepository) Reschedule(fechaHora time.Time, cambio *models.CambioEstadoCita) (bool, error) {
+	return r.applyStatusChange(cambio, map[string]interface{}{
+		"fecha_hora": fechaHora,
+		"estado":     cambio.EstadoNuevo,
+	})
+}
+
+// UpdateStatusFrom cambia el estado de una cita solo si sigue en el estado anterior del cambio
+// y registra el cambio en el historial. Si notas no es nil también se guardan en la cita.
+// Retorna false si la cita cambió de estado entre la lectura y la escritura
+func (r *AppointmentRepository) UpdateStatusFrom(cambio *models.CambioEstadoCita, notas *string) (bool, error) {
+	updates := map[string]interface{}{
+		"estado": cambio.EstadoNuevo,
+	}
+	if notas != nil {
+		updates["notas"] = *notas
+	}
+
+	return r.applyStatusChange(cambio, updates)
+}
+
+// applyStatusChange actualiza la cita y registra el cambio de estado en una sola transacción
+func (r *AppointmentRepository) applyStatusChange(cambio *models.CambioEstadoCita, updates map[string]interface{}) (bool, error) {
+	updated := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, err = applyStatusChangeTx(tx, cambio, updates)
+		return err
+	})
+
+	return updated, err
+}
+
+func applyStatusChangeTx(tx *gorm.DB, cambio *models.CambioEstadoCita, updates map[string]interface{}) (bool, error) {
+	result := tx.Model(&models.Appointment{}).
+		Where("id = ? AND estado = ?", cambio.CitaID, cambio.EstadoAnterior).
+		Updates(updates)
+
+	if result.Error != nil {
+		return false, fmt.Errorf("error al actualizar estado: %v", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("cita no encontrada")
+		return false, nil
+	}
+
+	if err := tx.Create(cambio).Error; err != nil {
+		return false, fmt.Errorf("error al registrar cambio de estado: %v", err)
+	}
+
+	return true, nil
+}
+
+// CreateProposals guarda los horarios propuestos para una cita y descarta las propuestas
+// pendientes anteriores, de modo que solo la última respuesta del especialista sea válida
+func (r *AppointmentRepository) CreateProposals(citaID int, propuestas []models.PropuestaHorario) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.PropuestaHorario{}).
+			Where("cita_id = ? AND estado = ?", citaID, models.PropuestaPendiente).
+			Update("estado", models.PropuestaDescartada).Error
+		if err != nil {
+			return fmt.Errorf("error al descartar propuestas anteriores: %v", err)
+		}
+
+		if err := tx.Create(&propuestas).Error; err != nil {
+			return fmt.Errorf("error al guardar propuestas: %v", err)
+		}
+
+		return nil
+	})
+}
+
+// FindProposal busca un horario propuesto por ID
+func (r *AppointmentRepository) FindProposal(id int) (*models.PropuestaHorario, error) {
+	var propuesta models.PropuestaHorario
+
+	err := r.db.First(&propuesta, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("propuesta no encontrada")
+		}
+		return nil, fmt.Errorf("error al buscar propuesta: %v", err)
+	}
+
+	return &propuesta, nil
+}
+
+// RescheduleToProposal mueve la cita al horario de una propuesta pendiente, la marca como
+// aceptada y descarta las demás propuestas de la cita en una sola transacción.
+// Retorna false si la propuesta ya no está pendiente o la cita cambió de estado
+func (r *AppointmentRepository) RescheduleToProposal(propuesta *models.PropuestaHorario, cambio *models.CambioEstadoCita) (bool, error) {
+	updated := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PropuestaHorario{}).
+			Where("id = ? AND cita_id = ? AND estado = ?", propuesta.ID, propuesta.CitaID, models.PropuestaPendiente).
+			Update("estado", models.PropuestaAceptada)
+
+		if result.Error != nil {
+			return fmt.Errorf("error al aceptar propuesta: %v", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		err := tx.Model(&models.PropuestaHorario{}).
+			Where("cita_id = ? AND id <> ? AND estado = ?", propuesta.CitaID, propuesta.ID, models.PropuestaPendiente).
+			Update("estado", models.PropuestaDescartada).Error
+		if err != nil {
+			return fmt.Errorf("error al descartar propuestas: %v", err)
+		}
+
+		rescheduled, err := applyStatusChangeTx(tx, cambio, map[string]interface{}{
+			"fecha_hora": propuesta.FechaHora,
+			"estado":     cambio.EstadoNuevo,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !rescheduled {
+			return errStaleAppointment
+		}
+
+		updated = true
+		return nil
+	})
+
+	if errors.Is(err, errStaleAppointment) {
+		return false, nil
 	}
 
+	return updated, err
+}
+
+// CreateLink registra un enlace firmado emitido para una cita
+func (r *AppointmentRepository) CreateLink(enlace *models.EnlaceCita) error {
+	if err := r.db.Create(enlace).Error; err != nil {
+		return fmt.Errorf("error al registrar enlace: %v", err)
+	}
 	return nil
 }
 
-// Delete elimina (cancela) una cita
-func (r *AppointmentRepository) Delete(id int) error {
-	// En lugar de eliminar, cambiamos el estado a cancelada
-	return r.UpdateStatus(id, "cancelada")
+// ConsumeLink marca un enlace como usado. Retorna false si no existe, no corresponde
+// a la cita y acción indicadas, ya expiró o ya había sido usado
+func (r *AppointmentRepository) ConsumeLink(jti string, citaID int, accion string) (bool, error) {
+	now := time.Now()
+
+	result := r.db.Model(&models.EnlaceCita{}).
+		Where("jti = ? AND cita_id = ? AND accion = ?", jti, citaID, accion).
+		Where("usado_en IS NULL AND expira_en > ?", now).
+		Update("usado_en", now)
+
+	if result.Error != nil {
+		return false, fmt.Errorf("error al validar enlace: %v", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
 }
 
-// FindEspecialistaByServicio encuentra un especialista disponible para un servicio
-// MODIFICADO: Ahora busca por especialidad en lugar de tratamiento exacto
-func (r *AppointmentRepository) FindEspecialistaByServicio(servicio string) (*struct {
-	ID              int
-	Nombre          string
-	Email           string
-	EspecialidadID  int
-	TratamientoID   int
-	DuracionMinutos int
-}, error) {
-	result := &struct {
-		ID              int
-		Nombre          string
-		Email           string
-		EspecialidadID  int
-		TratamientoID   int
-		DuracionMinutos int
-	}{}
+// FindStatusHistory obtiene el historial de cambios de estado de una cita
+func (r *AppointmentRepository) FindStatusHistory(citaID int) ([]models.CambioEstadoCita, error) {
+	var cambios []models.CambioEstadoCita
+
+	err := r.db.Where("cita_id = ?", citaID).Order("fecha ASC, id ASC").Find(&cambios).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener historial de la cita: %v", err)
+	}
+
+	return cambios, nil
+}
+
+// FindEspecialistaByUserID obtiene el especialista vinculado a una cuenta con rol especialista
+func (r *AppointmentRepository) FindEspecialistaByUserID(userID int) (*models.Especialista, error) {
+	var especialista models.Especialista
+
+	result := r.db.Model(&models.Especialista{}).
+		Joins("JOIN usuarios u ON u.especialista_id = especialistas.id").
+		Where("u.id = ? AND u.rol = ?", userID, models.RolEspecialista).
+		First(&especialista)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al buscar especialista: %v", result.Error)
+	}
+
+	return &especialista, nil
+}
+
+// FindEspecialistasByServicio obtiene todos los especialistas activos que pueden atender un servicio
+func (r *AppointmentRepository) FindEspecialistasByServicio(servicio string) ([]models.EspecialistaServicio, error) {
+	var rows []models.EspecialistaServicio
 
 	// Primero intentamos buscar por nombre exacto del tratamiento
 	err := r.db.Raw(`
@@ -193,15 +451,16 @@ func (r *AppointmentRepository) FindEspecialistaByServicio(servicio string) (*st
 			e.email,
 			t.especialidad_id,
 			t.id as tratamiento_id,
+			t.nombre as tratamiento,
 			t.duracion_estimada_minutos as duracion_minutos
 		FROM especialistas e
 		JOIN tratamientos t ON t.especialidad_id = e.especialidad_id
 		WHERE t.nombre = ? AND e.activo = TRUE AND t.activo = TRUE
-		LIMIT 1
-	`, servicio).Scan(result).Error
+		ORDER BY e.id
+	`, servicio).Scan(&rows).Error
 
 	// Si no se encuentra por nombre de tratamiento, buscar por nombre de especialidad
-	if err != nil || result.ID == 0 {
+	if err != nil || len(rows) == 0 {
 		err = r.db.Raw(`
 			SELECT 
 				e.id,
@@ -209,64 +468,172 @@ func (r *AppointmentRepository) FindEspecialistaByServicio(servicio string) (*st
 				e.email,
 				es.id as especialidad_id,
 				t.id as tratamiento_id,
+				t.nombre as tratamiento,
 				t.duracion_estimada_minutos as duracion_minutos
 			FROM especialistas e
 			JOIN especialidades es ON es.id = e.especialidad_id
 			JOIN tratamientos t ON t.especialidad_id = es.id
 			WHERE es.nombre = ? AND e.activo = TRUE AND t.activo = TRUE
-			LIMIT 1
-		`, servicio).Scan(result).Error
+			ORDER BY e.id, t.id
+		`, servicio).Scan(&rows).Error
 
 		if err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, fmt.Errorf("no se encontró especialista para el servicio: %s", servicio)
-			}
 			return nil, fmt.Errorf("error al buscar especialista: %v", err)
 		}
 	}
 
-	// Verificar si se encontró un resultado
-	if result.ID == 0 {
+	// Al buscar por especialidad hay una fila por tratamiento; nos quedamos con el primero de cada especialista
+	especialistas := []models.EspecialistaServicio{}
+	seen := make(map[int]bool)
+	for _, row := range rows {
+		if row.ID == 0 || seen[row.ID] {
+			continue
+		}
+		seen[row.ID] = true
+		especialistas = append(especialistas, row)
+	}
+
+	if len(especialistas) == 0 {
 		return nil, fmt.Errorf("no se encontró especialista para el servicio: %s", servicio)
 	}
 
-	return result, nil
+	return especialistas, nil
 }
 
-// CreateOrGetPaciente crea un paciente si no existe o lo obtiene si ya existe
-func (r *AppointmentRepository) CreateOrGetPaciente(nombre, apellido, email, telefono string) (int, error) {
-	// Primero intenta buscar el paciente por email
-	var paciente models.Paciente
-	err := r.db.Where("email = ?", email).First(&paciente).Error
-
-	if err == nil {
-		// El paciente ya existe, actualizamos su información
-		r.db.Model(&paciente).Updates(map[string]interface{}{
-			"nombre":           nombre,
-			"apellido_paterno": apellido,
-			"telefono":         telefono,
-		})
-		return paciente.ID, nil
+// FindEspecialistasByTratamiento obtiene los especialistas activos de la especialidad de un tratamiento
+func (r *AppointmentRepository) FindEspecialistasByTratamiento(tratamiento *models.Tratamiento) ([]models.EspecialistaServicio, error) {
+	var especialistas []models.EspecialistaServicio
+
+	err := r.db.Raw(`
+		SELECT 
+			e.id,
+			CONCAT(e.nombre, ' ', e.apellido_paterno) as nombre,
+			e.email,
+			e.especialidad_id
+		FROM especialistas e
+		WHERE e.especialidad_id = ? AND e.activo = TRUE
+		ORDER BY e.id
+	`, tratamiento.EspecialidadID).Scan(&especialistas).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar especialista: %v", err)
 	}
 
-	if err != gorm.ErrRecordNotFound {
-		return 0, fmt.Errorf("error al buscar paciente: %v", err)
+	if len(especialistas) == 0 {
+		return nil, fmt.Errorf("no se encontró especialista para el tratamiento: %s", tratamiento.Nombre)
 	}
 
-	// El paciente no existe, lo creamos
-	newPaciente := models.Paciente{
-		Nombre:          nombre,
-		ApellidoPaterno: apellido,
-		Email:           email,
-		Telefono:        telefono,
-		FechaNacimiento: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
-		Sexo:            "O",
+	for i := range especialistas {
+		especialistas[i].TratamientoID = tratamiento.ID
+		especialistas[i].Tratamiento = tratamiento.Nombre
+		especialistas[i].DuracionMinutos = tratamiento.DuracionEstimadaMinutos
 	}
 
-	result := r.db.Create(&newPaciente)
+	return especialistas, nil
+}
+
+// FindTratamientoByID busca un tratamiento por su ID
+func (r *AppointmentRepository) FindTratamientoByID(id int) (*models.Tratamiento, error) {
+	var tratamiento models.Tratamiento
+	result := r.db.First(&tratamiento, id)
+
 	if result.Error != nil {
-		return 0, fmt.Errorf("error al crear paciente: %v", result.Error)
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al buscar tratamiento: %v", result.Error)
+	}
+
+	return &tratamiento, nil
+}
+
+// FindEspecialistaByID busca un especialista por su ID
+func (r *AppointmentRepository) FindEspecialistaByID(id int) (*models.Especialista, error) {
+	var especialista models.Especialista
+	result := r.db.First(&especialista, id)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al buscar especialista: %v", result.Error)
+	}
+
+	return &especialista, nil
+}
+
+// CountByEspecialistaOnDate cuenta las citas no canceladas de cada especialista en el día indicado
+func (r *AppointmentRepository) CountByEspecialistaOnDate(especialistaIDs []int, day time.Time) (map[int]int64, error) {
+	var rows []struct {
+		EspecialistaID int
+		Total          int64
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	err := r.db.Model(&models.Appointment{}).
+		Select("especialista_id, COUNT(*) as total").
+		Where("especialista_id IN ?", especialistaIDs).
+		Where("estado <> ?", models.EstadoCancelada).
+		Where("fecha_hora >= ? AND fecha_hora < ?", start, end).
+		Group("especialista_id").
+		Scan(&rows).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error al contar citas por especialista: %v", err)
+	}
+
+	totals := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		totals[row.EspecialistaID] = row.Total
+	}
+
+	return totals, nil
+}
+
+// LastAssignmentByEspecialista obtiene la fecha en que se asignó la última cita a cada especialista
+func (r *AppointmentRepository) LastAssignmentByEspecialista(especialistaIDs []int) (map[int]time.Time, error) {
+	var rows []struct {
+		EspecialistaID int
+		Ultima         time.Time
+	}
+
+	err := r.db.Model(&models.Appointment{}).
+		Select("especialista_id, MAX(fecha_creacion) as ultima").
+		Where("especialista_id IN ?", especialistaIDs).
+		Group("especialista_id").
+		Scan(&rows).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener últimas asignaciones: %v", err)
+	}
+
+	last := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		last[row.EspecialistaID] = row.Ultima
+	}
+
+	return last, nil
+}
+
+// FindLastEspecialistaForPaciente obtiene el especialista de la cita más reciente del paciente
+// entre los candidatos dados. Retorna 0 si el paciente no ha sido atendido por ninguno
+func (r *AppointmentRepository) FindLastEspecialistaForPaciente(pacienteID int, especialistaIDs []int) (int, error) {
+	var appointment models.Appointment
+
+	err := r.db.Where("paciente_id = ?", pacienteID).
+		Where("especialista_id IN ?", especialistaIDs).
+		Where("estado <> ?", models.EstadoCancelada).
+		Order("fecha_hora DESC").
+		First(&appointment).Error
+
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error al buscar especialista previo: %v", err)
 	}
 
-	return newPaciente.ID, nil
+	return appointment.EspecialistaID, nil
 }
@@ -0,0 +1,42 @@
+// backend/internal/repositories/email_verification_repository.go
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type EmailVerificationRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailVerificationRepository crea una nueva instancia del repositorio
+func NewEmailVerificationRepository(db *gorm.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+// RecordSend registra el envío de un email de verificación y lo encola en la misma transacción
+func (r *EmailVerificationRepository) RecordSend(usuarioID int, emails ...*models.EmailSaliente) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.EnvioVerificacion{UsuarioID: usuarioID}).Error; err != nil {
+			return fmt.Errorf("error al registrar envío de verificación: %v", err)
+		}
+
+		return enqueueEmails(tx, emails)
+	})
+}
+
+// FindSendsSince obtiene los envíos de verificación del usuario desde una fecha, del más reciente al más antiguo
+func (r *EmailVerificationRepository) FindSendsSince(usuarioID int, since time.Time) ([]models.EnvioVerificacion, error) {
+	var envios []models.EnvioVerificacion
+	err := r.db.Where("usuario_id = ? AND fecha_envio >= ?", usuarioID, since).
+		Order("fecha_envio DESC").
+		Find(&envios).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener envíos de verificación: %v", err)
+	}
+	return envios, nil
+}
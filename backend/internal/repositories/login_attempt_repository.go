@@ -0,0 +1,77 @@
+// backend/internal/repositories/login_attempt_repository.go
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Largo de las columnas email e ip de intentos_login
+const (
+	maxAttemptEmailLength = 255
+	maxAttemptIPLength    = 45
+)
+
+type LoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptRepository crea una nueva instancia del repositorio
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// RecordFailure registra un intento fallido de inicio de sesión. Un email fuera del largo de
+// la columna es un error (el intento no se podría contar); la IP se recorta para que un valor
+// inesperado no impida registrarlo
+func (r *LoginAttemptRepository) RecordFailure(email, ip string) error {
+	if email == "" || len(email) > maxAttemptEmailLength {
+		return errors.New("error al registrar intento de inicio de sesión: email inválido")
+	}
+
+	if len(ip) > maxAttemptIPLength {
+		ip = ip[:maxAttemptIPLength]
+	}
+
+	if err := r.db.Create(&models.IntentoLogin{Email: email, IP: ip}).Error; err != nil {
+		return fmt.Errorf("error al registrar intento de inicio de sesión: %v", err)
+	}
+	return nil
+}
+
+// FindFailuresByEmail obtiene los intentos fallidos de un email desde una fecha, del más reciente al más antiguo
+func (r *LoginAttemptRepository) FindFailuresByEmail(email string, since time.Time) ([]models.IntentoLogin, error) {
+	var intentos []models.IntentoLogin
+	err := r.db.Where("email = ? AND fecha >= ?", email, since).
+		Order("fecha DESC").
+		Find(&intentos).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener intentos de inicio de sesión: %v", err)
+	}
+	return intentos, nil
+}
+
+// CountFailuresByIP cuenta los intentos fallidos desde una IP a partir de una fecha
+func (r *LoginAttemptRepository) CountFailuresByIP(ip string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.IntentoLogin{}).
+		Where("ip = ? AND fecha >= ?", ip, since).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("error al contar intentos de inicio de sesión: %v", err)
+	}
+	return count, nil
+}
+
+// ClearFailures borra los intentos fallidos de un email tras un inicio de sesión
+// exitoso o un desbloqueo
+func (r *LoginAttemptRepository) ClearFailures(email string) error {
+	if err := r.db.Where("email = ?", email).Delete(&models.IntentoLogin{}).Error; err != nil {
+		return fmt.Errorf("error al limpiar intentos de inicio de sesión: %v", err)
+	}
+	return nil
+}
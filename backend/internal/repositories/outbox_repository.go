@@ -0,0 +1,180 @@
+// backend/internal/repositories/outbox_repository.go
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository crea una nueva instancia del repositorio
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Enqueue guarda emails en la bandeja de salida fuera de cualquier otra transacción
+func (r *OutboxRepository) Enqueue(emails ...*models.EmailSaliente) error {
+	return enqueueEmails(r.db, emails)
+}
+
+// enqueueEmails guarda emails en la bandeja de salida con la conexión o transacción recibida,
+// para que se confirmen o descarten junto con el cambio que los origina. Los adjuntos se
+// guardan con el email. Ignora los nil
+func enqueueEmails(db *gorm.DB, emails []*models.EmailSaliente) error {
+	now := time.Now()
+	for _, email := range emails {
+		if email == nil {
+			continue
+		}
+
+		email.Estado = models.EmailPendiente
+		if email.ProximoIntento.IsZero() {
+			email.ProximoIntento = now
+		}
+
+		if err := db.Create(email).Error; err != nil {
+			return fmt.Errorf("error al encolar email: %v", err)
+		}
+	}
+	return nil
+}
+
+// ClaimNext reclama el siguiente email pendiente cuyo próximo intento ya llegó y lo reserva
+// durante lease a partir de este momento. Se reclama de a uno para que la reserva cubra solo
+// el envío de ese email. Las filas bloqueadas por otra réplica se saltan en lugar de
+// esperarlas. Retorna nil si no hay pendientes
+func (r *OutboxRepository) ClaimNext(now time.Time, lease time.Duration) (*models.EmailSaliente, error) {
+	var emails []models.EmailSaliente
+
+	// reclamado_hasta es DATETIME: sin fracciones de segundo el valor guardado es exactamente
+	// este, y MarkSent y RecordFailure lo usan para reconocer el reclamo
+	claimedUntil := time.Now().Truncate(time.Second).Add(lease)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("estado = ? AND proximo_intento <= ?", models.EmailPendiente, now).
+			Where("reclamado_hasta IS NULL OR reclamado_hasta < ?", now).
+			Order("proximo_intento ASC").
+			Limit(1).
+			Find(&emails).Error
+		if err != nil {
+			return err
+		}
+
+		if len(emails) == 0 {
+			return nil
+		}
+
+		email := &emails[0]
+		if err := tx.Where("email_id = ?", email.ID).Order("id ASC").Find(&email.Adjuntos).Error; err != nil {
+			return err
+		}
+
+		email.ReclamadoHasta = &claimedUntil
+		return tx.Model(&models.EmailSaliente{}).
+			Where("id = ?", email.ID).
+			Update("reclamado_hasta", claimedUntil).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al reclamar emails pendientes: %v", err)
+	}
+
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	return &emails[0], nil
+}
+
+// MarkSent registra que el email se entregó. Solo se aplica si el reclamo sigue siendo el de
+// claimedUntil; retorna false si venció y otra réplica lo retomó
+func (r *OutboxRepository) MarkSent(id int, claimedUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.EmailSaliente{}).
+		Where("id = ? AND reclamado_hasta = ?", id, claimedUntil).
+		Updates(map[string]interface{}{
+			"estado":          models.EmailEnviado,
+			"intentos":        gorm.Expr("intentos + 1"),
+			"enviado_en":      time.Now(),
+			"reclamado_hasta": nil,
+			"ultimo_error":    nil,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("error al marcar email como enviado: %v", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecordFailure registra un intento fallido. El email se reintenta en retryAt, o queda como
+// fallido si deadLetter es true. Como MarkSent, solo se aplica si el reclamo sigue siendo el
+// de claimedUntil
+func (r *OutboxRepository) RecordFailure(id int, claimedUntil time.Time, cause string, retryAt time.Time, deadLetter bool) (bool, error) {
+	updates := map[string]interface{}{
+		"intentos":        gorm.Expr("intentos + 1"),
+		"ultimo_error":    cause,
+		"proximo_intento": retryAt,
+		"reclamado_hasta": nil,
+	}
+	if deadLetter {
+		updates["estado"] = models.EmailFallido
+	}
+
+	result := r.db.Model(&models.EmailSaliente{}).
+		Where("id = ? AND reclamado_hasta = ?", id, claimedUntil).
+		Updates(updates)
+	if result.Error != nil {
+		return false, fmt.Errorf("error al registrar fallo de email: %v", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FindByID busca un email de la bandeja de salida con sus adjuntos
+func (r *OutboxRepository) FindByID(id int) (*models.EmailSaliente, error) {
+	var email models.EmailSaliente
+	result := r.db.Preload("Adjuntos").First(&email, id)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al buscar email: %v", result.Error)
+	}
+
+	return &email, nil
+}
+
+// FindByEstado lista los emails en un estado, del más reciente al más antiguo
+func (r *OutboxRepository) FindByEstado(estado string, limit int) ([]models.EmailSaliente, error) {
+	var emails []models.EmailSaliente
+	err := r.db.Where("estado = ?", estado).
+		Order("fecha_creacion DESC").
+		Limit(limit).
+		Find(&emails).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener emails: %v", err)
+	}
+	return emails, nil
+}
+
+// Requeue devuelve un email fallido a la cola con los intentos reiniciados. Retorna false si
+// el email ya no estaba fallido
+func (r *OutboxRepository) Requeue(id int) (bool, error) {
+	result := r.db.Model(&models.EmailSaliente{}).
+		Where("id = ? AND estado = ?", id, models.EmailFallido).
+		Updates(map[string]interface{}{
+			"estado":          models.EmailPendiente,
+			"intentos":        0,
+			"proximo_intento": time.Now(),
+			"reclamado_hasta": nil,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("error al reencolar email: %v", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,66 @@
+// backend/internal/repositories/password_reset_repository.go
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetRepository crea una nueva instancia del repositorio
+func NewPasswordResetRepository(db *gorm.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create guarda un token de restablecimiento e invalida los anteriores del usuario,
+// de modo que solo el último email enviado sirva. Los emails se encolan en la misma transacción
+func (r *PasswordResetRepository) Create(reset *models.RestablecimientoPassword, emails ...*models.EmailSaliente) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.RestablecimientoPassword{}).
+			Where("usuario_id = ? AND usado_en IS NULL", reset.UsuarioID).
+			Update("usado_en", time.Now()).Error
+		if err != nil {
+			return fmt.Errorf("error al invalidar tokens anteriores: %v", err)
+		}
+
+		if err := tx.Create(reset).Error; err != nil {
+			return fmt.Errorf("error al crear token de restablecimiento: %v", err)
+		}
+
+		return enqueueEmails(tx, emails)
+	})
+}
+
+// FindByTokenHash busca un token de restablecimiento por su hash
+func (r *PasswordResetRepository) FindByTokenHash(hash string) (*models.RestablecimientoPassword, error) {
+	var reset models.RestablecimientoPassword
+	result := r.db.Where("token_hash = ?", hash).First(&reset)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al buscar token de restablecimiento: %v", result.Error)
+	}
+
+	return &reset, nil
+}
+
+// MarkUsed marca el token como usado. Retorna false si ya había sido usado
+func (r *PasswordResetRepository) MarkUsed(id int) (bool, error) {
+	result := r.db.Model(&models.RestablecimientoPassword{}).
+		Where("id = ? AND usado_en IS NULL", id).
+		Update("usado_en", time.Now())
+
+	if result.Error != nil {
+		return false, fmt.Errorf("error al usar token de restablecimiento: %v", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,175 @@
+// backend/internal/repositories/patient_repository.go
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PatientRepository struct {
+	db *gorm.DB
+}
+
+// NewPatientRepository crea una nueva instancia del repositorio
+func NewPatientRepository(db *gorm.DB) *PatientRepository {
+	return &PatientRepository{db: db}
+}
+
+// FindByID busca un expediente por su ID
+func (r *PatientRepository) FindByID(id int) (*models.Paciente, error) {
+	var paciente models.Paciente
+	if err := r.db.First(&paciente, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al buscar paciente: %v", err)
+	}
+	return &paciente, nil
+}
+
+// FindByUsuario obtiene los expedientes activos que administra una cuenta, el titular primero
+func (r *PatientRepository) FindByUsuario(usuarioID int) ([]models.Paciente, error) {
+	var pacientes []models.Paciente
+
+	err := r.db.Where("usuario_id = ? AND activo = TRUE", usuarioID).
+		Order(clause.Expr{SQL: "parentesco = ? DESC, nombre ASC", Vars: []interface{}{models.ParentescoTitular}}).
+		Find(&pacientes).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener pacientes: %v", err)
+	}
+
+	return pacientes, nil
+}
+
+// Create registra un nuevo expediente
+func (r *PatientRepository) Create(paciente *models.Paciente) error {
+	if err := r.db.Create(paciente).Error; err != nil {
+		return fmt.Errorf("error al crear paciente: %v", err)
+	}
+	return nil
+}
+
+// Update actualiza los campos indicados de un expediente
+func (r *PatientRepository) Update(id int, updates map[string]interface{}) error {
+	if err := r.db.Model(&models.Paciente{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return fmt.Errorf("error al actualizar paciente: %v", err)
+	}
+	return nil
+}
+
+// Deactivate oculta un expediente sin borrarlo para conservar su historial de citas
+func (r *PatientRepository) Deactivate(id int) error {
+	if err := r.db.Model(&models.Paciente{}).Where("id = ?", id).Update("activo", false).Error; err != nil {
+		return fmt.Errorf("error al desactivar paciente: %v", err)
+	}
+	return nil
+}
+
+// EmailTaken indica si otro expediente distinto de exceptID ya usa el email
+func (r *PatientRepository) EmailTaken(email string, exceptID int) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Paciente{}).
+		Where("email = ? AND id <> ?", email, exceptID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error al buscar paciente: %v", err)
+	}
+	return count > 0, nil
+}
+
+// EnsureTitular obtiene el expediente propio de la cuenta y lo crea si no existe. Si hay un
+// expediente sin cuenta con el mismo email (registrado antes por recepción) se adopta
+func (r *PatientRepository) EnsureTitular(usuarioID int) (*models.Paciente, error) {
+	var paciente models.Paciente
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Bloquear la cuenta evita crear dos expedientes titulares con peticiones simultáneas
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, usuarioID).Error; err != nil {
+			return fmt.Errorf("error al obtener usuario: %v", err)
+		}
+
+		if user.PacienteID != nil {
+			if err := tx.First(&paciente, *user.PacienteID).Error; err != nil {
+				return fmt.Errorf("error al obtener paciente: %v", err)
+			}
+			return nil
+		}
+
+		err := tx.Where("email = ? AND usuario_id IS NULL", user.Email).First(&paciente).Error
+		switch {
+		case err == nil:
+			err = tx.Model(&paciente).Updates(map[string]interface{}{
+				"usuario_id": user.ID,
+				"parentesco": models.ParentescoTitular,
+				"activo":     true,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("error al vincular paciente: %v", err)
+			}
+		case err == gorm.ErrRecordNotFound:
+			email := user.Email
+			paciente = models.Paciente{
+				Nombre:          user.Nombre,
+				ApellidoPaterno: user.Apellido,
+				Email:           &email,
+				Telefono:        user.Telefono,
+				FechaNacimiento: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				Sexo:            "O",
+				UsuarioID:       &user.ID,
+				Parentesco:      models.ParentescoTitular,
+				Activo:          true,
+			}
+			if err := tx.Create(&paciente).Error; err != nil {
+				return fmt.Errorf("error al crear paciente: %v", err)
+			}
+		default:
+			return fmt.Errorf("error al buscar paciente: %v", err)
+		}
+
+		if err := tx.Model(&user).Update("paciente_id", paciente.ID).Error; err != nil {
+			return fmt.Errorf("error al vincular paciente: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &paciente, nil
+}
+
+// FindOrCreateByEmail obtiene el expediente con el email indicado o crea uno sin cuenta.
+// Un expediente existente no se modifica: quien agenda no puede cambiar los datos de otro
+func (r *PatientRepository) FindOrCreateByEmail(nombre, apellido, email, telefono string) (*models.Paciente, error) {
+	var paciente models.Paciente
+	err := r.db.Where("email = ?", email).First(&paciente).Error
+	if err == nil {
+		return &paciente, nil
+	}
+
+	if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("error al buscar paciente: %v", err)
+	}
+
+	paciente = models.Paciente{
+		Nombre:          nombre,
+		ApellidoPaterno: apellido,
+		Email:           &email,
+		Telefono:        telefono,
+		FechaNacimiento: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sexo:            "O",
+		Parentesco:      models.ParentescoTitular,
+		Activo:          true,
+	}
+
+	if err := r.db.Create(&paciente).Error; err != nil {
+		return nil, fmt.Errorf("error al crear paciente: %v", err)
+	}
+
+	return &paciente, nil
+}
@@ -0,0 +1,58 @@
+// backend/internal/repositories/recovery_code_repository.go
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository crea una nueva instancia del repositorio
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+// Replace reemplaza los códigos de recuperación del usuario por los nuevos hashes
+func (r *RecoveryCodeRepository) Replace(usuarioID int, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("usuario_id = ?", usuarioID).Delete(&models.CodigoRecuperacion{}).Error; err != nil {
+			return fmt.Errorf("error al borrar códigos de recuperación: %v", err)
+		}
+
+		codigos := make([]models.CodigoRecuperacion, 0, len(hashes))
+		for _, hash := range hashes {
+			codigos = append(codigos, models.CodigoRecuperacion{UsuarioID: usuarioID, CodigoHash: hash})
+		}
+
+		if err := tx.Create(&codigos).Error; err != nil {
+			return fmt.Errorf("error al guardar códigos de recuperación: %v", err)
+		}
+		return nil
+	})
+}
+
+// Use marca como usado un código de recuperación. Retorna false si no existe o ya se usó
+func (r *RecoveryCodeRepository) Use(usuarioID int, hash string) (bool, error) {
+	result := r.db.Model(&models.CodigoRecuperacion{}).
+		Where("usuario_id = ? AND codigo_hash = ? AND usado_en IS NULL", usuarioID, hash).
+		Update("usado_en", time.Now())
+
+	if result.Error != nil {
+		return false, fmt.Errorf("error al usar código de recuperación: %v", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteForUser borra los códigos de recuperación del usuario
+func (r *RecoveryCodeRepository) DeleteForUser(usuarioID int) error {
+	if err := r.db.Where("usuario_id = ?", usuarioID).Delete(&models.CodigoRecuperacion{}).Error; err != nil {
+		return fmt.Errorf("error al borrar códigos de recuperación: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// backend/internal/repositories/reminder_repository.go
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DueReminder cita que debe recibir un recordatorio
+type DueReminder struct {
+	CitaID    int
+	FechaHora time.Time
+}
+
+type ReminderRepository struct {
+	db *gorm.DB
+}
+
+// NewReminderRepository crea una nueva instancia del repositorio
+func NewReminderRepository(db *gorm.DB) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+// FindDue obtiene las citas activas que empiezan dentro de la anticipación indicada y aún no
+// tienen ese recordatorio ni uno más cercano, enviado o reclamado hace menos de staleAfter.
+// Las citas agendadas cuando la ventana ya había empezado se omiten: el paciente acaba de
+// recibir la confirmación
+func (r *ReminderRepository) FindDue(anticipacion time.Duration, now time.Time, staleAfter time.Duration, limit int) ([]DueReminder, error) {
+	var due []DueReminder
+	minutos := int(anticipacion.Minutes())
+
+	err := r.db.Raw(`
+		SELECT c.id as cita_id, c.fecha_hora
+		FROM citas c
+		WHERE c.estado IN (?, ?)
+			AND c.fecha_hora > ?
+			AND c.fecha_hora <= ?
+			AND c.fecha_creacion <= DATE_SUB(c.fecha_hora, INTERVAL ? MINUTE)
+			AND NOT EXISTS (
+				SELECT 1 FROM recordatorios_cita r
+				WHERE r.cita_id = c.id
+					AND r.fecha_cita = c.fecha_hora
+					AND r.anticipacion_minutos <= ?
+					AND (r.enviado_en IS NOT NULL OR r.reclamado_en >= ?)
+			)
+		ORDER BY c.fecha_hora ASC
+		LIMIT ?
+	`, models.EstadoProgramada, models.EstadoConfirmada, now, now.Add(anticipacion), minutos, minutos, now.Add(-staleAfter), limit).
+		Scan(&due).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar recordatorios pendientes: %v", err)
+	}
+
+	return due, nil
+}
+
+// Claim reclama el envío de un recordatorio y retorna la hora del reclamo, que identifica a
+// quien lo hizo al marcarlo como enviado. Retorna false si otra réplica ya lo reclamó; un
+// reclamo sin enviar más antiguo que staleAfter se considera abandonado y puede retomarse
+func (r *ReminderRepository) Claim(citaID int, anticipacion time.Duration, fechaCita time.Time, staleAfter time.Duration) (time.Time, bool, error) {
+	// reclamado_en es DATETIME: sin fracciones de segundo el valor guardado es exactamente este
+	now := time.Now().Truncate(time.Second)
+	recordatorio := models.RecordatorioCita{
+		CitaID:              citaID,
+		AnticipacionMinutos: int(anticipacion.Minutes()),
+		FechaCita:           fechaCita,
+		ReclamadoEn:         now,
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&recordatorio)
+	if result.Error != nil {
+		return time.Time{}, false, fmt.Errorf("error al reclamar recordatorio: %v", result.Error)
+	}
+
+	if result.RowsAffected == 1 {
+		return now, true, nil
+	}
+
+	result = r.db.Model(&models.RecordatorioCita{}).
+		Where("cita_id = ? AND anticipacion_minutos = ? AND fecha_cita = ?", citaID, recordatorio.AnticipacionMinutos, fechaCita).
+		Where("enviado_en IS NULL AND reclamado_en < ?", now.Add(-staleAfter)).
+		Update("reclamado_en", now)
+	if result.Error != nil {
+		return time.Time{}, false, fmt.Errorf("error al reclamar recordatorio: %v", result.Error)
+	}
+
+	return now, result.RowsAffected == 1, nil
+}
+
+// MarkSent registra que el recordatorio se envió y encola su email en la misma transacción,
+// solo si sigue sin enviar y el reclamo es todavía el de claimedAt. Retorna false sin encolar
+// nada si otra réplica retomó el reclamo entretanto, de modo que nunca se envían dos emails
+func (r *ReminderRepository) MarkSent(citaID int, anticipacion time.Duration, fechaCita, claimedAt time.Time, emails ...*models.EmailSaliente) (bool, error) {
+	marked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RecordatorioCita{}).
+			Where("cita_id = ? AND anticipacion_minutos = ? AND fecha_cita = ?", citaID, int(anticipacion.Minutes()), fechaCita).
+			Where("enviado_en IS NULL AND reclamado_en = ?", claimedAt).
+			Update("enviado_en", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("error al marcar recordatorio: %v", result.Error)
+		}
+
+		if result.RowsAffected != 1 {
+			return nil
+		}
+
+		marked = true
+		return enqueueEmails(tx, emails)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return marked, nil
+}
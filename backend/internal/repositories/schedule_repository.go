@@ -0,0 +1,143 @@
+// backend/internal/repositories/schedule_repository.go
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type ScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewScheduleRepository crea una nueva instancia del repositorio
+func NewScheduleRepository(db *gorm.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+// FindByEspecialista obtiene la jornada semanal activa de un especialista
+func (r *ScheduleRepository) FindByEspecialista(especialistaID int) ([]models.HorarioEspecialista, error) {
+	var horarios []models.HorarioEspecialista
+
+	err := r.db.Where("especialista_id = ? AND activo = TRUE", especialistaID).
+		Order("dia_semana ASC").
+		Find(&horarios).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener horario del especialista: %v", err)
+	}
+
+	return horarios, nil
+}
+
+// FindByEspecialistaAndDay obtiene la jornada de un especialista para un día de la semana
+func (r *ScheduleRepository) FindByEspecialistaAndDay(especialistaID int, dia time.Weekday) (*models.HorarioEspecialista, error) {
+	var horario models.HorarioEspecialista
+	result := r.db.Where("especialista_id = ? AND dia_semana = ? AND activo = TRUE", especialistaID, int(dia)).
+		First(&horario)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al obtener horario del especialista: %v", result.Error)
+	}
+
+	return &horario, nil
+}
+
+// FindBlocks obtiene los bloqueos que afectan a un especialista (propios o de toda la clínica) dentro de un rango
+func (r *ScheduleRepository) FindBlocks(especialistaID int, start, end time.Time) ([]models.BloqueoAgenda, error) {
+	var bloqueos []models.BloqueoAgenda
+
+	err := r.db.Where("especialista_id = ? OR especialista_id IS NULL", especialistaID).
+		Where("fecha_inicio < ? AND fecha_fin > ?", end, start).
+		Order("fecha_inicio ASC").
+		Find(&bloqueos).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener bloqueos de agenda: %v", err)
+	}
+
+	return bloqueos, nil
+}
+
+// ListBlocks obtiene los bloqueos registrados, opcionalmente filtrados por especialista y rango
+func (r *ScheduleRepository) ListBlocks(especialistaID int, from, to *time.Time) ([]models.BloqueoAgenda, error) {
+	var bloqueos []models.BloqueoAgenda
+
+	query := r.db.Model(&models.BloqueoAgenda{})
+	if especialistaID > 0 {
+		query = query.Where("especialista_id = ? OR especialista_id IS NULL", especialistaID)
+	}
+	if from != nil {
+		query = query.Where("fecha_fin > ?", *from)
+	}
+	if to != nil {
+		query = query.Where("fecha_inicio < ?", *to)
+	}
+
+	if err := query.Order("fecha_inicio ASC").Find(&bloqueos).Error; err != nil {
+		return nil, fmt.Errorf("error al obtener bloqueos de agenda: %v", err)
+	}
+
+	return bloqueos, nil
+}
+
+// FindBlockByID busca un bloqueo por su ID
+func (r *ScheduleRepository) FindBlockByID(id int) (*models.BloqueoAgenda, error) {
+	var bloqueo models.BloqueoAgenda
+	result := r.db.First(&bloqueo, id)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al buscar bloqueo: %v", result.Error)
+	}
+
+	return &bloqueo, nil
+}
+
+// CreateBlock inserta un nuevo bloqueo de agenda
+func (r *ScheduleRepository) CreateBlock(bloqueo *models.BloqueoAgenda) error {
+	if err := r.db.Create(bloqueo).Error; err != nil {
+		return fmt.Errorf("error al crear bloqueo: %v", err)
+	}
+	return nil
+}
+
+// UpdateBlock guarda los cambios de un bloqueo existente
+func (r *ScheduleRepository) UpdateBlock(bloqueo *models.BloqueoAgenda) error {
+	if err := r.db.Save(bloqueo).Error; err != nil {
+		return fmt.Errorf("error al actualizar bloqueo: %v", err)
+	}
+	return nil
+}
+
+// DeleteBlock elimina un bloqueo de agenda
+func (r *ScheduleRepository) DeleteBlock(id int) error {
+	result := r.db.Delete(&models.BloqueoAgenda{}, id)
+
+	if result.Error != nil {
+		return fmt.Errorf("error al eliminar bloqueo: %v", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("bloqueo no encontrado")
+	}
+
+	return nil
+}
+
+// EspecialistaExists verifica que exista un especialista con el ID dado
+func (r *ScheduleRepository) EspecialistaExists(id int) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.Especialista{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("error al buscar especialista: %v", err)
+	}
+	return count > 0, nil
+}
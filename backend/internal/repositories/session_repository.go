@@ -0,0 +1,113 @@
+// backend/internal/repositories/session_repository.go
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository crea una nueva instancia del repositorio
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create guarda un refresh token emitido
+func (r *SessionRepository) Create(sesion *models.Sesion) error {
+	if err := r.db.Create(sesion).Error; err != nil {
+		return fmt.Errorf("error al crear sesión: %v", err)
+	}
+	return nil
+}
+
+// FindByTokenHash busca un refresh token por su hash
+func (r *SessionRepository) FindByTokenHash(hash string) (*models.Sesion, error) {
+	var sesion models.Sesion
+	result := r.db.Where("token_hash = ?", hash).First(&sesion)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al buscar sesión: %v", result.Error)
+	}
+
+	return &sesion, nil
+}
+
+// MarkUsed marca un refresh token como rotado. Retorna false si ya había sido usado
+// o revocado, lo que indica que dos peticiones intentaron usar el mismo token
+func (r *SessionRepository) MarkUsed(id int) (bool, error) {
+	result := r.db.Model(&models.Sesion{}).
+		Where("id = ? AND usado_en IS NULL AND revocado_en IS NULL", id).
+		Update("usado_en", time.Now())
+
+	if result.Error != nil {
+		return false, fmt.Errorf("error al rotar sesión: %v", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeFamily revoca todos los refresh tokens de una sesión
+func (r *SessionRepository) RevokeFamily(usuarioID int, familiaID string) error {
+	err := r.db.Model(&models.Sesion{}).
+		Where("usuario_id = ? AND familia_id = ? AND revocado_en IS NULL", usuarioID, familiaID).
+		Update("revocado_en", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("error al revocar sesión: %v", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser revoca todas las sesiones de un usuario
+func (r *SessionRepository) RevokeAllForUser(usuarioID int) error {
+	err := r.db.Model(&models.Sesion{}).
+		Where("usuario_id = ? AND revocado_en IS NULL", usuarioID).
+		Update("revocado_en", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("error al revocar sesiones: %v", err)
+	}
+	return nil
+}
+
+// IsFamilyActive indica si la sesión tiene un refresh token vigente sin revocar
+func (r *SessionRepository) IsFamilyActive(familiaID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Sesion{}).
+		Where("familia_id = ? AND revocado_en IS NULL AND expira_en > ?", familiaID, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error al verificar sesión: %v", err)
+	}
+	return count > 0, nil
+}
+
+// FindActiveByUser obtiene el refresh token vigente de cada sesión activa del usuario
+func (r *SessionRepository) FindActiveByUser(usuarioID int) ([]models.Sesion, error) {
+	var sesiones []models.Sesion
+	err := r.db.Where("usuario_id = ? AND usado_en IS NULL AND revocado_en IS NULL AND expira_en > ?", usuarioID, time.Now()).
+		Order("fecha_creacion DESC").
+		Find(&sesiones).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener sesiones: %v", err)
+	}
+	return sesiones, nil
+}
+
+// RevokeOthersForUser revoca todas las sesiones del usuario excepto la indicada
+func (r *SessionRepository) RevokeOthersForUser(usuarioID int, familiaID string) error {
+	err := r.db.Model(&models.Sesion{}).
+		Where("usuario_id = ? AND familia_id <> ? AND revocado_en IS NULL", usuarioID, familiaID).
+		Update("revocado_en", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("error al revocar sesiones: %v", err)
+	}
+	return nil
+}
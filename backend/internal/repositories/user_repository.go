@@ -3,6 +3,8 @@ package repositories
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/wenka/backend/internal/models"
 	"gorm.io/gorm"
@@ -55,3 +57,211 @@ func (r *UserRepository) FindByID(id int) (*models.User, error) {
 
 	return &user, nil
 }
+
+// FindByEspecialistaID busca la cuenta vinculada a un especialista
+func (r *UserRepository) FindByEspecialistaID(especialistaID int) (*models.User, error) {
+	var user models.User
+	result := r.db.Where("especialista_id = ?", especialistaID).First(&user)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error al buscar usuario: %v", result.Error)
+	}
+
+	return &user, nil
+}
+
+// EspecialistaExists indica si existe un especialista activo con el ID indicado
+func (r *UserRepository) EspecialistaExists(especialistaID int) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Especialista{}).
+		Where("id = ? AND activo = ?", especialistaID, true).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error al buscar especialista: %v", err)
+	}
+	return count > 0, nil
+}
+
+// UpdateRole cambia el rol de un usuario y su vínculo con un especialista
+func (r *UserRepository) UpdateRole(userID int, rol string, especialistaID *int) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"rol":             rol,
+			"especialista_id": especialistaID,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("error al actualizar rol: %v", result.Error)
+	}
+	return nil
+}
+
+// UpdatePassword reemplaza el hash de la contraseña de un usuario
+func (r *UserRepository) UpdatePassword(userID int, hashedPassword string) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("password", hashedPassword)
+
+	if result.Error != nil {
+		return fmt.Errorf("error al actualizar contraseña: %v", result.Error)
+	}
+	return nil
+}
+
+// MarkEmailVerified marca el email del usuario como verificado
+func (r *UserRepository) MarkEmailVerified(userID int) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("email_verificado", true)
+
+	if result.Error != nil {
+		return fmt.Errorf("error al verificar email: %v", result.Error)
+	}
+	return nil
+}
+
+// SetTOTPSecret guarda un secreto TOTP pendiente de confirmar
+func (r *UserRepository) SetTOTPSecret(userID int, secret string) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"totp_secreto":     secret,
+			"totp_activo":      false,
+			"totp_ultimo_paso": 0,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("error al guardar secreto TOTP: %v", result.Error)
+	}
+	return nil
+}
+
+// UseTOTPStep registra el paso TOTP usado. Retorna false si ya se había usado ese paso
+// o uno posterior, lo que indica un código reutilizado
+func (r *UserRepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_ultimo_paso < ?", userID, step).
+		Update("totp_ultimo_paso", step)
+
+	if result.Error != nil {
+		return false, fmt.Errorf("error al registrar código TOTP: %v", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetTOTPActive activa o desactiva 2FA. Al desactivar se borra el secreto
+func (r *UserRepository) SetTOTPActive(userID int, active bool) error {
+	updates := map[string]interface{}{"totp_activo": active}
+	if !active {
+		updates["totp_secreto"] = ""
+		updates["totp_ultimo_paso"] = 0
+	}
+
+	result := r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		Updates(updates)
+
+	if result.Error != nil {
+		return fmt.Errorf("error al actualizar 2FA: %v", result.Error)
+	}
+	return nil
+}
+
+// PacienteEmailExists indica si un expediente distinto del propio de la cuenta usa el email
+func (r *UserRepository) PacienteEmailExists(email string, exceptID int) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Paciente{}).
+		Where("email = ? AND id <> ?", email, exceptID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error al buscar paciente: %v", err)
+	}
+	return count > 0, nil
+}
+
+// Columnas del expediente propio que reflejan los datos de la cuenta
+var perfilEnExpediente = map[string]string{
+	"nombre":   "nombre",
+	"apellido": "apellido_paterno",
+	"telefono": "telefono",
+	"email":    "email",
+}
+
+// UpdateProfile actualiza los datos del perfil y los copia al expediente propio de la cuenta
+func (r *UserRepository) UpdateProfile(user *models.User, updates map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("error al actualizar perfil: %v", err)
+		}
+
+		if user.PacienteID == nil {
+			return nil
+		}
+
+		expediente := map[string]interface{}{}
+		for campo, columna := range perfilEnExpediente {
+			if value, ok := updates[campo]; ok {
+				expediente[columna] = value
+			}
+		}
+
+		if len(expediente) == 0 {
+			return nil
+		}
+
+		err := tx.Model(&models.Paciente{}).
+			Where("id = ?", *user.PacienteID).
+			Updates(expediente).Error
+		if err != nil {
+			return fmt.Errorf("error al actualizar expediente del paciente: %v", err)
+		}
+		return nil
+	})
+}
+
+// DeleteAndAnonymize elimina la cuenta y anonimiza su expediente y los de sus dependientes.
+// Las citas se conservan ligadas a los expedientes anónimos; sesiones, tokens y códigos se
+// borran en cascada
+func (r *UserRepository) DeleteAndAnonymize(user *models.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var pacientes []models.Paciente
+		if err := tx.Where("usuario_id = ?", user.ID).Find(&pacientes).Error; err != nil {
+			return fmt.Errorf("error al buscar paciente: %v", err)
+		}
+
+		for _, paciente := range pacientes {
+			err := tx.Model(&models.Paciente{}).
+				Where("id = ?", paciente.ID).
+				Updates(map[string]interface{}{
+					"nombre":                       "Paciente",
+					"apellido_paterno":             "Eliminado",
+					"apellido_materno":             nil,
+					"email":                        fmt.Sprintf("eliminado-%d@anonimo.invalid", paciente.ID),
+					"telefono":                     nil,
+					"fecha_nacimiento":             time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+					"direccion":                    nil,
+					"ciudad":                       nil,
+					"codigo_postal":                nil,
+					"contacto_emergencia_nombre":   nil,
+					"contacto_emergencia_telefono": nil,
+					"activo":                       false,
+				}).Error
+			if err != nil {
+				return fmt.Errorf("error al anonimizar paciente: %v", err)
+			}
+		}
+
+		if err := tx.Where("email = ?", strings.ToLower(user.Email)).Delete(&models.IntentoLogin{}).Error; err != nil {
+			return fmt.Errorf("error al borrar intentos de inicio de sesión: %v", err)
+		}
+
+		if err := tx.Delete(&models.User{}, user.ID).Error; err != nil {
+			return fmt.Errorf("error al eliminar usuario: %v", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,131 @@
+// backend/internal/services/appointment_links_test.go
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/utils"
+)
+
+const testLinkSecret = "secreto-de-prueba-para-enlaces-de-citas"
+
+func TestLinkTokenValidation(t *testing.T) {
+	fechaHora := time.Date(2030, 5, 10, 9, 30, 0, 0, time.Local)
+	expiresAt := time.Now().Add(time.Hour)
+
+	valid, _, err := utils.GenerateLinkToken(12, 4, 0, models.AccionConfirmar, fechaHora, expiresAt, testLinkSecret)
+	if err != nil {
+		t.Fatalf("error al generar token: %v", err)
+	}
+
+	expired, _, err := utils.GenerateLinkToken(12, 4, 0, models.AccionConfirmar, fechaHora, time.Now().Add(-time.Minute), testLinkSecret)
+	if err != nil {
+		t.Fatalf("error al generar token: %v", err)
+	}
+
+	otherSecret, _, err := utils.GenerateLinkToken(12, 4, 0, models.AccionConfirmar, fechaHora, expiresAt, "otro-secreto-de-prueba-para-enlaces")
+	if err != nil {
+		t.Fatalf("error al generar token: %v", err)
+	}
+
+	// Mismo secreto pero otra audiencia: un enlace de verificación de email no abre una cita
+	verification, err := utils.GenerateEmailVerificationToken(12, "paciente@example.com", expiresAt, testLinkSecret)
+	if err != nil {
+		t.Fatalf("error al generar token: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"válido", valid, nil},
+		{"vacío", "", utils.ErrLinkTokenInvalid},
+		{"expirado", expired, utils.ErrLinkTokenExpired},
+		{"firmado con otro secreto", otherSecret, utils.ErrLinkTokenInvalid},
+		{"firma alterada", tamperSignature(valid), utils.ErrLinkTokenInvalid},
+		{"token de verificación de email", verification, utils.ErrLinkTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := utils.ValidateLinkToken(tt.token, testLinkSecret)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, se esperaba %v", err, tt.want)
+			}
+			if tt.want != nil {
+				return
+			}
+
+			if claims.CitaID != 12 || claims.EspecialistaID != 4 || claims.Accion != models.AccionConfirmar {
+				t.Errorf("claims inesperados: %+v", claims)
+			}
+			if claims.FechaHora != fechaHora.Unix() {
+				t.Errorf("fecha_hora = %d, se esperaba %d", claims.FechaHora, fechaHora.Unix())
+			}
+			if claims.ID == "" {
+				t.Error("el token no tiene jti")
+			}
+		})
+	}
+}
+
+// tamperSignature cambia un carácter de la firma del JWT
+func tamperSignature(token string) string {
+	b := []byte(token)
+	i := len(b) - 10
+	if b[i] == 'A' {
+		b[i] = 'B'
+	} else {
+		b[i] = 'A'
+	}
+	return string(b)
+}
+
+func TestLinkTokenIDsAreUnique(t *testing.T) {
+	fechaHora := time.Now().Add(24 * time.Hour)
+	seen := make(map[string]bool)
+
+	for i := 0; i < 20; i++ {
+		_, jti, err := utils.GenerateLinkToken(1, 1, 0, models.AccionConfirmar, fechaHora, fechaHora, testLinkSecret)
+		if err != nil {
+			t.Fatalf("error al generar token: %v", err)
+		}
+		if seen[jti] {
+			t.Fatalf("jti repetido: %s", jti)
+		}
+		seen[jti] = true
+	}
+}
+
+// Un enlace de otra cita o de otra acción se rechaza antes de consultar la cita
+func TestVerifyLinkTokenRejectsOtherAppointmentOrAction(t *testing.T) {
+	service := &AppointmentService{linkSecret: testLinkSecret}
+	fechaHora := time.Now().Add(24 * time.Hour)
+
+	token, _, err := utils.GenerateLinkToken(12, 4, 0, models.AccionConfirmar, fechaHora, fechaHora, testLinkSecret)
+	if err != nil {
+		t.Fatalf("error al generar token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		citaID int
+		accion string
+	}{
+		{"otra cita", 13, models.AccionConfirmar},
+		{"otra acción", 12, models.AccionResponder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := service.verifyLinkToken(tt.citaID, token, tt.accion)
+			if !errors.Is(err, utils.ErrLinkTokenInvalid) {
+				t.Fatalf("error = %v, se esperaba %v", err, utils.ErrLinkTokenInvalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// backend/internal/services/appointment_proposals.go
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/utils"
+)
+
+// Máximo de horarios alternativos que el especialista puede proponer por respuesta
+const maxProposals = 3
+
+// RespondToAppointment procesa la respuesta del especialista desde el enlace del email:
+// rechazar cancela la cita y avisa al paciente; proponer guarda hasta tres horarios
+// alternativos y se los envía al paciente con un enlace para aceptar cada uno
+func (s *AppointmentService) RespondToAppointment(id int, req *models.RespondAppointmentRequest) error {
+	claims, appointment, err := s.verifyLinkToken(id, req.Token, models.AccionResponder)
+	if err != nil {
+		return err
+	}
+
+	by := ChangedBy{Actor: ActorEspecialista}
+	motivo := strings.TrimSpace(req.Motivo)
+
+	switch req.Accion {
+	case models.RespuestaRechazar:
+		if err := CheckTransition(appointment.Estado, models.EstadoCancelada, by.Actor); err != nil {
+			return err
+		}
+
+		notas := "Rechazada por el especialista"
+		if motivo != "" {
+			notas = fmt.Sprintf("%s: %s", notas, motivo)
+		}
+
+		return s.inTransaction(func(tx *AppointmentService) error {
+			if err := tx.useLinkToken(claims); err != nil {
+				return err
+			}
+
+			if _, err := tx.transitionAppointment(id, models.EstadoCancelada, by, &notas); err != nil {
+				return err
+			}
+
+			return tx.enqueueForAppointment(id, func(details *models.AppointmentWithDetails) (*models.EmailSaliente, error) {
+				return s.emailService.AppointmentDeclinedEmail(details, motivo)
+			})
+		})
+
+	case models.RespuestaProponer:
+		if err := CheckTransition(appointment.Estado, models.EstadoProgramada, by.Actor); err != nil {
+			return err
+		}
+
+		propuestas, err := s.validateProposals(appointment, req.Horarios)
+		if err != nil {
+			return err
+		}
+
+		return s.inTransaction(func(tx *AppointmentService) error {
+			if err := tx.useLinkToken(claims); err != nil {
+				return err
+			}
+
+			if err := tx.appointmentRepo.CreateProposals(id, propuestas); err != nil {
+				return err
+			}
+
+			details, err := tx.appointmentRepo.FindByID(id)
+			if err != nil {
+				return fmt.Errorf("error al obtener detalles de la cita: %v", err)
+			}
+
+			links := make([]ProposalLink, 0, len(propuestas))
+			for i := range propuestas {
+				propuesta := &propuestas[i]
+				token, err := tx.issueLinkToken(details, models.AccionAceptarPropuesta, propuesta)
+				if err != nil {
+					return fmt.Errorf("error al generar enlace de propuesta: %v", err)
+				}
+
+				links = append(links, ProposalLink{
+					PropuestaID: propuesta.ID,
+					FechaHora:   propuesta.FechaHora,
+					Token:       token,
+				})
+			}
+
+			message, err := s.emailService.AppointmentProposalsEmail(details, motivo, links)
+			if err != nil {
+				return err
+			}
+
+			return tx.appointmentRepo.EnqueueEmails(message)
+		})
+	}
+
+	return fmt.Errorf("acción inválida. Opciones: %s, %s", models.RespuestaRechazar, models.RespuestaProponer)
+}
+
+// validateProposals verifica que los horarios propuestos sean futuros, distintos entre sí
+// y que el especialista realmente esté disponible en cada uno
+func (s *AppointmentService) validateProposals(appointment *models.Appointment, horarios []models.AvailableSlot) ([]models.PropuestaHorario, error) {
+	if len(horarios) == 0 {
+		return nil, fmt.Errorf("debes proponer al menos un horario")
+	}
+
+	if len(horarios) > maxProposals {
+		return nil, fmt.Errorf("puedes proponer como máximo %d horarios", maxProposals)
+	}
+
+	propuestas := make([]models.PropuestaHorario, 0, len(horarios))
+	seen := make(map[int64]bool)
+
+	for _, horario := range horarios {
+		fechaHora, err := parseFechaHora(horario.FechaCita, horario.HoraCita)
+		if err != nil {
+			return nil, err
+		}
+
+		if fechaHora.Before(time.Now()) {
+			return nil, fmt.Errorf("el horario %s %s ya pasó", horario.FechaCita, horario.HoraCita)
+		}
+
+		if fechaHora.Equal(appointment.FechaHora) || seen[fechaHora.Unix()] {
+			return nil, fmt.Errorf("el horario %s %s está repetido", horario.FechaCita, horario.HoraCita)
+		}
+		seen[fechaHora.Unix()] = true
+
+		isAvailable, err := s.appointmentRepo.CheckAvailabilityExcluding(
+			appointment.EspecialistaID,
+			fechaHora,
+			appointment.DuracionMinutos,
+			appointment.ID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error al verificar disponibilidad: %v", err)
+		}
+
+		if !isAvailable {
+			return nil, fmt.Errorf("el horario %s %s no está disponible", horario.FechaCita, horario.HoraCita)
+		}
+
+		propuestas = append(propuestas, models.PropuestaHorario{
+			CitaID:    appointment.ID,
+			FechaHora: fechaHora,
+			Estado:    models.PropuestaPendiente,
+		})
+	}
+
+	return propuestas, nil
+}
+
+// AcceptProposal reagenda la cita al horario propuesto que eligió el paciente con un clic.
+// Como el horario lo propuso el especialista, la cita queda confirmada directamente. El uso
+// del enlace, el cambio de horario y la confirmación se aplican juntos o no se aplican
+func (s *AppointmentService) AcceptProposal(id, propuestaID int, token string) error {
+	claims, appointment, err := s.verifyLinkToken(id, token, models.AccionAceptarPropuesta)
+	if err != nil {
+		return err
+	}
+
+	if claims.PropuestaID != propuestaID {
+		return utils.ErrLinkTokenInvalid
+	}
+
+	propuesta, err := s.appointmentRepo.FindProposal(propuestaID)
+	if err != nil {
+		return err
+	}
+
+	if propuesta.CitaID != id || propuesta.Estado != models.PropuestaPendiente {
+		return ErrProposalUnavailable
+	}
+
+	by := ChangedBy{Actor: ActorPaciente}
+	if err := CheckTransition(appointment.Estado, models.EstadoProgramada, by.Actor); err != nil {
+		return err
+	}
+
+	// El horario pudo ocuparse desde que se propuso
+	isAvailable, err := s.appointmentRepo.CheckAvailabilityExcluding(
+		appointment.EspecialistaID,
+		propuesta.FechaHora,
+		appointment.DuracionMinutos,
+		appointment.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("error al verificar disponibilidad: %v", err)
+	}
+
+	if !isAvailable {
+		return ErrProposalUnavailable
+	}
+
+	notas := fmt.Sprintf("Reagendada desde %s a propuesta del especialista", appointment.FechaHora.Format("2006-01-02 15:04"))
+	cambio := by.newCambio(id, appointment.Estado, models.EstadoProgramada, notas)
+
+	// La confirmación no la hace el especialista en este momento: la registra el sistema
+	sistema := ChangedBy{Actor: ActorSistema}
+	if err := CheckTransition(models.EstadoProgramada, models.EstadoConfirmada, sistema.Actor); err != nil {
+		return err
+	}
+	confirmacion := sistema.newCambio(id, models.EstadoProgramada, models.EstadoConfirmada,
+		"Confirmada al aceptar el paciente un horario propuesto por el especialista")
+
+	return s.inTransaction(func(tx *AppointmentService) error {
+		if err := tx.useLinkToken(claims); err != nil {
+			return err
+		}
+
+		rescheduled, err := tx.appointmentRepo.RescheduleToProposal(propuesta, cambio)
+		if err != nil {
+			return err
+		}
+
+		if !rescheduled {
+			return ErrProposalUnavailable
+		}
+
+		confirmed, err := tx.appointmentRepo.UpdateStatusFrom(confirmacion, nil)
+		if err != nil {
+			return err
+		}
+
+		if !confirmed {
+			return ErrProposalUnavailable
+		}
+
+		return tx.enqueueForAppointment(id, s.emailService.AppointmentConfirmationEmail)
+	})
+}
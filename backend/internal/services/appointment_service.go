@@ -2,58 +2,116 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/wenka/backend/internal/models"
 	"github.com/wenka/backend/internal/repositories"
+	"github.com/wenka/backend/internal/utils"
 )
 
+// ErrLinkTokenUsed el enlace ya fue utilizado (o fue revocado)
+var ErrLinkTokenUsed = errors.New("enlace ya utilizado")
+
+// ErrProposalUnavailable el horario propuesto ya fue aceptado, descartado u ocupado
+var ErrProposalUnavailable = errors.New("el horario propuesto ya no está disponible")
+
+// Parámetros para generar los horarios disponibles
+const (
+	slotIntervalMinutes = 30
+	maxAvailabilityDays = 31
+)
+
+// AppointmentEmails arma los emails del flujo de citas. Los implementa EmailService; el
+// servicio solo los encola junto con el cambio que los origina
+type AppointmentEmails interface {
+	AppointmentNotificationEmail(appointment *models.AppointmentWithDetails, links SpecialistLinks) (*models.EmailSaliente, error)
+	AppointmentRescheduleEmail(appointment *models.AppointmentWithDetails, previousFechaHora time.Time, links SpecialistLinks) (*models.EmailSaliente, error)
+	AppointmentConfirmationEmail(appointment *models.AppointmentWithDetails) (*models.EmailSaliente, error)
+	AppointmentCancelledEmail(appointment *models.AppointmentWithDetails) (*models.EmailSaliente, error)
+	AppointmentDeclinedEmail(appointment *models.AppointmentWithDetails, motivo string) (*models.EmailSaliente, error)
+	AppointmentProposalsEmail(appointment *models.AppointmentWithDetails, motivo string, proposals []ProposalLink) (*models.EmailSaliente, error)
+}
+
+// appointmentStore consultas y cambios de citas que usa el servicio. Lo implementa
+// AppointmentRepository; las pruebas lo reemplazan por uno en memoria
+type appointmentStore interface {
+	CheckAvailability(especialistaID int, fechaHora time.Time, duracionMinutos int) (bool, error)
+	CheckAvailabilityExcluding(especialistaID int, fechaHora time.Time, duracionMinutos int, excludeID int) (bool, error)
+	ConsumeLink(jti string, citaID int, accion string) (bool, error)
+	CountByEspecialistaOnDate(especialistaIDs []int, day time.Time) (map[int]int64, error)
+	Create(appointment *models.Appointment) error
+	CreateLink(enlace *models.EnlaceCita) error
+	CreateProposals(citaID int, propuestas []models.PropuestaHorario) error
+	EnqueueEmails(emails ...*models.EmailSaliente) error
+	FindAppointment(id int) (*models.Appointment, error)
+	FindByID(id int) (*models.AppointmentWithDetails, error)
+	FindByUserID(userID int) ([]models.AppointmentResponse, error)
+	FindEspecialistaByID(id int) (*models.Especialista, error)
+	FindEspecialistaByUserID(userID int) (*models.Especialista, error)
+	FindEspecialistasByServicio(servicio string) ([]models.EspecialistaServicio, error)
+	FindEspecialistasByTratamiento(tratamiento *models.Tratamiento) ([]models.EspecialistaServicio, error)
+	FindLastEspecialistaForPaciente(pacienteID int, especialistaIDs []int) (int, error)
+	FindOverlapping(especialistaID int, startTime, endTime time.Time) ([]models.Appointment, error)
+	FindProposal(id int) (*models.PropuestaHorario, error)
+	FindStatusHistory(citaID int) ([]models.CambioEstadoCita, error)
+	FindTratamientoByID(id int) (*models.Tratamiento, error)
+	IsOwnedByUser(citaID, userID int) (bool, error)
+	LastAssignmentByEspecialista(especialistaIDs []int) (map[int]time.Time, error)
+	Reschedule(fechaHora time.Time, cambio *models.CambioEstadoCita) (bool, error)
+	RescheduleToProposal(propuesta *models.PropuestaHorario, cambio *models.CambioEstadoCita) (bool, error)
+	Transaction(fn func(repo *repositories.AppointmentRepository) error) error
+	UpdateStatusFrom(cambio *models.CambioEstadoCita, notas *string) (bool, error)
+}
+
 type AppointmentService struct {
-	appointmentRepo *repositories.AppointmentRepository
-	emailService    *EmailService
+	appointmentRepo    appointmentStore
+	patientRepo        *repositories.PatientRepository
+	scheduleRepo       *repositories.ScheduleRepository
+	emailService       AppointmentEmails
+	assignmentStrategy AssignmentStrategy
+	linkSecret         string
+	linkTTL            time.Duration
 }
 
 // NewAppointmentService crea una nueva instancia del servicio
-func NewAppointmentService(appointmentRepo *repositories.AppointmentRepository, emailService *EmailService) *AppointmentService {
+func NewAppointmentService(appointmentRepo *repositories.AppointmentRepository, patientRepo *repositories.PatientRepository, scheduleRepo *repositories.ScheduleRepository, emailService AppointmentEmails, assignmentStrategy AssignmentStrategy, linkSecret string, linkTTL time.Duration) *AppointmentService {
 	return &AppointmentService{
-		appointmentRepo: appointmentRepo,
-		emailService:    emailService,
+		appointmentRepo:    appointmentRepo,
+		patientRepo:        patientRepo,
+		scheduleRepo:       scheduleRepo,
+		emailService:       emailService,
+		assignmentStrategy: assignmentStrategy,
+		linkSecret:         linkSecret,
+		linkTTL:            linkTTL,
 	}
 }
 
 // CreateAppointment crea una nueva cita con validaciones
-func (s *AppointmentService) CreateAppointment(userID int, req *models.CreateAppointmentRequest) (*models.AppointmentResponse, error) {
+func (s *AppointmentService) CreateAppointment(by ChangedBy, req *models.CreateAppointmentRequest) (*models.AppointmentResponse, error) {
 	// Validar datos de entrada
 	if err := s.validateAppointmentRequest(req); err != nil {
 		return nil, err
 	}
 
-	// Parsear nombre completo del paciente
-	nombreParts := strings.Fields(req.NombrePaciente)
-	if len(nombreParts) < 2 {
-		return nil, fmt.Errorf("el nombre debe incluir nombre y apellido")
-	}
-	nombre := nombreParts[0]
-	apellido := strings.Join(nombreParts[1:], " ")
-
-	// Crear o obtener paciente
-	pacienteID, err := s.appointmentRepo.CreateOrGetPaciente(nombre, apellido, req.Email, req.Telefono)
+	paciente, err := s.resolvePaciente(by, req)
 	if err != nil {
-		return nil, fmt.Errorf("error al procesar paciente: %v", err)
+		return nil, err
 	}
+	pacienteID := paciente.ID
 
-	// Buscar especialista y tratamiento disponible para el servicio
-	especialistaInfo, err := s.appointmentRepo.FindEspecialistaByServicio(req.Servicio)
+	// Buscar los especialistas que pueden atender la cita
+	candidatos, err := s.resolveCandidatos(req)
 	if err != nil {
 		return nil, err
 	}
 
 	// Combinar fecha y hora
-	fechaHora, err := time.Parse("2006-01-02 15:04", req.FechaCita+" "+req.HoraCita)
+	fechaHora, err := parseFechaHora(req.FechaCita, req.HoraCita)
 	if err != nil {
-		return nil, fmt.Errorf("formato de fecha u hora inválido: %v", err)
+		return nil, err
 	}
 
 	// Validar que la fecha no sea en el pasado
@@ -61,20 +119,24 @@ func (s *AppointmentService) CreateAppointment(userID int, req *models.CreateApp
 		return nil, fmt.Errorf("no se puede agendar cita en el pasado")
 	}
 
-	// Verificar disponibilidad del especialista
-	isAvailable, err := s.appointmentRepo.CheckAvailability(
-		especialistaInfo.ID,
-		fechaHora,
-		especialistaInfo.DuracionMinutos,
-	)
+	// Quedarnos solo con los especialistas libres en ese horario
+	disponibles, err := s.findAvailableEspecialistas(candidatos, fechaHora)
 	if err != nil {
-		return nil, fmt.Errorf("error al verificar disponibilidad: %v", err)
+		return nil, err
 	}
 
-	if !isAvailable {
+	if len(disponibles) == 0 {
+		if req.EspecialistaID != 0 {
+			return nil, fmt.Errorf("el especialista seleccionado no está disponible en ese horario. Por favor elige otro horario")
+		}
 		return nil, fmt.Errorf("el horario seleccionado no está disponible. Por favor elige otro horario")
 	}
 
+	especialistaInfo, err := s.pickEspecialista(disponibles, pacienteID, fechaHora)
+	if err != nil {
+		return nil, fmt.Errorf("error al asignar especialista: %v", err)
+	}
+
 	// Crear la cita
 	appointment := &models.Appointment{
 		PacienteID:     pacienteID,
@@ -82,39 +144,51 @@ func (s *AppointmentService) CreateAppointment(userID int, req *models.CreateApp
 		TratamientoID:  especialistaInfo.TratamientoID,
 		FechaHora:      fechaHora,
 		Motivo:         req.Mensaje,
-		Estado:         "programada",
+		Estado:         models.EstadoProgramada,
 		Notas:          "",
 	}
 
-	err = s.appointmentRepo.Create(appointment)
-	if err != nil {
-		return nil, fmt.Errorf("error al crear cita: %v", err)
-	}
+	// La cita, los enlaces del especialista y su email se guardan en una sola transacción
+	var appointmentDetails *models.AppointmentWithDetails
+	err = s.inTransaction(func(tx *AppointmentService) error {
+		if err := tx.appointmentRepo.Create(appointment); err != nil {
+			return fmt.Errorf("error al crear cita: %v", err)
+		}
 
-	// Obtener la cita completa con detalles
-	appointmentDetails, err := s.appointmentRepo.FindByID(appointment.ID)
-	if err != nil {
-		return nil, fmt.Errorf("error al obtener detalles de la cita: %v", err)
-	}
+		// Obtener la cita completa con detalles
+		appointmentDetails, err = tx.appointmentRepo.FindByID(appointment.ID)
+		if err != nil {
+			return fmt.Errorf("error al obtener detalles de la cita: %v", err)
+		}
+
+		// Email al especialista con enlaces firmados para confirmar o responder
+		links, err := tx.issueSpecialistLinks(appointmentDetails)
+		if err != nil {
+			return fmt.Errorf("error al generar enlaces para el especialista: %v", err)
+		}
 
-	// Enviar emails de confirmación de forma asíncrona ----
-	go func() {
-		// Email al especialista
-		if err := s.emailService.SendAppointmentNotificationToSpecialist(appointmentDetails); err != nil {
-			fmt.Printf("Error al enviar email al especialista: %v\n", err)
+		message, err := s.emailService.AppointmentNotificationEmail(appointmentDetails, links)
+		if err != nil {
+			return err
 		}
-	}()
+
+		return tx.appointmentRepo.EnqueueEmails(message)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Construir respuesta
 	response := &models.AppointmentResponse{
 		ID:             appointment.ID,
-		NombrePaciente: req.NombrePaciente,
-		Telefono:       req.Telefono,
-		Email:          req.Email,
-		Servicio:       req.Servicio,
+		PacienteID:     pacienteID,
+		NombrePaciente: strings.TrimSpace(appointmentDetails.NombrePaciente),
+		Telefono:       appointmentDetails.TelefonoPaciente,
+		Email:          appointmentDetails.EmailPaciente,
+		Servicio:       especialistaInfo.Tratamiento,
 		FechaCita:      req.FechaCita,
 		HoraCita:       req.HoraCita,
-		Estado:         "programada",
+		Estado:         models.EstadoProgramada,
 		Mensaje:        req.Mensaje,
 		CreatedAt:      time.Now(),
 	}
@@ -122,6 +196,114 @@ func (s *AppointmentService) CreateAppointment(userID int, req *models.CreateApp
 	return response, nil
 }
 
+// GetAvailability calcula los horarios libres de cada especialista que atiende un servicio
+func (s *AppointmentService) GetAvailability(servicio, from, to string) ([]models.AvailabilityResponse, error) {
+	if servicio == "" {
+		return nil, fmt.Errorf("el servicio es requerido")
+	}
+
+	if from == "" {
+		return nil, fmt.Errorf("la fecha inicial es requerida")
+	}
+
+	if to == "" {
+		to = from
+	}
+
+	fromDate, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("formato de fecha inválido. Use YYYY-MM-DD")
+	}
+
+	toDate, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("formato de fecha inválido. Use YYYY-MM-DD")
+	}
+
+	if toDate.Before(fromDate) {
+		return nil, fmt.Errorf("rango de fechas inválido: la fecha final es anterior a la inicial")
+	}
+
+	if toDate.Sub(fromDate) >= maxAvailabilityDays*24*time.Hour {
+		return nil, fmt.Errorf("rango de fechas inválido: máximo %d días", maxAvailabilityDays)
+	}
+
+	especialistas, err := s.appointmentRepo.FindEspecialistasByServicio(servicio)
+	if err != nil {
+		return nil, err
+	}
+
+	rangeStart := fromDate
+	rangeEnd := toDate.AddDate(0, 0, 1)
+	now := time.Now()
+
+	availability := make([]models.AvailabilityResponse, 0, len(especialistas))
+	for _, especialista := range especialistas {
+		duracion := especialista.DuracionMinutos
+		if duracion <= 0 {
+			duracion = 30
+		}
+
+		jornadas, err := s.scheduleRepo.FindByEspecialista(especialista.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		jornadaPorDia := make(map[time.Weekday]models.HorarioEspecialista, len(jornadas))
+		for _, jornada := range jornadas {
+			jornadaPorDia[time.Weekday(jornada.DiaSemana)] = jornada
+		}
+
+		bloqueos, err := s.scheduleRepo.FindBlocks(especialista.ID, rangeStart, rangeEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		// Una sola consulta por especialista; el traslape se evalúa en memoria
+		ocupadas, err := s.appointmentRepo.FindOverlapping(especialista.ID, rangeStart, rangeEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		horarios := []models.AvailableSlot{}
+		for day := rangeStart; day.Before(rangeEnd); day = day.AddDate(0, 0, 1) {
+			jornada, ok := jornadaPorDia[day.Weekday()]
+			if !ok {
+				continue
+			}
+
+			opening, closing := jornada.Bounds(day)
+
+			for start := opening; !start.Add(time.Duration(duracion) * time.Minute).After(closing); start = start.Add(slotIntervalMinutes * time.Minute) {
+				if start.Before(now) || !jornada.Covers(start, duracion) {
+					continue
+				}
+
+				end := start.Add(time.Duration(duracion) * time.Minute)
+				if overlapsAny(ocupadas, start, end) || blockedAny(bloqueos, start, end) {
+					continue
+				}
+
+				horarios = append(horarios, models.AvailableSlot{
+					FechaCita: start.Format("2006-01-02"),
+					HoraCita:  start.Format("15:04"),
+				})
+			}
+		}
+
+		availability = append(availability, models.AvailabilityResponse{
+			EspecialistaID:     especialista.ID,
+			NombreEspecialista: especialista.Nombre,
+			TratamientoID:      especialista.TratamientoID,
+			Tratamiento:        especialista.Tratamiento,
+			DuracionMinutos:    duracion,
+			Horarios:           horarios,
+		})
+	}
+
+	return availability, nil
+}
+
 // GetUserAppointments obtiene todas las citas de un usuario
 func (s *AppointmentService) GetUserAppointments(userID int) ([]models.AppointmentResponse, error) {
 	appointments, err := s.appointmentRepo.FindByUserID(userID)
@@ -136,8 +318,12 @@ func (s *AppointmentService) GetUserAppointments(userID int) ([]models.Appointme
 	return appointments, nil
 }
 
-// GetAppointmentByID obtiene una cita específica
-func (s *AppointmentService) GetAppointmentByID(id int) (*models.AppointmentWithDetails, error) {
+// GetAppointmentByID obtiene una cita específica si el usuario tiene acceso a ella
+func (s *AppointmentService) GetAppointmentByID(id int, by ChangedBy) (*models.AppointmentWithDetails, error) {
+	if err := s.authorizeAppointment(id, by); err != nil {
+		return nil, err
+	}
+
 	appointment, err := s.appointmentRepo.FindByID(id)
 	if err != nil {
 		return nil, err
@@ -150,26 +336,283 @@ func (s *AppointmentService) GetAppointmentByID(id int) (*models.AppointmentWith
 	return appointment, nil
 }
 
-// CancelAppointment cancela una cita
-func (s *AppointmentService) CancelAppointment(id int) error {
-	return s.appointmentRepo.Delete(id)
+// CancelAppointment cancela una cita si el usuario tiene acceso a ella y avisa al paciente
+// para que la quite de su calendario
+func (s *AppointmentService) CancelAppointment(id int, by ChangedBy) error {
+	if err := s.authorizeAppointment(id, by); err != nil {
+		return err
+	}
+
+	return s.inTransaction(func(tx *AppointmentService) error {
+		if _, err := tx.transitionAppointment(id, models.EstadoCancelada, by, nil); err != nil {
+			return err
+		}
+		return tx.enqueueForAppointment(id, tx.emailService.AppointmentCancelledEmail)
+	})
 }
 
-// validateAppointmentRequest valida los datos de la solicitud
-func (s *AppointmentService) validateAppointmentRequest(req *models.CreateAppointmentRequest) error {
+// authorizeAppointment verifica que el usuario pueda ver o modificar la cita. Recepción y
+// administración acceden a todas, un especialista a las que tiene asignadas y un paciente
+// solo a las suyas. A un paciente se le responde que la cita no existe para no revelar IDs ajenos
+func (s *AppointmentService) authorizeAppointment(id int, by ChangedBy) error {
+	switch by.Actor {
+	case ActorAdmin, ActorRecepcion:
+		return nil
+
+	case ActorEspecialista:
+		_, err := s.assignedAppointment(id, by.UsuarioID)
+		return err
+
+	case ActorPaciente:
+		owned, err := s.appointmentRepo.IsOwnedByUser(id, by.UsuarioID)
+		if err != nil {
+			return err
+		}
+
+		if !owned {
+			return fmt.Errorf("cita no encontrada")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("acceso denegado: rol desconocido")
+}
+
+// CheckInAppointment registra la llegada del paciente y pone la cita en curso
+func (s *AppointmentService) CheckInAppointment(id int, by ChangedBy) error {
+	_, err := s.staffAction(id, by, models.EstadoEnCurso, nil)
+	return err
+}
+
+// CompleteAppointment cierra la consulta guardando las notas del especialista
+func (s *AppointmentService) CompleteAppointment(id int, by ChangedBy, notas string) error {
+	_, err := s.staffAction(id, by, models.EstadoCompletada, &notas)
+	return err
+}
+
+// MarkNoShow marca que el paciente no se presentó a la cita
+func (s *AppointmentService) MarkNoShow(id int, by ChangedBy) error {
+	appointment, err := s.appointmentRepo.FindAppointment(id)
+	if err != nil {
+		return err
+	}
+
+	if time.Now().Before(appointment.FechaHora) {
+		return fmt.Errorf("no se puede marcar inasistencia antes de la hora de la cita")
+	}
+
+	_, err = s.staffAction(id, by, models.EstadoNoAsistio, nil)
+	return err
+}
+
+// GetAppointmentHistory obtiene el historial de cambios de estado de una cita si el usuario
+// tiene acceso a ella
+func (s *AppointmentService) GetAppointmentHistory(id int, by ChangedBy) ([]models.CambioEstadoCita, error) {
+	if err := s.authorizeAppointment(id, by); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.appointmentRepo.FindAppointment(id); err != nil {
+		return nil, err
+	}
+
+	cambios, err := s.appointmentRepo.FindStatusHistory(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if cambios == nil {
+		return []models.CambioEstadoCita{}, nil
+	}
+
+	return cambios, nil
+}
+
+// staffAction aplica un cambio de estado del personal de la clínica. Un especialista solo
+// puede actuar sobre las citas que tiene asignadas; los permisos de recepción y administración
+// los define la máquina de estados
+func (s *AppointmentService) staffAction(id int, by ChangedBy, to string, notas *string) (*models.Appointment, error) {
+	if by.Actor == ActorEspecialista {
+		if err := s.authorizeAppointment(id, by); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.transitionAppointment(id, to, by, notas)
+}
+
+// assignedAppointment obtiene una cita verificando que la cuenta esté vinculada
+// al especialista que la tiene asignada
+func (s *AppointmentService) assignedAppointment(id, userID int) (*models.Appointment, error) {
+	especialista, err := s.appointmentRepo.FindEspecialistaByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if especialista == nil {
+		return nil, fmt.Errorf("acceso denegado: la cuenta no está vinculada a un especialista")
+	}
+
+	appointment, err := s.appointmentRepo.FindAppointment(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if appointment.EspecialistaID != especialista.ID {
+		return nil, fmt.Errorf("acceso denegado: la cita está asignada a otro especialista")
+	}
+
+	return appointment, nil
+}
+
+// RescheduleAppointment mueve una cita a otra fecha conservando su historial.
+// La cita vuelve a programada y el especialista debe confirmarla de nuevo
+func (s *AppointmentService) RescheduleAppointment(id int, req *models.RescheduleAppointmentRequest, by ChangedBy) (*models.AppointmentWithDetails, error) {
+	if req.FechaCita == "" {
+		return nil, fmt.Errorf("la fecha de la cita es requerida")
+	}
+
+	if req.HoraCita == "" {
+		return nil, fmt.Errorf("la hora de la cita es requerida")
+	}
+
+	fechaHora, err := parseFechaHora(req.FechaCita, req.HoraCita)
+	if err != nil {
+		return nil, err
+	}
+
+	if fechaHora.Before(time.Now()) {
+		return nil, fmt.Errorf("no se puede agendar cita en el pasado")
+	}
+
+	if err := s.authorizeAppointment(id, by); err != nil {
+		return nil, err
+	}
+
+	appointment, err := s.appointmentRepo.FindAppointment(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckTransition(appointment.Estado, models.EstadoProgramada, by.Actor); err != nil {
+		return nil, err
+	}
+
+	previousFechaHora := appointment.FechaHora
+
+	isAvailable, err := s.appointmentRepo.CheckAvailabilityExcluding(
+		appointment.EspecialistaID,
+		fechaHora,
+		appointment.DuracionMinutos,
+		appointment.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error al verificar disponibilidad: %v", err)
+	}
+
+	if !isAvailable {
+		return nil, fmt.Errorf("el horario seleccionado no está disponible. Por favor elige otro horario")
+	}
+
+	notas := fmt.Sprintf("Reagendada desde %s", previousFechaHora.Format("2006-01-02 15:04"))
+	cambio := by.newCambio(id, appointment.Estado, models.EstadoProgramada, notas)
+
+	var details *models.AppointmentWithDetails
+	err = s.inTransaction(func(tx *AppointmentService) error {
+		rescheduled, err := tx.appointmentRepo.Reschedule(fechaHora, cambio)
+		if err != nil {
+			return err
+		}
+
+		if !rescheduled {
+			return &TransitionError{From: appointment.Estado, To: models.EstadoProgramada, Actor: by.Actor, Reason: "la cita cambió de estado mientras se procesaba"}
+		}
+
+		details, err = tx.appointmentRepo.FindByID(id)
+		if err != nil {
+			return fmt.Errorf("error al obtener detalles de la cita: %v", err)
+		}
+
+		links, err := tx.issueSpecialistLinks(details)
+		if err != nil {
+			return fmt.Errorf("error al generar enlaces para el especialista: %v", err)
+		}
+
+		message, err := s.emailService.AppointmentRescheduleEmail(details, previousFechaHora, links)
+		if err != nil {
+			return err
+		}
+
+		return tx.appointmentRepo.EnqueueEmails(message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
+
+// resolvePaciente determina el expediente de la cita. Un paciente agenda para sí o para sus
+// dependientes; el personal elige un expediente o da los datos de un paciente sin cuenta
+func (s *AppointmentService) resolvePaciente(by ChangedBy, req *models.CreateAppointmentRequest) (*models.Paciente, error) {
+	if by.Actor == ActorPaciente {
+		if req.PacienteID == 0 {
+			return s.patientRepo.EnsureTitular(by.UsuarioID)
+		}
+
+		paciente, err := s.patientRepo.FindByID(req.PacienteID)
+		if err != nil {
+			return nil, err
+		}
+
+		if paciente == nil || !paciente.Activo || paciente.UsuarioID == nil || *paciente.UsuarioID != by.UsuarioID {
+			return nil, ErrPacienteNotFound
+		}
+		return paciente, nil
+	}
+
+	if req.PacienteID != 0 {
+		paciente, err := s.patientRepo.FindByID(req.PacienteID)
+		if err != nil {
+			return nil, err
+		}
+
+		if paciente == nil {
+			return nil, ErrPacienteNotFound
+		}
+		return paciente, nil
+	}
+
 	if req.NombrePaciente == "" {
-		return fmt.Errorf("el nombre del paciente es requerido")
+		return nil, fmt.Errorf("el nombre del paciente es requerido")
 	}
 
 	if req.Email == "" {
-		return fmt.Errorf("el email es requerido")
+		return nil, fmt.Errorf("el email es requerido")
 	}
 
 	if req.Telefono == "" {
-		return fmt.Errorf("el teléfono es requerido")
+		return nil, fmt.Errorf("el teléfono es requerido")
 	}
 
-	if req.Servicio == "" {
+	// Parsear nombre completo del paciente
+	nombreParts := strings.Fields(req.NombrePaciente)
+	if len(nombreParts) < 2 {
+		return nil, fmt.Errorf("el nombre debe incluir nombre y apellido")
+	}
+	nombre := nombreParts[0]
+	apellido := strings.Join(nombreParts[1:], " ")
+
+	paciente, err := s.patientRepo.FindOrCreateByEmail(nombre, apellido, strings.ToLower(strings.TrimSpace(req.Email)), req.Telefono)
+	if err != nil {
+		return nil, fmt.Errorf("error al procesar paciente: %v", err)
+	}
+	return paciente, nil
+}
+
+// validateAppointmentRequest valida los datos de la solicitud
+func (s *AppointmentService) validateAppointmentRequest(req *models.CreateAppointmentRequest) error {
+	if req.Servicio == "" && req.TratamientoID == 0 {
 		return fmt.Errorf("el servicio es requerido")
 	}
 
@@ -196,36 +639,226 @@ func (s *AppointmentService) validateAppointmentRequest(req *models.CreateAppoin
 	return nil
 }
 
-// ConfirmAppointment confirma una cita y envía email al paciente
-func (s *AppointmentService) ConfirmAppointment(id int) error {
-	// Primero obtener los detalles de la cita ANTES de confirmar
+// resolveCandidatos determina los especialistas elegibles para la cita. Si el paciente eligió
+// tratamiento y/o especialista se validan; si no, se usan todos los que atienden el servicio
+func (s *AppointmentService) resolveCandidatos(req *models.CreateAppointmentRequest) ([]models.EspecialistaServicio, error) {
+	var candidatos []models.EspecialistaServicio
+
+	if req.TratamientoID != 0 {
+		tratamiento, err := s.appointmentRepo.FindTratamientoByID(req.TratamientoID)
+		if err != nil {
+			return nil, err
+		}
+
+		if tratamiento == nil || !tratamiento.Activo {
+			return nil, fmt.Errorf("tratamiento inválido o inactivo")
+		}
+
+		candidatos, err = s.appointmentRepo.FindEspecialistasByTratamiento(tratamiento)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		candidatos, err = s.appointmentRepo.FindEspecialistasByServicio(req.Servicio)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.EspecialistaID == 0 {
+		return candidatos, nil
+	}
+
+	especialista, err := s.appointmentRepo.FindEspecialistaByID(req.EspecialistaID)
+	if err != nil {
+		return nil, err
+	}
+
+	if especialista == nil || !especialista.Activo {
+		return nil, fmt.Errorf("especialista inválido o inactivo")
+	}
+
+	for _, candidato := range candidatos {
+		if candidato.ID == especialista.ID {
+			return []models.EspecialistaServicio{candidato}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("el especialista seleccionado no atiende este servicio")
+}
+
+// parseFechaHora combina la fecha (YYYY-MM-DD) y la hora (HH:MM) de una cita
+func parseFechaHora(fecha, hora string) (time.Time, error) {
+	fechaHora, err := time.Parse("2006-01-02 15:04", fecha+" "+hora)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("formato de fecha u hora inválido: %v", err)
+	}
+	return fechaHora, nil
+}
+
+// overlapsAny indica si el rango [start, end) se traslapa con alguna de las citas,
+// con el mismo criterio que AppointmentRepository.CheckAvailability
+func overlapsAny(appointments []models.Appointment, start, end time.Time) bool {
+	for _, a := range appointments {
+		aEnd := a.FechaHora.Add(time.Duration(a.DuracionMinutos) * time.Minute)
+		if a.FechaHora.Before(end) && aEnd.After(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedAny indica si el rango [start, end) cae dentro de algún bloqueo de agenda
+func blockedAny(bloqueos []models.BloqueoAgenda, start, end time.Time) bool {
+	for _, b := range bloqueos {
+		if b.Overlaps(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfirmAppointment confirma una cita con el enlace firmado enviado al especialista y avisa al paciente.
+// El enlace usado, el cambio de estado y el email se guardan en una sola transacción
+func (s *AppointmentService) ConfirmAppointment(id int, token string) error {
+	return s.inTransaction(func(tx *AppointmentService) error {
+		if err := tx.consumeLinkToken(id, token, models.AccionConfirmar); err != nil {
+			return err
+		}
+
+		if _, err := tx.transitionAppointment(id, models.EstadoConfirmada, ChangedBy{Actor: ActorEspecialista}, nil); err != nil {
+			return err
+		}
+
+		return tx.enqueueForAppointment(id, s.emailService.AppointmentConfirmationEmail)
+	})
+}
+
+// inTransaction ejecuta fn con una copia del servicio cuyo repositorio de citas opera dentro de
+// una transacción, para que los emails encolados se confirmen o descarten junto con el cambio
+func (s *AppointmentService) inTransaction(fn func(tx *AppointmentService) error) error {
+	return s.appointmentRepo.Transaction(func(repo *repositories.AppointmentRepository) error {
+		tx := *s
+		tx.appointmentRepo = repo
+		return fn(&tx)
+	})
+}
+
+// enqueueForAppointment encola el email que build arma con los detalles actuales de la cita
+func (s *AppointmentService) enqueueForAppointment(id int, build func(*models.AppointmentWithDetails) (*models.EmailSaliente, error)) error {
 	details, err := s.appointmentRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("error al obtener detalles de la cita: %v", err)
+	}
+
+	email, err := build(details)
 	if err != nil {
 		return err
 	}
 
-	if details == nil {
-		return fmt.Errorf("cita no encontrada")
+	return s.appointmentRepo.EnqueueEmails(email)
+}
+
+// issueSpecialistLinks emite los enlaces para confirmar y para rechazar o proponer otro horario
+func (s *AppointmentService) issueSpecialistLinks(details *models.AppointmentWithDetails) (SpecialistLinks, error) {
+	confirmToken, err := s.issueLinkToken(details, models.AccionConfirmar, nil)
+	if err != nil {
+		return SpecialistLinks{}, err
 	}
 
-	// Confirmar la cita
-	err = s.appointmentRepo.UpdateStatus(id, "confirmada")
+	respondToken, err := s.issueLinkToken(details, models.AccionResponder, nil)
+	if err != nil {
+		return SpecialistLinks{}, err
+	}
+
+	return SpecialistLinks{ConfirmToken: confirmToken, RespondToken: respondToken}, nil
+}
+
+// issueLinkToken firma y registra un enlace de un solo uso para ejecutar una acción sobre la cita.
+// Expira tras linkTTL o al llegar la hora de la cita, lo que ocurra primero. Los enlaces para
+// aceptar un horario propuesto llevan la propuesta y expiran a más tardar a la hora propuesta
+func (s *AppointmentService) issueLinkToken(details *models.AppointmentWithDetails, accion string, propuesta *models.PropuestaHorario) (string, error) {
+	deadline := details.FechaHora
+	propuestaID := 0
+	if propuesta != nil {
+		deadline = propuesta.FechaHora
+		propuestaID = propuesta.ID
+	}
+
+	expiresAt := time.Now().Add(s.linkTTL)
+	if deadline.Before(expiresAt) {
+		expiresAt = deadline
+	}
+
+	token, jti, err := utils.GenerateLinkToken(details.ID, details.EspecialistaID, propuestaID, accion, details.FechaHora, expiresAt, s.linkSecret)
+	if err != nil {
+		return "", err
+	}
+
+	enlace := &models.EnlaceCita{
+		JTI:            jti,
+		CitaID:         details.ID,
+		EspecialistaID: details.EspecialistaID,
+		Accion:         accion,
+		ExpiraEn:       expiresAt,
+	}
+	if propuestaID > 0 {
+		enlace.PropuestaID = &propuestaID
+	}
+
+	if err := s.appointmentRepo.CreateLink(enlace); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeLinkToken valida un enlace firmado contra la cita actual y lo marca como usado
+func (s *AppointmentService) consumeLinkToken(id int, token, accion string) error {
+	claims, _, err := s.verifyLinkToken(id, token, accion)
 	if err != nil {
 		return err
 	}
-	go func() {
-		// Obtener los detalles actualizados
-		updatedDetails, err := s.appointmentRepo.FindByID(id)
-		if err != nil {
-			fmt.Printf("Error al obtener detalles actualizados: %v\n", err)
-			return
-		}
 
-		// Enviar email de confirmación al paciente
-		if err := s.emailService.SendAppointmentConfirmationToPatient(updatedDetails); err != nil {
-			fmt.Printf("Error al enviar email al paciente: %v\n", err)
-		}
-	}()
+	return s.useLinkToken(claims)
+}
+
+// verifyLinkToken valida un enlace firmado contra la cita actual sin marcarlo como usado,
+// para poder validar el resto de la petición antes de gastar el enlace
+func (s *AppointmentService) verifyLinkToken(id int, token, accion string) (*utils.LinkClaims, *models.Appointment, error) {
+	claims, err := utils.ValidateLinkToken(token, s.linkSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if claims.CitaID != id || claims.Accion != accion {
+		return nil, nil, utils.ErrLinkTokenInvalid
+	}
+
+	appointment, err := s.appointmentRepo.FindAppointment(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// El enlace pertenece al especialista y al horario vigentes de la cita
+	if appointment.EspecialistaID != claims.EspecialistaID || appointment.FechaHora.Unix() != claims.FechaHora {
+		return nil, nil, utils.ErrLinkTokenInvalid
+	}
+
+	return claims, appointment, nil
+}
+
+// useLinkToken marca como usado un enlace ya verificado
+func (s *AppointmentService) useLinkToken(claims *utils.LinkClaims) error {
+	consumed, err := s.appointmentRepo.ConsumeLink(claims.ID, claims.CitaID, claims.Accion)
+	if err != nil {
+		return err
+	}
+
+	if !consumed {
+		return ErrLinkTokenUsed
+	}
 
 	return nil
 }
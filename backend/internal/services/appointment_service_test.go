@@ -0,0 +1,100 @@
+// backend/internal/services/appointment_service_test.go
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// memoryAppointments appointmentStore en memoria con las consultas que usan las pruebas; el
+// resto de los métodos no está implementado
+type memoryAppointments struct {
+	appointmentStore
+	citas         map[int]models.Appointment
+	detalles      map[int]*models.AppointmentWithDetails
+	titulares     map[int]int // paciente_id -> usuario_id de la cuenta a la que pertenece
+	especialistas map[int]int // usuario_id -> especialista_id vinculado
+}
+
+func (m *memoryAppointments) FindAppointment(id int) (*models.Appointment, error) {
+	appointment, ok := m.citas[id]
+	if !ok {
+		return nil, fmt.Errorf("cita no encontrada")
+	}
+	return &appointment, nil
+}
+
+func (m *memoryAppointments) FindByID(id int) (*models.AppointmentWithDetails, error) {
+	return m.detalles[id], nil
+}
+
+func (m *memoryAppointments) FindEspecialistaByUserID(userID int) (*models.Especialista, error) {
+	id, ok := m.especialistas[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &models.Especialista{ID: id}, nil
+}
+
+func (m *memoryAppointments) IsOwnedByUser(citaID, userID int) (bool, error) {
+	appointment, ok := m.citas[citaID]
+	if !ok {
+		return false, nil
+	}
+	return m.titulares[appointment.PacienteID] == userID, nil
+}
+
+func TestAuthorizeAppointment(t *testing.T) {
+	// La cuenta 100 es titular del paciente 10 y tiene al dependiente 11; la cuenta 300 está
+	// vinculada al especialista 3 y la 301 a ninguno
+	service := &AppointmentService{appointmentRepo: &memoryAppointments{
+		citas: map[int]models.Appointment{
+			1: {ID: 1, PacienteID: 10, EspecialistaID: 3},
+			2: {ID: 2, PacienteID: 11, EspecialistaID: 4},
+			3: {ID: 3, PacienteID: 12, EspecialistaID: 3},
+		},
+		titulares:     map[int]int{10: 100, 11: 100, 12: 200},
+		especialistas: map[int]int{300: 3},
+	}}
+
+	tests := []struct {
+		name    string
+		citaID  int
+		by      ChangedBy
+		wantErr string
+	}{
+		{"admin ve cualquier cita", 2, ChangedBy{Actor: ActorAdmin, UsuarioID: 1}, ""},
+		{"recepción ve cualquier cita", 3, ChangedBy{Actor: ActorRecepcion, UsuarioID: 2}, ""},
+		{"especialista ve su cita", 1, ChangedBy{Actor: ActorEspecialista, UsuarioID: 300}, ""},
+		{"especialista no ve cita de otro", 2, ChangedBy{Actor: ActorEspecialista, UsuarioID: 300}, "acceso denegado: la cita está asignada a otro especialista"},
+		{"cuenta sin especialista vinculado", 1, ChangedBy{Actor: ActorEspecialista, UsuarioID: 301}, "acceso denegado: la cuenta no está vinculada a un especialista"},
+		{"especialista con cita inexistente", 99, ChangedBy{Actor: ActorEspecialista, UsuarioID: 300}, "cita no encontrada"},
+		{"paciente ve su cita", 1, ChangedBy{Actor: ActorPaciente, UsuarioID: 100}, ""},
+		{"paciente ve cita de su dependiente", 2, ChangedBy{Actor: ActorPaciente, UsuarioID: 100}, ""},
+		{"paciente no ve cita ajena", 3, ChangedBy{Actor: ActorPaciente, UsuarioID: 100}, "cita no encontrada"},
+		{"paciente con cita inexistente", 99, ChangedBy{Actor: ActorPaciente, UsuarioID: 100}, "cita no encontrada"},
+		{"sistema no consulta citas", 1, ChangedBy{Actor: ActorSistema}, "acceso denegado: rol desconocido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.authorizeAppointment(tt.citaID, tt.by)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("error inesperado: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("se esperaba error %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, se esperaba %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// backend/internal/services/appointment_state.go
+package services
+
+import (
+	"fmt"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// Actor quien solicita un cambio de estado de una cita
+type Actor string
+
+const (
+	ActorPaciente     Actor = "paciente"
+	ActorEspecialista Actor = "especialista"
+	ActorRecepcion    Actor = "recepcion"
+	ActorAdmin        Actor = "admin"
+	// ActorSistema cambios que la aplicación hace por su cuenta como consecuencia de otro,
+	// p. ej. confirmar la cita cuando el paciente acepta un horario propuesto por el especialista
+	ActorSistema Actor = "sistema"
+)
+
+// ChangedBy identifica a quien realiza un cambio de estado. UsuarioID es 0 cuando la
+// acción llega desde un enlace de email sin sesión
+type ChangedBy struct {
+	Actor     Actor
+	UsuarioID int
+}
+
+// newCambio construye el registro de historial para un cambio de estado
+func (by ChangedBy) newCambio(citaID int, from, to, notas string) *models.CambioEstadoCita {
+	cambio := &models.CambioEstadoCita{
+		CitaID:         citaID,
+		EstadoAnterior: from,
+		EstadoNuevo:    to,
+		Actor:          string(by.Actor),
+		Notas:          notas,
+	}
+	if by.UsuarioID > 0 {
+		usuarioID := by.UsuarioID
+		cambio.UsuarioID = &usuarioID
+	}
+	return cambio
+}
+
+type transition struct {
+	from string
+	to   string
+}
+
+var (
+	anyone    = []Actor{ActorPaciente, ActorEspecialista, ActorRecepcion, ActorAdmin}
+	staff     = []Actor{ActorEspecialista, ActorRecepcion, ActorAdmin}
+	clinician = []Actor{ActorEspecialista, ActorAdmin}
+)
+
+// appointmentTransitions define los cambios de estado permitidos y quién puede realizarlos.
+// Cualquier transición que no aparezca aquí es ilegal. cancelada, completada y no_asistio son finales
+var appointmentTransitions = map[transition][]Actor{
+	// Reagendar regresa la cita a programada
+	{models.EstadoProgramada, models.EstadoProgramada}: anyone,
+	{models.EstadoConfirmada, models.EstadoProgramada}: anyone,
+
+	{models.EstadoProgramada, models.EstadoConfirmada}: append(staff, ActorSistema),
+	{models.EstadoProgramada, models.EstadoCancelada}:  anyone,
+	{models.EstadoConfirmada, models.EstadoCancelada}:  anyone,
+
+	{models.EstadoProgramada, models.EstadoEnCurso}:   staff,
+	{models.EstadoConfirmada, models.EstadoEnCurso}:   staff,
+	{models.EstadoProgramada, models.EstadoNoAsistio}: staff,
+	{models.EstadoConfirmada, models.EstadoNoAsistio}: staff,
+	{models.EstadoEnCurso, models.EstadoCompletada}:   clinician,
+}
+
+// TransitionError indica un cambio de estado no permitido
+type TransitionError struct {
+	From   string
+	To     string
+	Actor  Actor
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("transición de estado inválida de %s a %s: %s", e.From, e.To, e.Reason)
+}
+
+// CheckTransition verifica que actor pueda mover una cita del estado from al estado to
+func CheckTransition(from, to string, actor Actor) error {
+	actors, ok := appointmentTransitions[transition{from, to}]
+	if !ok {
+		reason := "transición no permitida"
+		if from == to || isFinalEstado(from) {
+			reason = fmt.Sprintf("la cita ya está %s", from)
+		}
+		return &TransitionError{From: from, To: to, Actor: actor, Reason: reason}
+	}
+
+	for _, allowed := range actors {
+		if allowed == actor {
+			return nil
+		}
+	}
+
+	return &TransitionError{From: from, To: to, Actor: actor, Reason: fmt.Sprintf("%s no tiene permiso para este cambio", actor)}
+}
+
+func isFinalEstado(estado string) bool {
+	return estado == models.EstadoCancelada ||
+		estado == models.EstadoCompletada ||
+		estado == models.EstadoNoAsistio
+}
+
+// transitionAppointment aplica un cambio de estado validado por la máquina de estados y lo
+// registra en el historial. La actualización es condicional al estado leído para no pisar
+// cambios concurrentes. Si notas no es nil también se guardan en la cita
+func (s *AppointmentService) transitionAppointment(id int, to string, by ChangedBy, notas *string) (*models.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindAppointment(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckTransition(appointment.Estado, to, by.Actor); err != nil {
+		return nil, err
+	}
+
+	notasCambio := ""
+	if notas != nil {
+		notasCambio = *notas
+	}
+
+	cambio := by.newCambio(id, appointment.Estado, to, notasCambio)
+	updated, err := s.appointmentRepo.UpdateStatusFrom(cambio, notas)
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return nil, &TransitionError{From: appointment.Estado, To: to, Actor: by.Actor, Reason: "la cita cambió de estado mientras se procesaba"}
+	}
+
+	appointment.Estado = to
+	if notas != nil {
+		appointment.Notas = *notas
+	}
+	return appointment, nil
+}
@@ -0,0 +1,110 @@
+// backend/internal/services/appointment_state_test.go
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		actor   Actor
+		allowed bool
+		reason  string
+	}{
+		{"paciente reagenda", models.EstadoProgramada, models.EstadoProgramada, ActorPaciente, true, ""},
+		{"paciente reagenda confirmada", models.EstadoConfirmada, models.EstadoProgramada, ActorPaciente, true, ""},
+		{"especialista confirma", models.EstadoProgramada, models.EstadoConfirmada, ActorEspecialista, true, ""},
+		{"recepcion confirma", models.EstadoProgramada, models.EstadoConfirmada, ActorRecepcion, true, ""},
+		{"sistema confirma propuesta aceptada", models.EstadoProgramada, models.EstadoConfirmada, ActorSistema, true, ""},
+		{"paciente no confirma", models.EstadoProgramada, models.EstadoConfirmada, ActorPaciente, false, "paciente no tiene permiso"},
+		{"paciente cancela", models.EstadoConfirmada, models.EstadoCancelada, ActorPaciente, true, ""},
+		{"sistema no cancela", models.EstadoConfirmada, models.EstadoCancelada, ActorSistema, false, "sistema no tiene permiso"},
+		{"recepcion inicia", models.EstadoConfirmada, models.EstadoEnCurso, ActorRecepcion, true, ""},
+		{"paciente no inicia", models.EstadoConfirmada, models.EstadoEnCurso, ActorPaciente, false, "paciente no tiene permiso"},
+		{"recepcion marca inasistencia", models.EstadoProgramada, models.EstadoNoAsistio, ActorRecepcion, true, ""},
+		{"especialista completa", models.EstadoEnCurso, models.EstadoCompletada, ActorEspecialista, true, ""},
+		{"admin completa", models.EstadoEnCurso, models.EstadoCompletada, ActorAdmin, true, ""},
+		{"recepcion no completa", models.EstadoEnCurso, models.EstadoCompletada, ActorRecepcion, false, "recepcion no tiene permiso"},
+		{"no se completa sin iniciar", models.EstadoConfirmada, models.EstadoCompletada, ActorEspecialista, false, "transición no permitida"},
+		{"en curso no se cancela", models.EstadoEnCurso, models.EstadoCancelada, ActorAdmin, false, "transición no permitida"},
+		{"cancelada es final", models.EstadoCancelada, models.EstadoProgramada, ActorAdmin, false, "la cita ya está cancelada"},
+		{"completada es final", models.EstadoCompletada, models.EstadoCancelada, ActorAdmin, false, "la cita ya está completada"},
+		{"no_asistio es final", models.EstadoNoAsistio, models.EstadoConfirmada, ActorEspecialista, false, "la cita ya está no_asistio"},
+		{"confirmar dos veces", models.EstadoConfirmada, models.EstadoConfirmada, ActorEspecialista, false, "la cita ya está confirmada"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckTransition(tt.from, tt.to, tt.actor)
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("se esperaba permitida, error: %v", err)
+				}
+				return
+			}
+
+			var transitionErr *TransitionError
+			if !errors.As(err, &transitionErr) {
+				t.Fatalf("se esperaba *TransitionError, se obtuvo %v", err)
+			}
+			if transitionErr.From != tt.from || transitionErr.To != tt.to || transitionErr.Actor != tt.actor {
+				t.Errorf("error con datos inesperados: %+v", transitionErr)
+			}
+			if !strings.Contains(transitionErr.Reason, tt.reason) {
+				t.Errorf("motivo = %q, se esperaba que contenga %q", transitionErr.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestFinalEstadosHaveNoTransitions(t *testing.T) {
+	for tr := range appointmentTransitions {
+		if isFinalEstado(tr.from) {
+			t.Errorf("el estado final %s no debería tener transiciones (a %s)", tr.from, tr.to)
+		}
+	}
+}
+
+func TestChangedByNewCambio(t *testing.T) {
+	tests := []struct {
+		name      string
+		by        ChangedBy
+		usuarioID *int
+	}{
+		{"desde sesión", ChangedBy{Actor: ActorRecepcion, UsuarioID: 7}, intPtr(7)},
+		{"desde enlace de email", ChangedBy{Actor: ActorEspecialista}, nil},
+		{"sistema", ChangedBy{Actor: ActorSistema}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cambio := tt.by.newCambio(3, models.EstadoProgramada, models.EstadoConfirmada, "nota")
+
+			if cambio.CitaID != 3 || cambio.EstadoAnterior != models.EstadoProgramada ||
+				cambio.EstadoNuevo != models.EstadoConfirmada || cambio.Notas != "nota" {
+				t.Errorf("cambio inesperado: %+v", cambio)
+			}
+			if cambio.Actor != string(tt.by.Actor) {
+				t.Errorf("actor = %q, se esperaba %q", cambio.Actor, tt.by.Actor)
+			}
+
+			switch {
+			case tt.usuarioID == nil && cambio.UsuarioID != nil:
+				t.Errorf("usuario_id = %d, se esperaba nil", *cambio.UsuarioID)
+			case tt.usuarioID != nil && (cambio.UsuarioID == nil || *cambio.UsuarioID != *tt.usuarioID):
+				t.Errorf("usuario_id = %v, se esperaba %d", cambio.UsuarioID, *tt.usuarioID)
+			}
+		})
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,114 @@
+// backend/internal/services/assignment.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// AssignmentStrategy criterio para elegir especialista cuando varios están libres
+type AssignmentStrategy string
+
+const (
+	// AssignLeastBooked elige al especialista con menos citas ese día
+	AssignLeastBooked AssignmentStrategy = "least_booked"
+	// AssignRoundRobin elige al especialista que lleva más tiempo sin recibir una cita
+	AssignRoundRobin AssignmentStrategy = "round_robin"
+	// AssignPreviousSpecialist prefiere al especialista que ya atendió al paciente
+	AssignPreviousSpecialist AssignmentStrategy = "previous_specialist"
+)
+
+// ParseAssignmentStrategy interpreta el valor de configuración; los valores desconocidos usan least_booked
+func ParseAssignmentStrategy(value string) AssignmentStrategy {
+	switch strategy := AssignmentStrategy(value); strategy {
+	case AssignLeastBooked, AssignRoundRobin, AssignPreviousSpecialist:
+		return strategy
+	case "":
+		return AssignLeastBooked
+	default:
+		log.Printf("Estrategia de asignación desconocida %q, usando %s", value, AssignLeastBooked)
+		return AssignLeastBooked
+	}
+}
+
+// findAvailableEspecialistas filtra los candidatos que están libres en fechaHora
+func (s *AppointmentService) findAvailableEspecialistas(candidatos []models.EspecialistaServicio, fechaHora time.Time) ([]models.EspecialistaServicio, error) {
+	disponibles := []models.EspecialistaServicio{}
+
+	for _, candidato := range candidatos {
+		isAvailable, err := s.appointmentRepo.CheckAvailability(candidato.ID, fechaHora, candidato.DuracionMinutos)
+		if err != nil {
+			return nil, fmt.Errorf("error al verificar disponibilidad: %v", err)
+		}
+
+		if isAvailable {
+			disponibles = append(disponibles, candidato)
+		}
+	}
+
+	return disponibles, nil
+}
+
+// pickEspecialista elige entre los especialistas disponibles según la estrategia configurada
+func (s *AppointmentService) pickEspecialista(disponibles []models.EspecialistaServicio, pacienteID int, fechaHora time.Time) (*models.EspecialistaServicio, error) {
+	if len(disponibles) == 1 {
+		return &disponibles[0], nil
+	}
+
+	ids := make([]int, len(disponibles))
+	for i, e := range disponibles {
+		ids[i] = e.ID
+	}
+
+	switch s.assignmentStrategy {
+	case AssignPreviousSpecialist:
+		previousID, err := s.appointmentRepo.FindLastEspecialistaForPaciente(pacienteID, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range disponibles {
+			if disponibles[i].ID == previousID {
+				return &disponibles[i], nil
+			}
+		}
+
+		// Paciente nuevo para esta especialidad: repartir por carga
+		return s.pickLeastBooked(disponibles, ids, fechaHora)
+
+	case AssignRoundRobin:
+		last, err := s.appointmentRepo.LastAssignmentByEspecialista(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		chosen := 0
+		for i := 1; i < len(disponibles); i++ {
+			if last[disponibles[i].ID].Before(last[disponibles[chosen].ID]) {
+				chosen = i
+			}
+		}
+		return &disponibles[chosen], nil
+
+	default:
+		return s.pickLeastBooked(disponibles, ids, fechaHora)
+	}
+}
+
+func (s *AppointmentService) pickLeastBooked(disponibles []models.EspecialistaServicio, ids []int, fechaHora time.Time) (*models.EspecialistaServicio, error) {
+	totals, err := s.appointmentRepo.CountByEspecialistaOnDate(ids, fechaHora)
+	if err != nil {
+		return nil, err
+	}
+
+	chosen := 0
+	for i := 1; i < len(disponibles); i++ {
+		if totals[disponibles[i].ID] < totals[disponibles[chosen].ID] {
+			chosen = i
+		}
+	}
+	return &disponibles[chosen], nil
+}
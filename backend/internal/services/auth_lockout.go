@@ -0,0 +1,130 @@
+// backend/internal/services/auth_lockout.go
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// Política contra fuerza bruta en el inicio de sesión
+const (
+	lockoutThreshold = 5                // fallos seguidos que bloquean la cuenta
+	lockoutDuration  = 15 * time.Minute // duración del bloqueo y ventana de conteo
+	throttleAfter    = 3                // fallos a partir de los cuales se exige esperar entre intentos
+	maxThrottleDelay = 30 * time.Second
+	ipFailureLimit   = 20 // fallos desde una misma IP dentro de la ventana
+	ipFailureWindow  = 15 * time.Minute
+)
+
+// LoginThrottledError indica que el intento se rechazó sin verificar la contraseña
+type LoginThrottledError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return e.Message
+}
+
+// checkLoginAllowed aplica el límite por IP, el bloqueo temporal de la cuenta y la espera
+// progresiva entre intentos fallidos
+func (s *AuthService) checkLoginAllowed(email, ip string) error {
+	now := time.Now()
+
+	if ip != "" {
+		count, err := s.attemptRepo.CountFailuresByIP(ip, now.Add(-ipFailureWindow))
+		if err != nil {
+			return err
+		}
+
+		if count >= ipFailureLimit {
+			return &LoginThrottledError{
+				Message:    "demasiados intentos fallidos desde esta conexión; intenta más tarde",
+				RetryAfter: ipFailureWindow,
+			}
+		}
+	}
+
+	fallos, err := s.attemptRepo.FindFailuresByEmail(email, now.Add(-lockoutDuration))
+	if err != nil {
+		return err
+	}
+
+	if len(fallos) == 0 {
+		return nil
+	}
+
+	ultimo := fallos[0].CreatedAt
+	if len(fallos) >= lockoutThreshold {
+		return &LoginThrottledError{
+			Message:    "la cuenta está bloqueada temporalmente por intentos fallidos",
+			RetryAfter: ultimo.Add(lockoutDuration).Sub(now),
+		}
+	}
+
+	if len(fallos) >= throttleAfter {
+		espera := throttleDelay(len(fallos))
+		if now.Before(ultimo.Add(espera)) {
+			return &LoginThrottledError{
+				Message:    "espera unos segundos antes de volver a intentarlo",
+				RetryAfter: ultimo.Add(espera).Sub(now),
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordLoginFailure registra el fallo y avisa al dueño de la cuenta cuando se bloquea.
+// Si el fallo no se puede registrar retorna el error y el intento se rechaza sin revelar
+// el resultado: de lo contrario el bloqueo dejaría de contar y permitiría seguir probando
+func (s *AuthService) recordLoginFailure(email, ip string, user *models.User) error {
+	if err := s.attemptRepo.RecordFailure(email, ip); err != nil {
+		return err
+	}
+
+	fallos, err := s.attemptRepo.FindFailuresByEmail(email, time.Now().Add(-lockoutDuration))
+	if err != nil {
+		fmt.Printf("Error al obtener intentos fallidos: %v\n", err)
+		return nil
+	}
+
+	if user == nil || len(fallos) != lockoutThreshold {
+		return nil
+	}
+
+	message, err := s.emailService.AccountLockedEmail(user, ip, time.Now().Add(lockoutDuration))
+	if err == nil {
+		err = s.emailService.Enqueue(message)
+	}
+	if err != nil {
+		fmt.Printf("Error al encolar aviso de bloqueo: %v\n", err)
+	}
+	return nil
+}
+
+// UnlockUser levanta el bloqueo por intentos fallidos de una cuenta
+func (s *AuthService) UnlockUser(userID int) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	return s.attemptRepo.ClearFailures(normalizeEmail(user.Email))
+}
+
+// throttleDelay espera exigida tras n fallos: 2s, 4s, 8s... hasta maxThrottleDelay
+func throttleDelay(fallos int) time.Duration {
+	espera := time.Second << uint(fallos-throttleAfter+1)
+	if espera > maxThrottleDelay {
+		return maxThrottleDelay
+	}
+	return espera
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,168 @@
+// backend/internal/services/auth_lockout_test.go
+package services
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/repositories"
+	"github.com/wenka/backend/internal/utils"
+)
+
+func TestThrottleDelay(t *testing.T) {
+	tests := []struct {
+		fallos int
+		want   time.Duration
+	}{
+		{throttleAfter, 2 * time.Second},
+		{throttleAfter + 1, 4 * time.Second},
+		{throttleAfter + 2, 8 * time.Second},
+		{throttleAfter + 3, 16 * time.Second},
+		{throttleAfter + 4, maxThrottleDelay},
+		{throttleAfter + 20, maxThrottleDelay},
+	}
+
+	for _, tt := range tests {
+		if got := throttleDelay(tt.fallos); got != tt.want {
+			t.Errorf("throttleDelay(%d) = %v, se esperaba %v", tt.fallos, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"paciente@example.com", "paciente@example.com"},
+		{"  Paciente@Example.COM ", "paciente@example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeEmail(tt.email); got != tt.want {
+			t.Errorf("normalizeEmail(%q) = %q, se esperaba %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+// Un fallo que no se puede registrar rechaza el intento sin tokens ni revelar si la
+// contraseña era correcta: de lo contrario el bloqueo dejaría de contar
+func TestRecordLoginFailureFailsClosed(t *testing.T) {
+	insertErr := errors.New("error al registrar intento fallido: conexión cerrada")
+	hashed, err := utils.HashPassword("correcta")
+	if err != nil {
+		t.Fatalf("error al hashear contraseña: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		email     string
+		recordErr error
+		wantErr   error
+		failures  int
+	}{
+		{"contraseña incorrecta con registro caído", "ana@example.com", insertErr, insertErr, 0},
+		{"email desconocido con registro caído", "nadie@example.com", insertErr, insertErr, 0},
+		{"contraseña incorrecta registrada", "ana@example.com", nil, nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, fakes := newTestAuthService(t)
+			fakes.users.users[1].Password = hashed
+			fakes.attempts.recordErr = tt.recordErr
+
+			auth, challenge, err := service.Login(&models.LoginRequest{Email: tt.email, Password: "incorrecta"}, models.DeviceInfo{IP: "203.0.113.7"})
+			if auth != nil || challenge != nil {
+				t.Fatal("se entregaron tokens con credenciales inválidas")
+			}
+			if err == nil {
+				t.Fatal("se esperaba error")
+			}
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, se esperaba %v", err, tt.wantErr)
+				}
+			} else if err.Error() != "credenciales inválidas" {
+				t.Errorf("error = %q, se esperaba credenciales inválidas", err.Error())
+			}
+
+			if got := fakes.attempts.failures[tt.email]; got != tt.failures {
+				t.Errorf("fallos registrados = %d, se esperaba %d", got, tt.failures)
+			}
+		})
+	}
+}
+
+// El email se valida antes de tocar la base de datos
+func TestRecordFailureRejectsInvalidEmail(t *testing.T) {
+	service := &AuthService{attemptRepo: repositories.NewLoginAttemptRepository(nil)}
+
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{"email vacío", ""},
+		{"email más largo que la columna", strings.Repeat("a", 250) + "@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.recordLoginFailure(tt.email, "203.0.113.7", nil); err == nil {
+				t.Fatal("se esperaba error al registrar el intento")
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := utils.ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("error al interpretar proxies: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"sin proxy", "203.0.113.7:51000", nil, "203.0.113.7"},
+		{"cliente que falsifica X-Forwarded-For", "203.0.113.7:51000", []string{"198.51.100.9"}, "203.0.113.7"},
+		{"proxy de confianza", "10.0.0.2:443", []string{"198.51.100.9"}, "198.51.100.9"},
+		{"proxy por IP exacta", "192.0.2.1:443", []string{"198.51.100.9"}, "198.51.100.9"},
+		{"cadena de proxies", "10.0.0.2:443", []string{"198.51.100.9, 10.0.0.5"}, "198.51.100.9"},
+		{"valor falso a la izquierda", "10.0.0.2:443", []string{"1.2.3.4, 198.51.100.9"}, "198.51.100.9"},
+		{"varias cabeceras", "10.0.0.2:443", []string{"1.2.3.4", "198.51.100.9"}, "198.51.100.9"},
+		{"entrada inválida", "10.0.0.2:443", []string{"no-es-ip, 10.0.0.5"}, "10.0.0.5"},
+		{"proxy sin cabecera", "10.0.0.2:443", nil, "10.0.0.2"},
+		{"IPv6", "[2001:db8::1]:51000", []string{"198.51.100.9"}, "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/auth/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := utils.ClientIP(r, trusted); got != tt.want {
+				t.Errorf("ClientIP = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsInvalid(t *testing.T) {
+	for _, value := range []string{"proxy.local", "10.0.0.0/33", ""} {
+		if _, err := utils.ParseTrustedProxies([]string{value}); err == nil {
+			t.Errorf("se esperaba error para %q", value)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// backend/internal/services/auth_mfa.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/utils"
+)
+
+const (
+	mfaTokenTTL       = 5 * time.Minute // tiempo para capturar el segundo factor tras la contraseña
+	recoveryCodeCount = 10
+	totpIssuer        = "Clínica Wenka"
+)
+
+// Errores de verificación en dos pasos
+var (
+	ErrMFACodeInvalid     = errors.New("código de verificación incorrecto")
+	ErrMFANotPending      = errors.New("primero inicia la configuración de la verificación en dos pasos")
+	ErrMFAAlreadyEnabled  = errors.New("la verificación en dos pasos ya está activa")
+	ErrMFANotEnabled      = errors.New("la verificación en dos pasos no está activa")
+	ErrMFARequiredForRole = errors.New("la verificación en dos pasos es obligatoria para cuentas del personal")
+	ErrMFASetupRequired   = errors.New("debes activar la verificación en dos pasos para continuar")
+)
+
+// requiresMFA indica si el rol puede ver datos clínicos y por lo tanto debe usar 2FA
+func requiresMFA(rol string) bool {
+	return rol == models.RolEspecialista || rol == models.RolRecepcion || rol == models.RolAdmin
+}
+
+// mfaChallenge emite el token parcial que se canjea por la sesión con el segundo factor
+func (s *AuthService) mfaChallenge(user *models.User) (*models.MFAChallenge, error) {
+	token, err := utils.GenerateMFAToken(user.ID, time.Now().Add(mfaTokenTTL), s.keys)
+	if err != nil {
+		return nil, fmt.Errorf("error al generar token de verificación: %v", err)
+	}
+
+	return &models.MFAChallenge{
+		MFARequired:   true,
+		MFAToken:      token,
+		SetupRequired: !user.TOTPActivo,
+		ExpiresIn:     int(mfaTokenTTL.Seconds()),
+	}, nil
+}
+
+// UserIDFromMFAToken obtiene el usuario del token parcial del login
+func (s *AuthService) UserIDFromMFAToken(token string) (int, error) {
+	claims, err := utils.ValidateMFAToken(token, s.keys)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// VerifyMFA completa el login con el código de la app o un código de recuperación. Los
+// códigos incorrectos cuentan como intentos fallidos para el bloqueo de la cuenta
+func (s *AuthService) VerifyMFA(req *models.MFAVerifyRequest, device models.DeviceInfo) (*models.AuthResponse, error) {
+	userID, err := s.UserIDFromMFAToken(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, utils.ErrMFATokenInvalid
+	}
+
+	if !user.TOTPActivo {
+		return nil, ErrMFASetupRequired
+	}
+
+	email := normalizeEmail(user.Email)
+	if err := s.checkLoginAllowed(email, device.IP); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkSecondFactor(user, req.Code, req.RecoveryCode); err != nil {
+		if errors.Is(err, ErrMFACodeInvalid) {
+			if recordErr := s.recordLoginFailure(email, device.IP, user); recordErr != nil {
+				return nil, recordErr
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.attemptRepo.ClearFailures(email); err != nil {
+		fmt.Printf("Error al limpiar intentos fallidos: %v\n", err)
+	}
+
+	return s.startSession(user, device)
+}
+
+// SetupMFA genera un secreto nuevo pendiente de confirmar con el primer código
+func (s *AuthService) SetupMFA(userID int) (*models.MFASetupResponse, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPActivo {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetTOTPSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.MFASetupResponse{
+		Secret:     secret,
+		OtpauthURI: utils.TOTPURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// EnableMFA activa 2FA al confirmar el primer código y entrega los códigos de recuperación.
+// Si la activación viene del login del personal (startSession), también abre la sesión
+func (s *AuthService) EnableMFA(userID int, code string, startSession bool, device models.DeviceInfo) (*models.MFAEnableResponse, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPActivo {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if user.TOTPSecreto == "" {
+		return nil, ErrMFANotPending
+	}
+
+	if err := s.checkTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(code))
+	}
+
+	if err := s.recoveryRepo.Replace(user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetTOTPActive(user.ID, true); err != nil {
+		return nil, err
+	}
+
+	response := &models.MFAEnableResponse{RecoveryCodes: codes}
+	if startSession {
+		user.TOTPActivo = true
+		response.Session, err = s.startSession(user, device)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// DisableMFA desactiva 2FA con un código vigente. El personal no puede desactivarla
+func (s *AuthService) DisableMFA(userID int, code string) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if requiresMFA(user.Rol) {
+		return ErrMFARequiredForRole
+	}
+
+	if !user.TOTPActivo {
+		return ErrMFANotEnabled
+	}
+
+	if err := s.checkTOTP(user, code); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.SetTOTPActive(user.ID, false); err != nil {
+		return err
+	}
+
+	return s.recoveryRepo.DeleteForUser(user.ID)
+}
+
+// checkSecondFactor acepta el código de la app o, si no viene, un código de recuperación
+func (s *AuthService) checkSecondFactor(user *models.User, code, recoveryCode string) error {
+	if code != "" {
+		return s.checkTOTP(user, code)
+	}
+
+	if recoveryCode == "" {
+		return ErrMFACodeInvalid
+	}
+
+	used, err := s.recoveryRepo.Use(user.ID, utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode)))
+	if err != nil {
+		return err
+	}
+
+	if !used {
+		return ErrMFACodeInvalid
+	}
+	return nil
+}
+
+// checkTOTP valida el código y registra su paso para que no pueda reutilizarse
+func (s *AuthService) checkTOTP(user *models.User, code string) error {
+	step, ok := utils.ValidateTOTP(user.TOTPSecreto, code, time.Now(), user.TOTPUltimoPaso)
+	if !ok {
+		return ErrMFACodeInvalid
+	}
+
+	fresh, err := s.userRepo.UseTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+
+	if !fresh {
+		return ErrMFACodeInvalid
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// backend/internal/services/auth_mfa_test.go
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/utils"
+)
+
+// Secreto ASCII "12345678901234567890" de los vectores de prueba de RFC 6238 (SHA1)
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	at := func(unix int64) time.Time { return time.Unix(unix, 0) }
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		// Últimos 6 dígitos de los códigos de 8 del RFC
+		{"rfc t=59", "287082", at(59), 0, 1, true},
+		{"rfc t=1111111109", "081804", at(1111111109), 0, 37037036, true},
+		{"rfc t=1111111111", "050471", at(1111111111), 0, 37037037, true},
+		{"rfc t=1234567890", "005924", at(1234567890), 0, 41152263, true},
+		{"rfc t=2000000000", "279037", at(2000000000), 0, 66666666, true},
+		{"espacios alrededor", " 005924 ", at(1234567890), 0, 41152263, true},
+		{"paso anterior por desfase de reloj", "081804", at(1111111109 + 30), 0, 37037036, true},
+		{"paso siguiente por desfase de reloj", "050471", at(1111111111 - 30), 0, 37037037, true},
+		{"fuera de la tolerancia", "081804", at(1111111109 + 90), 0, 0, false},
+		{"código ya usado", "005924", at(1234567890), 41152263, 0, false},
+		{"paso anterior al último usado", "081804", at(1111111109 + 30), 37037036, 0, false},
+		{"código incorrecto", "123456", at(1234567890), 0, 0, false},
+		{"muy corto", "05924", at(1234567890), 0, 0, false},
+		{"muy largo", "89005924", at(1234567890), 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := utils.ValidateTOTP(rfcTOTPSecret, tt.code, tt.at, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP = (%d, %v), se esperaba (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPInvalidSecret(t *testing.T) {
+	if _, ok := utils.ValidateTOTP("no es base32!", "123456", time.Now(), 0); ok {
+		t.Error("se aceptó un código con un secreto inválido")
+	}
+}
+
+func TestRequiresMFA(t *testing.T) {
+	tests := []struct {
+		rol  string
+		want bool
+	}{
+		{models.RolPaciente, false},
+		{models.RolEspecialista, true},
+		{models.RolRecepcion, true},
+		{models.RolAdmin, true},
+	}
+
+	for _, tt := range tests {
+		if got := requiresMFA(tt.rol); got != tt.want {
+			t.Errorf("requiresMFA(%q) = %v, se esperaba %v", tt.rol, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"ab12c-d34ef", "ab12c-d34ef"},
+		{" AB12C-D34EF ", "ab12c-d34ef"},
+		{"ab12cd34ef", "ab12c-d34ef"},
+		{"ab12c d34ef", "ab12c-d34ef"},
+	}
+
+	for _, tt := range tests {
+		if got := utils.NormalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, se esperaba %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+// Los códigos que no son válidos se rechazan sin consultar la base de datos
+func TestCheckSecondFactorRejectsInvalidCodes(t *testing.T) {
+	service := &AuthService{}
+	user := &models.User{ID: 1, TOTPSecreto: rfcTOTPSecret, TOTPActivo: true}
+
+	tests := []struct {
+		name         string
+		code         string
+		recoveryCode string
+	}{
+		{"sin código", "", ""},
+		{"código incompleto", "12345", ""},
+		{"código con letras", "abcdef", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.checkSecondFactor(user, tt.code, tt.recoveryCode)
+			if !errors.Is(err, ErrMFACodeInvalid) {
+				t.Fatalf("error = %v, se esperaba %v", err, ErrMFACodeInvalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// backend/internal/services/auth_profile.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/utils"
+)
+
+// Errores de autogestión de la cuenta
+var (
+	ErrPasswordIncorrect   = errors.New("la contraseña actual es incorrecta")
+	ErrEmailInUse          = errors.New("el email ya está registrado")
+	ErrStaffAccountDelete  = errors.New("las cuentas del personal no pueden eliminarse desde aquí; contacta a un administrador")
+	ErrProfileFieldInvalid = errors.New("nombre y apellido no pueden quedar vacíos")
+)
+
+// UpdateProfile actualiza nombre, apellido, teléfono o email del usuario. Cambiar el email
+// exige la contraseña actual y deja la cuenta sin verificar hasta confirmar la nueva dirección
+func (s *AuthService) UpdateProfile(userID int, req *models.UpdateProfileRequest, device models.DeviceInfo) (*models.User, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+
+	if req.Nombre != nil {
+		nombre := strings.TrimSpace(*req.Nombre)
+		if nombre == "" {
+			return nil, ErrProfileFieldInvalid
+		}
+		updates["nombre"] = nombre
+	}
+
+	if req.Apellido != nil {
+		apellido := strings.TrimSpace(*req.Apellido)
+		if apellido == "" {
+			return nil, ErrProfileFieldInvalid
+		}
+		updates["apellido"] = apellido
+	}
+
+	if req.Telefono != nil {
+		updates["telefono"] = strings.TrimSpace(*req.Telefono)
+	}
+
+	if req.Idioma != nil {
+		if !isIdioma(*req.Idioma) {
+			return nil, fmt.Errorf("idioma inválido. Opciones: %s", strings.Join(models.Idiomas, ", "))
+		}
+		updates["idioma"] = *req.Idioma
+	}
+
+	newEmail := ""
+	if req.Email != nil && normalizeEmail(*req.Email) != normalizeEmail(user.Email) {
+		newEmail = normalizeEmail(*req.Email)
+		if !strings.Contains(newEmail, "@") {
+			return nil, errors.New("email inválido")
+		}
+
+		if err := s.verifyPassword(user, req.PasswordActual, device.IP); err != nil {
+			return nil, err
+		}
+
+		existing, err := s.userRepo.FindByEmail(newEmail)
+		if err != nil {
+			return nil, fmt.Errorf("error al verificar email: %v", err)
+		}
+
+		// Un expediente con ese email pertenece a otra persona; compartirlo mezclaría historiales
+		propio := 0
+		if user.PacienteID != nil {
+			propio = *user.PacienteID
+		}
+
+		pacienteExists, err := s.userRepo.PacienteEmailExists(newEmail, propio)
+		if err != nil {
+			return nil, err
+		}
+
+		if existing != nil || pacienteExists {
+			return nil, ErrEmailInUse
+		}
+
+		updates["email"] = newEmail
+		updates["email_verificado"] = false
+	}
+
+	if len(updates) == 0 {
+		return user, nil
+	}
+
+	if err := s.userRepo.UpdateProfile(user, updates); err != nil {
+		return nil, err
+	}
+
+	user, err = s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if newEmail != "" {
+		if err := s.sendVerification(user); err != nil {
+			fmt.Printf("Error al enviar verificación al usuario %d: %v\n", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
+
+// ChangePassword cambia la contraseña tras comprobar la actual y cierra las demás sesiones
+func (s *AuthService) ChangePassword(userID int, sessionID string, req *models.ChangePasswordRequest, device models.DeviceInfo) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifyPassword(user, req.PasswordActual, device.IP); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.PasswordNuevo)
+	if err != nil {
+		return fmt.Errorf("error al hashear contraseña: %v", err)
+	}
+
+	if err := s.userRepo.UpdatePassword(userID, hashedPassword); err != nil {
+		return err
+	}
+
+	return s.sessionRepo.RevokeOthersForUser(userID, sessionID)
+}
+
+// DeleteAccount elimina la cuenta del paciente. Su expediente se anonimiza en lugar de
+// borrarse para que el historial de citas de la clínica siga completo
+func (s *AuthService) DeleteAccount(userID int, req *models.DeleteAccountRequest, device models.DeviceInfo) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Rol != models.RolPaciente {
+		return ErrStaffAccountDelete
+	}
+
+	if err := s.verifyPassword(user, req.Password, device.IP); err != nil {
+		return err
+	}
+
+	return s.userRepo.DeleteAndAnonymize(user)
+}
+
+// verifyPassword comprueba la contraseña del usuario con las mismas reglas de bloqueo del
+// inicio de sesión, para que una sesión robada no sirva para adivinarla
+func (s *AuthService) verifyPassword(user *models.User, password, ip string) error {
+	email := normalizeEmail(user.Email)
+	if err := s.checkLoginAllowed(email, ip); err != nil {
+		return err
+	}
+
+	if !utils.CheckPassword(password, user.Password) {
+		if err := s.recordLoginFailure(email, ip, user); err != nil {
+			return err
+		}
+		return ErrPasswordIncorrect
+	}
+
+	return nil
+}
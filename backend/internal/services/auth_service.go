@@ -4,27 +4,115 @@ package services
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/wenka/backend/internal/models"
 	"github.com/wenka/backend/internal/repositories"
 	"github.com/wenka/backend/internal/utils"
 )
 
+// Errores de refresh token
+var (
+	ErrRefreshTokenInvalid = errors.New("sesión inválida o expirada")
+	ErrRefreshTokenReused  = errors.New("refresh token reutilizado; se cerró la sesión por seguridad")
+	ErrResetTokenInvalid   = errors.New("el enlace para restablecer la contraseña no es válido o expiró")
+)
+
+// userStore cuentas de usuario que usa el servicio. Lo implementa UserRepository; las pruebas
+// lo reemplazan por uno en memoria
+type userStore interface {
+	Create(user *models.User) error
+	DeleteAndAnonymize(user *models.User) error
+	EspecialistaExists(especialistaID int) (bool, error)
+	FindByEmail(email string) (*models.User, error)
+	FindByEspecialistaID(especialistaID int) (*models.User, error)
+	FindByID(id int) (*models.User, error)
+	MarkEmailVerified(userID int) error
+	PacienteEmailExists(email string, exceptID int) (bool, error)
+	SetTOTPActive(userID int, active bool) error
+	SetTOTPSecret(userID int, secret string) error
+	UpdatePassword(userID int, hashedPassword string) error
+	UpdateProfile(user *models.User, updates map[string]interface{}) error
+	UpdateRole(userID int, rol string, especialistaID *int) error
+	UseTOTPStep(userID int, step int64) (bool, error)
+}
+
+// sessionStore refresh tokens de las sesiones. Lo implementa SessionRepository
+type sessionStore interface {
+	Create(sesion *models.Sesion) error
+	FindActiveByUser(usuarioID int) ([]models.Sesion, error)
+	FindByTokenHash(hash string) (*models.Sesion, error)
+	IsFamilyActive(familiaID string) (bool, error)
+	MarkUsed(id int) (bool, error)
+	RevokeAllForUser(usuarioID int) error
+	RevokeFamily(usuarioID int, familiaID string) error
+	RevokeOthersForUser(usuarioID int, familiaID string) error
+}
+
+// resetStore tokens para restablecer la contraseña. Lo implementa PasswordResetRepository
+type resetStore interface {
+	Create(reset *models.RestablecimientoPassword, emails ...*models.EmailSaliente) error
+	FindByTokenHash(hash string) (*models.RestablecimientoPassword, error)
+	MarkUsed(id int) (bool, error)
+}
+
+// attemptStore intentos fallidos de inicio de sesión. Lo implementa LoginAttemptRepository
+type attemptStore interface {
+	ClearFailures(email string) error
+	CountFailuresByIP(ip string, since time.Time) (int64, error)
+	FindFailuresByEmail(email string, since time.Time) ([]models.IntentoLogin, error)
+	RecordFailure(email, ip string) error
+}
+
 type AuthService struct {
-	userRepo  *repositories.UserRepository
-	jwtSecret string
+	userRepo         userStore
+	sessionRepo      sessionStore
+	resetRepo        resetStore
+	verificationRepo *repositories.EmailVerificationRepository
+	attemptRepo      attemptStore
+	recoveryRepo     *repositories.RecoveryCodeRepository
+	emailService     *EmailService
+	keys             *utils.KeySet
+	verifySecret     string // firma los enlaces de verificación de email
+	accessTTL        time.Duration
+	refreshTTL       time.Duration
+	resetTTL         time.Duration
+	verifyTTL        time.Duration
 }
 
 // NewAuthService crea una nueva instancia del servicio
-func NewAuthService(userRepo *repositories.UserRepository, jwtSecret string) *AuthService {
+func NewAuthService(
+	userRepo *repositories.UserRepository,
+	sessionRepo *repositories.SessionRepository,
+	resetRepo *repositories.PasswordResetRepository,
+	verificationRepo *repositories.EmailVerificationRepository,
+	attemptRepo *repositories.LoginAttemptRepository,
+	recoveryRepo *repositories.RecoveryCodeRepository,
+	emailService *EmailService,
+	keys *utils.KeySet,
+	verifySecret string,
+	accessTTL, refreshTTL, resetTTL, verifyTTL time.Duration,
+) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:         userRepo,
+		sessionRepo:      sessionRepo,
+		resetRepo:        resetRepo,
+		verificationRepo: verificationRepo,
+		attemptRepo:      attemptRepo,
+		recoveryRepo:     recoveryRepo,
+		emailService:     emailService,
+		keys:             keys,
+		verifySecret:     verifySecret,
+		accessTTL:        accessTTL,
+		refreshTTL:       refreshTTL,
+		resetTTL:         resetTTL,
+		verifyTTL:        verifyTTL,
 	}
 }
 
 // Register registra un nuevo usuario
-func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthResponse, error) {
+func (s *AuthService) Register(req *models.RegisterRequest, device models.DeviceInfo) (*models.AuthResponse, error) {
 	// Validar que el email no exista
 	existingUser, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
@@ -48,6 +136,12 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthRespons
 		Email:    req.Email,
 		Password: hashedPassword,
 		Telefono: req.Telefono,
+		Rol:      models.RolPaciente, // los roles de personal solo los asigna un administrador
+		Idioma:   models.IdiomaEs,
+	}
+
+	if isIdioma(req.Idioma) {
+		user.Idioma = req.Idioma
 	}
 
 	err = s.userRepo.Create(user)
@@ -55,47 +149,256 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthRespons
 		return nil, err
 	}
 
-	// Generar token
-	token, err := utils.GenerateToken(user.ID, user.Email, s.jwtSecret)
-	if err != nil {
-		return nil, fmt.Errorf("error al generar token: %v", err)
+	// La cuenta queda sin verificar hasta que el usuario abra el enlace del email
+	if err := s.sendVerification(user); err != nil {
+		fmt.Printf("Error al enviar verificación al usuario %d: %v\n", user.ID, err)
 	}
 
-	return &models.AuthResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	// Abrir sesión
+	return s.startSession(user, device)
 }
 
-// Login autentica a un usuario
-func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
+// Login autentica a un usuario. Si la cuenta usa 2FA, o es de personal y debe activarla,
+// en lugar de la sesión retorna un reto que se completa con el segundo factor
+func (s *AuthService) Login(req *models.LoginRequest, device models.DeviceInfo) (*models.AuthResponse, *models.MFAChallenge, error) {
+	// Rechazar antes de comparar contraseñas si la cuenta o la IP están bloqueadas
+	email := normalizeEmail(req.Email)
+	if err := s.checkLoginAllowed(email, device.IP); err != nil {
+		return nil, nil, err
+	}
+
 	// Buscar usuario por email
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
-		return nil, fmt.Errorf("error al buscar usuario: %v", err)
+		return nil, nil, fmt.Errorf("error al buscar usuario: %v", err)
+	}
+
+	// Verificar contraseña
+	if user == nil || !utils.CheckPassword(req.Password, user.Password) {
+		if err := s.recordLoginFailure(email, device.IP, user); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("credenciales inválidas")
+	}
+
+	// Los intentos fallidos se limpian hasta completar el segundo factor; si no, conocer
+	// la contraseña permitiría reiniciar el conteo entre códigos adivinados
+	if user.TOTPActivo || requiresMFA(user.Rol) {
+		challenge, err := s.mfaChallenge(user)
+		return nil, challenge, err
+	}
+
+	if err := s.attemptRepo.ClearFailures(email); err != nil {
+		fmt.Printf("Error al limpiar intentos fallidos: %v\n", err)
+	}
+
+	// Abrir sesión
+	authResponse, err := s.startSession(user, device)
+	return authResponse, nil, err
+}
+
+// Refresh rota el refresh token y emite un nuevo token de acceso. Si se presenta un refresh
+// token que ya fue rotado, alguien más lo tiene: se revoca toda la sesión
+func (s *AuthService) Refresh(refreshToken string, device models.DeviceInfo) (*models.AuthResponse, error) {
+	if refreshToken == "" {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	sesion, err := s.sessionRepo.FindByTokenHash(utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if sesion == nil || sesion.RevocadoEn != nil || time.Now().After(sesion.ExpiraEn) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if sesion.UsadoEn != nil {
+		return nil, s.revokeReusedFamily(sesion)
+	}
+
+	rotated, err := s.sessionRepo.MarkUsed(sesion.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rotated {
+		return nil, s.revokeReusedFamily(sesion)
+	}
+
+	user, err := s.GetUserByID(sesion.UsuarioID)
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	return s.issueTokens(user, sesion.FamiliaID, device)
+}
+
+// Logout cierra la sesión actual
+func (s *AuthService) Logout(userID int, sessionID string) error {
+	return s.sessionRepo.RevokeFamily(userID, sessionID)
+}
+
+// LogoutAll cierra todas las sesiones del usuario en todos sus dispositivos
+func (s *AuthService) LogoutAll(userID int) error {
+	return s.sessionRepo.RevokeAllForUser(userID)
+}
+
+// ListSessions obtiene las sesiones activas del usuario con su dispositivo
+func (s *AuthService) ListSessions(userID int) ([]models.Sesion, error) {
+	sesiones, err := s.sessionRepo.FindActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sesiones == nil {
+		return []models.Sesion{}, nil
+	}
+
+	return sesiones, nil
+}
+
+// IsSessionActive indica si la sesión de un token de acceso sigue vigente
+func (s *AuthService) IsSessionActive(sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+	return s.sessionRepo.IsFamilyActive(sessionID)
+}
+
+// ForgotPassword envía por email un enlace de un solo uso para restablecer la contraseña.
+// No revela si el email está registrado: sin cuenta simplemente no se envía nada
+func (s *AuthService) ForgotPassword(email string) error {
+	user, err := s.userRepo.FindByEmail(strings.TrimSpace(email))
+	if err != nil {
+		return err
 	}
 
 	if user == nil {
-		return nil, errors.New("credenciales inválidas")
+		return nil
 	}
 
-	// Verificar contraseña
-	if !utils.CheckPassword(req.Password, user.Password) {
-		return nil, errors.New("credenciales inválidas")
+	token, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("error al generar token de restablecimiento: %v", err)
+	}
+
+	expiraEn := time.Now().Add(s.resetTTL)
+	message, err := s.emailService.PasswordResetEmail(user, token, expiraEn)
+	if err != nil {
+		return err
+	}
+
+	return s.resetRepo.Create(&models.RestablecimientoPassword{
+		UsuarioID: user.ID,
+		TokenHash: hash,
+		ExpiraEn:  expiraEn,
+	}, message)
+}
+
+// ResetPassword fija una nueva contraseña con el token del email y cierra todas las
+// sesiones abiertas, por si la cuenta estaba comprometida
+func (s *AuthService) ResetPassword(req *models.ResetPasswordRequest) error {
+	if req.Token == "" {
+		return ErrResetTokenInvalid
+	}
+
+	reset, err := s.resetRepo.FindByTokenHash(utils.HashToken(req.Token))
+	if err != nil {
+		return err
+	}
+
+	if reset == nil || reset.UsadoEn != nil || time.Now().After(reset.ExpiraEn) {
+		return ErrResetTokenInvalid
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return fmt.Errorf("error al hashear contraseña: %v", err)
+	}
+
+	used, err := s.resetRepo.MarkUsed(reset.ID)
+	if err != nil {
+		return err
+	}
+
+	if !used {
+		return ErrResetTokenInvalid
+	}
+
+	if err := s.userRepo.UpdatePassword(reset.UsuarioID, hashedPassword); err != nil {
+		return err
+	}
+
+	// Con la contraseña nueva el dueño recupera el acceso aunque la cuenta estuviera bloqueada
+	if user, err := s.userRepo.FindByID(reset.UsuarioID); err == nil && user != nil {
+		if err := s.attemptRepo.ClearFailures(normalizeEmail(user.Email)); err != nil {
+			fmt.Printf("Error al limpiar intentos fallidos: %v\n", err)
+		}
+	}
+
+	return s.sessionRepo.RevokeAllForUser(reset.UsuarioID)
+}
+
+// startSession abre una nueva familia de refresh tokens para un inicio de sesión
+func (s *AuthService) startSession(user *models.User, device models.DeviceInfo) (*models.AuthResponse, error) {
+	familiaID, err := utils.GenerateSessionID()
+	if err != nil {
+		return nil, fmt.Errorf("error al generar sesión: %v", err)
+	}
+
+	return s.issueTokens(user, familiaID, device)
+}
+
+// issueTokens emite un token de acceso y un refresh token nuevo dentro de la sesión
+func (s *AuthService) issueTokens(user *models.User, familiaID string, device models.DeviceInfo) (*models.AuthResponse, error) {
+	refreshToken, hash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("error al generar refresh token: %v", err)
 	}
 
-	// Generar token
-	token, err := utils.GenerateToken(user.ID, user.Email, s.jwtSecret)
+	err = s.sessionRepo.Create(&models.Sesion{
+		UsuarioID:   user.ID,
+		FamiliaID:   familiaID,
+		TokenHash:   hash,
+		Dispositivo: truncate(device.UserAgent, 255),
+		IP:          truncate(device.IP, 45),
+		ExpiraEn:    time.Now().Add(s.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Email, user.Rol, familiaID, s.accessTTL, s.keys)
 	if err != nil {
 		return nil, fmt.Errorf("error al generar token: %v", err)
 	}
 
 	return &models.AuthResponse{
-		Token: token,
-		User:  *user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.accessTTL.Seconds()),
+		User:         *user,
 	}, nil
 }
 
+// revokeReusedFamily revoca la sesión completa al detectar la reutilización de un refresh token
+func (s *AuthService) revokeReusedFamily(sesion *models.Sesion) error {
+	if err := s.sessionRepo.RevokeFamily(sesion.UsuarioID, sesion.FamiliaID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Reutilización de refresh token detectada en la sesión %s del usuario %d\n", sesion.FamiliaID, sesion.UsuarioID)
+	return ErrRefreshTokenReused
+}
+
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
+
 // GetUserByID obtiene un usuario por su ID
 func (s *AuthService) GetUserByID(id int) (*models.User, error) {
 	user, err := s.userRepo.FindByID(id)
@@ -109,3 +412,57 @@ func (s *AuthService) GetUserByID(id int) (*models.User, error) {
 
 	return user, nil
 }
+
+// UpdateUserRole asigna un rol a un usuario. Las cuentas de especialista deben vincularse
+// a un registro de la tabla especialistas que no esté ligado a otra cuenta
+func (s *AuthService) UpdateUserRole(userID int, req *models.UpdateRoleRequest) (*models.User, error) {
+	if !containsString(models.Roles, req.Rol) {
+		return nil, fmt.Errorf("rol inválido. Opciones: %s", strings.Join(models.Roles, ", "))
+	}
+
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var especialistaID *int
+	if req.Rol == models.RolEspecialista {
+		if req.EspecialistaID == nil {
+			return nil, errors.New("el especialista es requerido para el rol especialista")
+		}
+
+		exists, err := s.userRepo.EspecialistaExists(*req.EspecialistaID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New("especialista no encontrado")
+		}
+
+		linked, err := s.userRepo.FindByEspecialistaID(*req.EspecialistaID)
+		if err != nil {
+			return nil, err
+		}
+		if linked != nil && linked.ID != user.ID {
+			return nil, errors.New("el especialista ya está vinculado a otra cuenta")
+		}
+
+		especialistaID = req.EspecialistaID
+	}
+
+	if err := s.userRepo.UpdateRole(user.ID, req.Rol, especialistaID); err != nil {
+		return nil, err
+	}
+
+	// Las sesiones abiertas llevan el rol anterior en sus tokens; al cambiarlo el usuario
+	// vuelve a iniciar sesión (y el personal pasa por la verificación en dos pasos)
+	if user.Rol != req.Rol {
+		if err := s.sessionRepo.RevokeAllForUser(user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	user.Rol = req.Rol
+	user.EspecialistaID = especialistaID
+	return user, nil
+}
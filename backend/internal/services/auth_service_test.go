@@ -0,0 +1,412 @@
+// backend/internal/services/auth_service_test.go
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/utils"
+)
+
+// memoryUsers userStore en memoria con las consultas que usan las pruebas
+type memoryUsers struct {
+	userStore
+	users map[int]*models.User
+}
+
+func (m *memoryUsers) FindByID(id int) (*models.User, error) {
+	user, ok := m.users[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *user
+	return &found, nil
+}
+
+func (m *memoryUsers) FindByEmail(email string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryUsers) UpdatePassword(userID int, hashedPassword string) error {
+	m.users[userID].Password = hashedPassword
+	return nil
+}
+
+// memorySessions sessionStore en memoria que reproduce las condiciones de SessionRepository
+type memorySessions struct {
+	sessionStore
+	sesiones []*models.Sesion
+}
+
+func (m *memorySessions) Create(sesion *models.Sesion) error {
+	sesion.ID = len(m.sesiones) + 1
+	stored := *sesion
+	m.sesiones = append(m.sesiones, &stored)
+	return nil
+}
+
+func (m *memorySessions) FindByTokenHash(hash string) (*models.Sesion, error) {
+	for _, sesion := range m.sesiones {
+		if sesion.TokenHash == hash {
+			found := *sesion
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memorySessions) MarkUsed(id int) (bool, error) {
+	for _, sesion := range m.sesiones {
+		if sesion.ID == id && sesion.UsadoEn == nil && sesion.RevocadoEn == nil {
+			now := time.Now()
+			sesion.UsadoEn = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memorySessions) RevokeFamily(usuarioID int, familiaID string) error {
+	for _, sesion := range m.sesiones {
+		if sesion.UsuarioID == usuarioID && sesion.FamiliaID == familiaID && sesion.RevocadoEn == nil {
+			now := time.Now()
+			sesion.RevocadoEn = &now
+		}
+	}
+	return nil
+}
+
+func (m *memorySessions) RevokeAllForUser(usuarioID int) error {
+	for _, sesion := range m.sesiones {
+		if sesion.UsuarioID == usuarioID && sesion.RevocadoEn == nil {
+			now := time.Now()
+			sesion.RevocadoEn = &now
+		}
+	}
+	return nil
+}
+
+func (m *memorySessions) IsFamilyActive(familiaID string) (bool, error) {
+	for _, sesion := range m.sesiones {
+		if sesion.FamiliaID == familiaID && sesion.RevocadoEn == nil && sesion.ExpiraEn.After(time.Now()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// memoryResets resetStore en memoria; guarda también los emails que se encolan con cada token
+type memoryResets struct {
+	resetStore
+	resets []*models.RestablecimientoPassword
+	emails []*models.EmailSaliente
+}
+
+func (m *memoryResets) Create(reset *models.RestablecimientoPassword, emails ...*models.EmailSaliente) error {
+	reset.ID = len(m.resets) + 1
+	stored := *reset
+	m.resets = append(m.resets, &stored)
+	m.emails = append(m.emails, emails...)
+	return nil
+}
+
+func (m *memoryResets) FindByTokenHash(hash string) (*models.RestablecimientoPassword, error) {
+	for _, reset := range m.resets {
+		if reset.TokenHash == hash {
+			found := *reset
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryResets) MarkUsed(id int) (bool, error) {
+	for _, reset := range m.resets {
+		if reset.ID == id && reset.UsadoEn == nil {
+			now := time.Now()
+			reset.UsadoEn = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// memoryAttempts attemptStore en memoria con los fallos de cada email. Con recordErr el
+// registro de un fallo se comporta como un INSERT que falla
+type memoryAttempts struct {
+	attemptStore
+	failures  map[string]int
+	recordErr error
+}
+
+func (m *memoryAttempts) RecordFailure(email, ip string) error {
+	if m.recordErr != nil {
+		return m.recordErr
+	}
+	m.failures[email]++
+	return nil
+}
+
+func (m *memoryAttempts) CountFailuresByIP(ip string, since time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *memoryAttempts) FindFailuresByEmail(email string, since time.Time) ([]models.IntentoLogin, error) {
+	return make([]models.IntentoLogin, m.failures[email]), nil
+}
+
+func (m *memoryAttempts) ClearFailures(email string) error {
+	delete(m.failures, email)
+	return nil
+}
+
+// authFakes repositorios en memoria del AuthService de pruebas
+type authFakes struct {
+	users    *memoryUsers
+	sessions *memorySessions
+	resets   *memoryResets
+	attempts *memoryAttempts
+}
+
+func newTestKeys(t *testing.T) *utils.KeySet {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := utils.GenerateDevKey(path); err != nil {
+		t.Fatalf("error al generar llave: %v", err)
+	}
+
+	keys, err := utils.LoadKeySet(path, nil)
+	if err != nil {
+		t.Fatalf("error al cargar llaves: %v", err)
+	}
+	return keys
+}
+
+func newTestAuthService(t *testing.T) (*AuthService, *authFakes) {
+	t.Helper()
+
+	fakes := &authFakes{
+		users: &memoryUsers{users: map[int]*models.User{
+			1: {ID: 1, Nombre: "Ana", Email: "ana@example.com", Rol: models.RolPaciente, Idioma: models.IdiomaEs},
+		}},
+		sessions: &memorySessions{},
+		resets:   &memoryResets{},
+		attempts: &memoryAttempts{failures: map[string]int{}},
+	}
+	emailService, _ := newTestEmailService(t)
+
+	return &AuthService{
+		userRepo:     fakes.users,
+		sessionRepo:  fakes.sessions,
+		resetRepo:    fakes.resets,
+		attemptRepo:  fakes.attempts,
+		emailService: emailService,
+		keys:         newTestKeys(t),
+		accessTTL:    15 * time.Minute,
+		refreshTTL:   24 * time.Hour,
+		resetTTL:     time.Hour,
+	}, fakes
+}
+
+// Un refresh token rota una sola vez; presentarlo de nuevo revoca toda su sesión sin tocar
+// las demás sesiones del usuario
+func TestRefreshRotationAndReuse(t *testing.T) {
+	service, _ := newTestAuthService(t)
+	device := models.DeviceInfo{UserAgent: "pruebas", IP: "203.0.113.7"}
+	user := &models.User{ID: 1, Email: "ana@example.com", Rol: models.RolPaciente}
+
+	first, err := service.issueTokens(user, "sesion-1", device)
+	if err != nil {
+		t.Fatalf("error al abrir sesión: %v", err)
+	}
+	if _, err := service.issueTokens(user, "sesion-2", device); err != nil {
+		t.Fatalf("error al abrir la otra sesión: %v", err)
+	}
+
+	rotated, err := service.Refresh(first.RefreshToken, device)
+	if err != nil {
+		t.Fatalf("error al rotar: %v", err)
+	}
+	if rotated.RefreshToken == first.RefreshToken {
+		t.Fatal("la rotación retornó el mismo refresh token")
+	}
+
+	steps := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"el token rotado se reutiliza", first.RefreshToken, ErrRefreshTokenReused},
+		{"el token nuevo quedó revocado con su sesión", rotated.RefreshToken, ErrRefreshTokenInvalid},
+	}
+
+	for _, step := range steps {
+		if _, err := service.Refresh(step.token, device); !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: error = %v, se esperaba %v", step.name, err, step.wantErr)
+		}
+	}
+
+	sessions := []struct {
+		familiaID string
+		want      bool
+	}{
+		{"sesion-1", false},
+		{"sesion-2", true},
+	}
+
+	for _, tt := range sessions {
+		active, err := service.IsSessionActive(tt.familiaID)
+		if err != nil {
+			t.Fatalf("error al verificar %s: %v", tt.familiaID, err)
+		}
+		if active != tt.want {
+			t.Errorf("IsSessionActive(%s) = %v, se esperaba %v", tt.familiaID, active, tt.want)
+		}
+	}
+}
+
+func TestRefreshRejectsInactiveSessions(t *testing.T) {
+	service, fakes := newTestAuthService(t)
+	device := models.DeviceInfo{UserAgent: "pruebas", IP: "203.0.113.7"}
+	revocadoEn := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name      string
+		familiaID string
+		sesion    *models.Sesion // nil: el token no existe
+		active    bool
+		wantErr   error
+	}{
+		{"vigente", "vigente", &models.Sesion{ExpiraEn: time.Now().Add(time.Hour)}, true, nil},
+		{"expirada", "expirada", &models.Sesion{ExpiraEn: time.Now().Add(-time.Minute)}, false, ErrRefreshTokenInvalid},
+		{"revocada", "revocada", &models.Sesion{ExpiraEn: time.Now().Add(time.Hour), RevocadoEn: &revocadoEn}, false, ErrRefreshTokenInvalid},
+		{"desconocida", "desconocida", nil, false, ErrRefreshTokenInvalid},
+		{"sin sesión", "", nil, false, ErrRefreshTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := "token-" + tt.name
+			if tt.sesion != nil {
+				tt.sesion.UsuarioID = 1
+				tt.sesion.FamiliaID = tt.familiaID
+				tt.sesion.TokenHash = utils.HashToken(token)
+				if err := fakes.sessions.Create(tt.sesion); err != nil {
+					t.Fatalf("error al guardar sesión: %v", err)
+				}
+			}
+
+			active, err := service.IsSessionActive(tt.familiaID)
+			if err != nil {
+				t.Fatalf("error al verificar sesión: %v", err)
+			}
+			if active != tt.active {
+				t.Errorf("IsSessionActive = %v, se esperaba %v", active, tt.active)
+			}
+
+			if _, err := service.Refresh(token, device); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Refresh: error = %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// ForgotPassword responde igual exista o no la cuenta; solo a una cuenta registrada se le
+// guarda un token con su email
+func TestForgotPasswordSameResponseForUnknownEmail(t *testing.T) {
+	tests := []struct {
+		name   string
+		email  string
+		resets int
+	}{
+		{"email registrado", "ana@example.com", 1},
+		{"email registrado con espacios", "  ana@example.com ", 1},
+		{"email no registrado", "nadie@example.com", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, fakes := newTestAuthService(t)
+
+			if err := service.ForgotPassword(tt.email); err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if len(fakes.resets.resets) != tt.resets || len(fakes.resets.emails) != tt.resets {
+				t.Errorf("tokens = %d y emails = %d, se esperaban %d", len(fakes.resets.resets), len(fakes.resets.emails), tt.resets)
+			}
+		})
+	}
+}
+
+var resetTokenPattern = regexp.MustCompile(`reset-password\?token=([0-9a-f]{64})`)
+
+// El token del email sirve una sola vez y antes de expirar; al usarlo se cambia la contraseña,
+// se desbloquea la cuenta y se cierran todas las sesiones
+func TestResetPassword(t *testing.T) {
+	service, fakes := newTestAuthService(t)
+
+	if err := service.ForgotPassword("ana@example.com"); err != nil {
+		t.Fatalf("error al solicitar el enlace: %v", err)
+	}
+	if len(fakes.resets.emails) != 1 {
+		t.Fatalf("emails encolados = %d, se esperaba 1", len(fakes.resets.emails))
+	}
+	match := resetTokenPattern.FindStringSubmatch(fakes.resets.emails[0].CuerpoTexto)
+	if match == nil {
+		t.Fatal("el email no contiene el enlace para restablecer la contraseña")
+	}
+	token := match[1]
+
+	expired, expiredHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		t.Fatalf("error al generar token: %v", err)
+	}
+	fakes.resets.Create(&models.RestablecimientoPassword{UsuarioID: 1, TokenHash: expiredHash, ExpiraEn: time.Now().Add(-time.Minute)})
+
+	for _, familiaID := range []string{"sesion-1", "sesion-2"} {
+		fakes.sessions.Create(&models.Sesion{UsuarioID: 1, FamiliaID: familiaID, TokenHash: familiaID, ExpiraEn: time.Now().Add(time.Hour)})
+	}
+	fakes.attempts.failures["ana@example.com"] = lockoutThreshold
+
+	steps := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"token vacío", "", ErrResetTokenInvalid},
+		{"token desconocido", "desconocido", ErrResetTokenInvalid},
+		{"token expirado", expired, ErrResetTokenInvalid},
+		{"token del email", token, nil},
+		{"token ya usado", token, ErrResetTokenInvalid},
+	}
+
+	for _, step := range steps {
+		err := service.ResetPassword(&models.ResetPasswordRequest{Token: step.token, Password: "nueva-" + step.name})
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: error = %v, se esperaba %v", step.name, err, step.wantErr)
+		}
+	}
+
+	if !utils.CheckPassword("nueva-token del email", fakes.users.users[1].Password) {
+		t.Error("la contraseña no es la del único restablecimiento válido")
+	}
+	if _, locked := fakes.attempts.failures["ana@example.com"]; locked {
+		t.Error("no se limpiaron los intentos fallidos")
+	}
+	for _, familiaID := range []string{"sesion-1", "sesion-2"} {
+		if active, _ := service.IsSessionActive(familiaID); active {
+			t.Errorf("la sesión %s sigue activa tras restablecer la contraseña", familiaID)
+		}
+	}
+}
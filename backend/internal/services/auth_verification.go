@@ -0,0 +1,98 @@
+// backend/internal/services/auth_verification.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/utils"
+)
+
+// Límites de reenvío del email de verificación
+const (
+	verificationCooldown   = time.Minute
+	maxVerificationsPerDay = 5
+	verificationRateWindow = 24 * time.Hour
+)
+
+// Errores de verificación de email
+var (
+	ErrEmailAlreadyVerified    = errors.New("el email ya está verificado")
+	ErrVerificationRateLimited = errors.New("se enviaron demasiados emails de verificación; intenta más tarde")
+)
+
+// VerifyEmail marca como verificado el email del usuario del enlace. Verificar dos
+// veces no es un error
+func (s *AuthService) VerifyEmail(token string) error {
+	claims, err := utils.ValidateEmailVerificationToken(token, s.verifySecret)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil || user.Email != claims.Email {
+		return utils.ErrLinkTokenInvalid
+	}
+
+	if user.EmailVerificado {
+		return nil
+	}
+
+	return s.userRepo.MarkEmailVerified(user.ID)
+}
+
+// ResendVerification vuelve a enviar el email de verificación respetando un minuto entre
+// envíos y un máximo de envíos por día
+func (s *AuthService) ResendVerification(userID int) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerificado {
+		return ErrEmailAlreadyVerified
+	}
+
+	envios, err := s.verificationRepo.FindSendsSince(user.ID, time.Now().Add(-verificationRateWindow))
+	if err != nil {
+		return err
+	}
+
+	if len(envios) >= maxVerificationsPerDay ||
+		(len(envios) > 0 && time.Since(envios[0].CreatedAt) < verificationCooldown) {
+		return ErrVerificationRateLimited
+	}
+
+	return s.sendVerification(user)
+}
+
+// IsEmailVerified indica si el usuario ya verificó su email
+func (s *AuthService) IsEmailVerified(userID int) (bool, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.EmailVerificado, nil
+}
+
+// sendVerification registra el envío y encola el email con el enlace de verificación
+func (s *AuthService) sendVerification(user *models.User) error {
+	expiraEn := time.Now().Add(s.verifyTTL)
+	token, err := utils.GenerateEmailVerificationToken(user.ID, user.Email, expiraEn, s.verifySecret)
+	if err != nil {
+		return fmt.Errorf("error al generar enlace de verificación: %v", err)
+	}
+
+	message, err := s.emailService.VerificationEmail(user, token, expiraEn)
+	if err != nil {
+		return err
+	}
+
+	return s.verificationRepo.RecordSend(user.ID, message)
+}
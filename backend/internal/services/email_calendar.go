@@ -0,0 +1,162 @@
+// backend/internal/services/email_calendar.go
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// Métodos iTIP (RFC 5546) de las invitaciones de calendario
+const (
+	calendarRequest = "REQUEST" // crea o actualiza el evento
+	calendarCancel  = "CANCEL"  // elimina el evento
+)
+
+const (
+	calendarFileName    = "cita.ics"
+	calendarDefaultMins = 30
+	calendarLineOctets  = 75 // longitud máxima de línea de RFC 5545 sin contar CRLF
+)
+
+// calendarSummaries título del evento en cada idioma
+var calendarSummaries = map[string]string{
+	models.IdiomaEs: "Cita: %s - Clínica Wenka",
+	models.IdiomaEn: "Appointment: %s - Clínica Wenka",
+}
+
+// calendarDescriptions descripción del evento en cada idioma
+var calendarDescriptions = map[string]string{
+	models.IdiomaEs: "Especialista: %s\nPaciente: %s",
+	models.IdiomaEn: "Specialist: %s\nPatient: %s",
+}
+
+// attachInvite adjunta al email la invitación de calendario de la cita. Sin email no hace nada
+func (s *EmailService) attachInvite(email *models.EmailSaliente, appointment *models.AppointmentWithDetails, lang, method string) *models.EmailSaliente {
+	if email == nil {
+		return nil
+	}
+
+	email.Adjuntos = append(email.Adjuntos, models.AdjuntoEmail{
+		Nombre:        calendarFileName,
+		TipoContenido: fmt.Sprintf("text/calendar; charset=UTF-8; method=%s", method),
+		Contenido:     s.appointmentInvite(appointment, email.Destinatario, lang, method, time.Now()),
+	})
+	return email
+}
+
+// appointmentInvite genera el .ics (RFC 5545) de una cita. El UID es el mismo para todos los
+// emails de la cita y SEQUENCE crece con cada envío, así el cliente de calendario actualiza o
+// elimina el evento existente en lugar de duplicarlo
+func (s *EmailService) appointmentInvite(appointment *models.AppointmentWithDetails, attendee, lang, method string, now time.Time) []byte {
+	if !isIdioma(lang) {
+		lang = models.IdiomaEs
+	}
+
+	duracion := appointment.DuracionMinutos
+	if duracion <= 0 {
+		duracion = calendarDefaultMins
+	}
+
+	start := appointment.FechaHora
+	end := start.Add(time.Duration(duracion) * time.Minute)
+
+	status := "CONFIRMED"
+	if method == calendarCancel {
+		status = "CANCELLED"
+	}
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Clinica Wenka//Citas//ES",
+		"CALSCALE:GREGORIAN",
+		"METHOD:" + method,
+		"BEGIN:VEVENT",
+		"UID:" + s.appointmentUID(appointment.ID),
+		// Los segundos Unix crecen en cada envío y caben en el entero de 32 bits que
+		// esperan los clientes de calendario
+		fmt.Sprintf("SEQUENCE:%d", now.Unix()),
+		"DTSTAMP:" + icsTime(now),
+		"DTSTART:" + icsTime(start),
+		"DTEND:" + icsTime(end),
+		"SUMMARY:" + icsText(fmt.Sprintf(calendarSummaries[lang], appointment.Tratamiento)),
+		"DESCRIPTION:" + icsText(fmt.Sprintf(calendarDescriptions[lang], appointment.NombreEspecialista, appointment.NombrePaciente)),
+		"STATUS:" + status,
+		"ORGANIZER;CN=" + icsParam("Clínica Wenka") + ":mailto:" + s.fromEmail,
+		"ATTENDEE;CN=" + icsParam(attendeeName(appointment, attendee)) + ";ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED;RSVP=FALSE:mailto:" + attendee,
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+// appointmentUID identificador estable del evento de una cita
+func (s *EmailService) appointmentUID(citaID int) string {
+	domain := "clinicawenka.com"
+	if at := strings.LastIndex(s.fromEmail, "@"); at >= 0 && at < len(s.fromEmail)-1 {
+		domain = s.fromEmail[at+1:]
+	}
+	return fmt.Sprintf("cita-%d@%s", citaID, domain)
+}
+
+// attendeeName nombre del participante según a quién va dirigido el email
+func attendeeName(appointment *models.AppointmentWithDetails, attendee string) string {
+	if attendee == appointment.EmailEspecialista {
+		return appointment.NombreEspecialista
+	}
+	return appointment.NombrePaciente
+}
+
+// icsTime fecha y hora en UTC con el formato de RFC 5545
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsText escapa un valor de tipo TEXT
+func icsText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+// icsParam entrecomilla el valor de un parámetro; las comillas no están permitidas dentro
+func icsParam(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "'") + `"`
+}
+
+// foldICSLine parte las líneas de más de 75 octetos sin cortar caracteres UTF-8; cada
+// continuación empieza con un espacio
+func foldICSLine(line string) string {
+	if len(line) <= calendarLineOctets {
+		return line
+	}
+
+	var b strings.Builder
+	limit := calendarLineOctets
+	count := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if count+size > limit {
+			b.WriteString("\r\n ")
+			count = 0
+			limit = calendarLineOctets - 1 // el espacio inicial cuenta
+		}
+		b.WriteRune(r)
+		count += size
+	}
+	return b.String()
+}
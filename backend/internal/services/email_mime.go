@@ -0,0 +1,141 @@
+// backend/internal/services/email_mime.go
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// base64LineLength longitud de línea del contenido en base64 (RFC 2045)
+const base64LineLength = 76
+
+// buildMIMEMessage arma el mensaje MIME de un email. El cuerpo es multipart/alternative con la
+// versión en texto plano y la HTML (los clientes muestran la última que saben interpretar);
+// si el email tiene adjuntos ese cuerpo va dentro de un multipart/mixed junto con ellos
+func buildMIMEMessage(from string, email *models.EmailSaliente) ([]byte, error) {
+	var buf bytes.Buffer
+
+	headers := []string{
+		"From: " + from,
+		"To: " + email.Destinatario,
+		"Subject: " + mime.BEncoding.Encode("UTF-8", email.Asunto),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+	}
+
+	if len(email.Adjuntos) == 0 {
+		body := multipart.NewWriter(&buf)
+		headers = append(headers, "Content-Type: multipart/alternative; boundary="+body.Boundary())
+		buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+		if err := writeAlternative(body, email); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	headers = append(headers, "Content-Type: multipart/mixed; boundary="+mixed.Boundary())
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	// El writer interno se crea antes que la parte para conocer su boundary
+	var alternative bytes.Buffer
+	body := multipart.NewWriter(&alternative)
+	if err := writeAlternative(body, email); err != nil {
+		return nil, err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "multipart/alternative; boundary="+body.Boundary())
+	part, err := mixed.CreatePart(header)
+	if err != nil {
+		return nil, fmt.Errorf("error al armar email: %v", err)
+	}
+	if _, err := part.Write(alternative.Bytes()); err != nil {
+		return nil, fmt.Errorf("error al armar email: %v", err)
+	}
+
+	for i := range email.Adjuntos {
+		if err := writeAttachment(mixed, &email.Adjuntos[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, fmt.Errorf("error al armar email: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeAlternative escribe las versiones en texto plano y HTML del email y cierra el writer
+func writeAlternative(writer *multipart.Writer, email *models.EmailSaliente) error {
+	// Los emails encolados antes de las plantillas no tienen versión en texto
+	if email.CuerpoTexto != "" {
+		if err := writeTextPart(writer, "text/plain", email.CuerpoTexto); err != nil {
+			return err
+		}
+	}
+
+	if err := writeTextPart(writer, "text/html", email.Cuerpo); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("error al armar email: %v", err)
+	}
+	return nil
+}
+
+// writeTextPart agrega una parte de texto en quoted-printable, que respeta el límite de
+// longitud de línea de SMTP aunque el HTML tenga líneas largas
+func writeTextPart(writer *multipart.Writer, contentType, content string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=UTF-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("error al armar email: %v", err)
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return fmt.Errorf("error al armar email: %v", err)
+	}
+	return qp.Close()
+}
+
+// writeAttachment agrega un adjunto codificado en base64
+func writeAttachment(writer *multipart.Writer, adjunto *models.AdjuntoEmail) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", fmt.Sprintf("%s; name=%q", adjunto.TipoContenido, adjunto.Nombre))
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", adjunto.Nombre))
+	header.Set("Content-Transfer-Encoding", "base64")
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("error al armar email: %v", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(adjunto.Contenido)
+	for len(encoded) > base64LineLength {
+		if _, err := part.Write([]byte(encoded[:base64LineLength] + "\r\n")); err != nil {
+			return fmt.Errorf("error al armar email: %v", err)
+		}
+		encoded = encoded[base64LineLength:]
+	}
+	if _, err := part.Write([]byte(encoded + "\r\n")); err != nil {
+		return fmt.Errorf("error al armar email: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// backend/internal/services/email_notices.go
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// ProposalLink horario propuesto por el especialista con el token para aceptarlo
+type ProposalLink struct {
+	PropuestaID int
+	FechaHora   time.Time
+	Token       string
+}
+
+// AppointmentProposalsEmail arma el email con los horarios alternativos propuestos por el especialista
+func (s *EmailService) AppointmentProposalsEmail(appointment *models.AppointmentWithDetails, motivo string, proposals []ProposalLink) (*models.EmailSaliente, error) {
+	views := make([]proposalView, 0, len(proposals))
+	for _, proposal := range proposals {
+		views = append(views, proposalView{
+			FechaHora: proposal.FechaHora,
+			AcceptURL: fmt.Sprintf("%s/confirm-appointment/respond/%d?proposal=%d&token=%s",
+				s.frontendURL, appointment.ID, proposal.PropuestaID, url.QueryEscape(proposal.Token)),
+		})
+	}
+
+	return s.render(appointment.EmailPaciente, appointment.IdiomaPaciente, tplAppointmentProposals, &emailData{
+		Appointment: appointment,
+		Motivo:      strings.TrimSpace(motivo),
+		Proposals:   views,
+	})
+}
+
+// AppointmentDeclinedEmail arma el aviso al paciente de que el especialista no puede atender la
+// cita. La invitación adjunta la quita de su calendario si ya la tenía
+func (s *EmailService) AppointmentDeclinedEmail(appointment *models.AppointmentWithDetails, motivo string) (*models.EmailSaliente, error) {
+	email, err := s.render(appointment.EmailPaciente, appointment.IdiomaPaciente, tplAppointmentDeclined, &emailData{
+		Appointment: appointment,
+		Motivo:      strings.TrimSpace(motivo),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.attachInvite(email, appointment, appointment.IdiomaPaciente, calendarCancel), nil
+}
+
+// AppointmentCancelledEmail arma el aviso al paciente de que su cita fue cancelada, con la
+// invitación que la quita de su calendario
+func (s *EmailService) AppointmentCancelledEmail(appointment *models.AppointmentWithDetails) (*models.EmailSaliente, error) {
+	email, err := s.render(appointment.EmailPaciente, appointment.IdiomaPaciente, tplAppointmentCancelled, &emailData{
+		Appointment: appointment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.attachInvite(email, appointment, appointment.IdiomaPaciente, calendarCancel), nil
+}
+
+// PasswordResetEmail arma el email con el enlace para restablecer la contraseña
+func (s *EmailService) PasswordResetEmail(user *models.User, token string, expiraEn time.Time) (*models.EmailSaliente, error) {
+	return s.render(user.Email, user.Idioma, tplPasswordReset, &emailData{
+		User:      user,
+		ActionURL: fmt.Sprintf("%s/reset-password?token=%s", s.frontendURL, url.QueryEscape(token)),
+		ExpiraEn:  expiraEn,
+	})
+}
+
+// VerificationEmail arma el email con el enlace para verificar el email de una cuenta nueva
+func (s *EmailService) VerificationEmail(user *models.User, token string, expiraEn time.Time) (*models.EmailSaliente, error) {
+	return s.render(user.Email, user.Idioma, tplEmailVerification, &emailData{
+		User:      user,
+		ActionURL: fmt.Sprintf("%s/verify-email?token=%s", s.frontendURL, url.QueryEscape(token)),
+		ExpiraEn:  expiraEn,
+	})
+}
+
+// AccountLockedEmail arma el aviso al dueño de la cuenta de que se bloqueó por intentos fallidos
+func (s *EmailService) AccountLockedEmail(user *models.User, ip string, hasta time.Time) (*models.EmailSaliente, error) {
+	return s.render(user.Email, user.Idioma, tplAccountLocked, &emailData{
+		User:     user,
+		IP:       ip,
+		ExpiraEn: hasta,
+	})
+}
+
+// AppointmentReminderEmail arma el recordatorio de una cita próxima
+func (s *EmailService) AppointmentReminderEmail(appointment *models.AppointmentWithDetails, anticipacion time.Duration) (*models.EmailSaliente, error) {
+	return s.render(appointment.EmailPaciente, appointment.IdiomaPaciente, tplAppointmentReminder, &emailData{
+		Appointment:  appointment,
+		Anticipacion: anticipacion,
+	})
+}
@@ -0,0 +1,179 @@
+// backend/internal/services/email_outbox.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/repositories"
+)
+
+// Parámetros de la entrega de la bandeja de salida
+const (
+	outboxBatchSize   = 50              // emails entregados como máximo por ronda
+	outboxLease       = 2 * time.Minute // tiempo que una réplica reserva el email que reclamó; cubre de sobra smtpTimeout
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = time.Hour
+	outboxListLimit   = 100
+)
+
+// ErrEmailNotFailed solo los emails fallidos pueden reenviarse
+var ErrEmailNotFailed = errors.New("solo se pueden reenviar emails fallidos")
+
+// outboxStore emails de la bandeja de salida. Lo implementa OutboxRepository; las pruebas lo
+// reemplazan por uno en memoria
+type outboxStore interface {
+	ClaimNext(now time.Time, lease time.Duration) (*models.EmailSaliente, error)
+	MarkSent(id int, claimedUntil time.Time) (bool, error)
+	RecordFailure(id int, claimedUntil time.Time, cause string, retryAt time.Time, deadLetter bool) (bool, error)
+	FindByID(id int) (*models.EmailSaliente, error)
+	FindByEstado(estado string, limit int) ([]models.EmailSaliente, error)
+	Requeue(id int) (bool, error)
+}
+
+// EmailOutbox entrega los emails de la bandeja de salida. Un envío fallido se reintenta con
+// espera exponencial; al agotar maxAttempts el email queda fallido hasta que un administrador
+// lo reenvíe
+type EmailOutbox struct {
+	outboxRepo   outboxStore
+	emailService *EmailService
+	interval     time.Duration
+	maxAttempts  int
+}
+
+// NewEmailOutbox crea el proceso de entrega de la bandeja de salida
+func NewEmailOutbox(outboxRepo *repositories.OutboxRepository, emailService *EmailService, interval time.Duration, maxAttempts int) *EmailOutbox {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &EmailOutbox{
+		outboxRepo:   outboxRepo,
+		emailService: emailService,
+		interval:     interval,
+		maxAttempts:  maxAttempts,
+	}
+}
+
+// Start entrega los emails pendientes cada intervalo hasta que se cancele el contexto
+func (o *EmailOutbox) Start(ctx context.Context) {
+	if o.interval <= 0 {
+		fmt.Println("Entrega de emails desactivada")
+		return
+	}
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		o.RunOnce(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce entrega los emails cuyo próximo intento llegó hasta el momento indicado. Reclama
+// uno a la vez: un lote reclamado de una sola vez podría vencer a mitad del envío y otra
+// réplica entregaría de nuevo los que aún no se marcaron
+func (o *EmailOutbox) RunOnce(now time.Time) {
+	for i := 0; i < outboxBatchSize; i++ {
+		email, err := o.outboxRepo.ClaimNext(now, outboxLease)
+		if err != nil {
+			fmt.Printf("Error al obtener emails pendientes: %v\n", err)
+			return
+		}
+
+		if email == nil {
+			return
+		}
+
+		if err := o.deliver(email); err != nil {
+			fmt.Printf("Error en la bandeja de salida: %v\n", err)
+		}
+	}
+}
+
+// deliver entrega un email y registra el resultado si el reclamo sigue vigente
+func (o *EmailOutbox) deliver(email *models.EmailSaliente) error {
+	claimedUntil := *email.ReclamadoHasta
+
+	var recorded bool
+	var err error
+	if sendErr := o.emailService.Deliver(email); sendErr == nil {
+		recorded, err = o.outboxRepo.MarkSent(email.ID, claimedUntil)
+	} else {
+		intentos := email.Intentos + 1
+		deadLetter := intentos >= o.maxAttempts
+		if deadLetter {
+			fmt.Printf("El email %d a %s agotó sus %d intentos: %v\n", email.ID, email.Destinatario, intentos, sendErr)
+		}
+
+		recorded, err = o.outboxRepo.RecordFailure(email.ID, claimedUntil, sendErr.Error(), time.Now().Add(outboxBackoff(intentos)), deadLetter)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !recorded {
+		fmt.Printf("El reclamo del email %d venció antes de registrar el resultado; otra réplica lo retomó\n", email.ID)
+	}
+	return nil
+}
+
+// outboxBackoff espera antes del siguiente intento: se duplica con cada fallo hasta outboxMaxBackoff
+func outboxBackoff(intentos int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < intentos && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
+
+// ListEmails lista los emails de la bandeja de salida en un estado; por omisión los fallidos
+func (o *EmailOutbox) ListEmails(estado string) ([]models.EmailSaliente, error) {
+	if estado == "" {
+		estado = models.EmailFallido
+	}
+
+	switch estado {
+	case models.EmailPendiente, models.EmailEnviado, models.EmailFallido:
+	default:
+		return nil, fmt.Errorf("estado inválido. Opciones: %s, %s, %s", models.EmailPendiente, models.EmailEnviado, models.EmailFallido)
+	}
+
+	return o.outboxRepo.FindByEstado(estado, outboxListLimit)
+}
+
+// ResendEmail devuelve a la cola un email fallido con los intentos reiniciados
+func (o *EmailOutbox) ResendEmail(id int) (*models.EmailSaliente, error) {
+	email, err := o.outboxRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if email == nil {
+		return nil, fmt.Errorf("email no encontrado")
+	}
+
+	requeued, err := o.outboxRepo.Requeue(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !requeued {
+		return nil, ErrEmailNotFailed
+	}
+
+	return o.outboxRepo.FindByID(id)
+}
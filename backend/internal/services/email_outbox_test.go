@@ -0,0 +1,160 @@
+// backend/internal/services/email_outbox_test.go
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// outboxFailure fallo registrado por la bandeja de salida
+type outboxFailure struct {
+	cause      string
+	retryAt    time.Time
+	deadLetter bool
+}
+
+// memoryOutbox outboxStore en memoria con las operaciones de la entrega
+type memoryOutbox struct {
+	outboxStore
+	emails   []*models.EmailSaliente
+	failures []outboxFailure
+}
+
+func (m *memoryOutbox) ClaimNext(now time.Time, lease time.Duration) (*models.EmailSaliente, error) {
+	for _, email := range m.emails {
+		claimed := email.ReclamadoHasta != nil && email.ReclamadoHasta.After(now)
+		if email.Estado == models.EmailPendiente && !email.ProximoIntento.After(now) && !claimed {
+			until := now.Add(lease)
+			email.ReclamadoHasta = &until
+			found := *email
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryOutbox) MarkSent(id int, claimedUntil time.Time) (bool, error) {
+	for _, email := range m.emails {
+		if email.ID == id && email.ReclamadoHasta != nil && email.ReclamadoHasta.Equal(claimedUntil) {
+			now := time.Now()
+			email.Estado = models.EmailEnviado
+			email.EnviadoEn = &now
+			email.ReclamadoHasta = nil
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryOutbox) RecordFailure(id int, claimedUntil time.Time, cause string, retryAt time.Time, deadLetter bool) (bool, error) {
+	for _, email := range m.emails {
+		if email.ID == id && email.ReclamadoHasta != nil && email.ReclamadoHasta.Equal(claimedUntil) {
+			m.failures = append(m.failures, outboxFailure{cause, retryAt, deadLetter})
+			email.Intentos++
+			email.UltimoError = &cause
+			email.ProximoIntento = retryAt
+			email.ReclamadoHasta = nil
+			if deadLetter {
+				email.Estado = models.EmailFallido
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		intentos int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, outboxMaxBackoff},
+		{30, outboxMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.intentos); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %v, se esperaba %v", tt.intentos, got, tt.want)
+		}
+	}
+}
+
+// Un envío fallido se reintenta con espera exponencial hasta agotar maxAttempts; entonces el
+// email queda fallido y no se reclama de nuevo
+func TestEmailOutboxDeadLetter(t *testing.T) {
+	const maxAttempts = 3
+	smtpErr := errors.New("smtp: servidor no disponible")
+
+	tests := []struct {
+		name       string
+		intentos   int // intentos fallidos antes de esta ronda
+		sendErr    error
+		estado     string
+		deadLetter bool
+		backoff    time.Duration
+	}{
+		{"primer fallo", 0, smtpErr, models.EmailPendiente, false, 30 * time.Second},
+		{"segundo fallo", 1, smtpErr, models.EmailPendiente, false, time.Minute},
+		{"agota los intentos", maxAttempts - 1, smtpErr, models.EmailFallido, true, 2 * time.Minute},
+		{"entrega tras un fallo", 1, nil, models.EmailEnviado, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emailService, mailer := newTestEmailService(t)
+			mailer.FailWith(tt.sendErr)
+
+			user := &models.User{ID: 1, Nombre: "Ana", Email: "ana@example.com", Idioma: models.IdiomaEs}
+			email, err := emailService.PasswordResetEmail(user, "token", time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatalf("error al armar email: %v", err)
+			}
+			email.ID = 1
+			email.Estado = models.EmailPendiente
+			email.Intentos = tt.intentos
+
+			store := &memoryOutbox{emails: []*models.EmailSaliente{email}}
+			outbox := NewEmailOutbox(nil, emailService, time.Minute, maxAttempts)
+			outbox.outboxRepo = store
+
+			start := time.Now()
+			outbox.RunOnce(start)
+
+			if email.Estado != tt.estado {
+				t.Errorf("estado = %s, se esperaba %s", email.Estado, tt.estado)
+			}
+			if tt.sendErr == nil {
+				if len(store.failures) != 0 || len(mailer.MessagesTo("ana@example.com")) != 1 {
+					t.Errorf("fallos = %d y mensajes = %d, se esperaba una entrega sin fallos", len(store.failures), len(mailer.Messages()))
+				}
+				return
+			}
+
+			// Una sola ronda: el email fallido espera su siguiente intento
+			if len(store.failures) != 1 {
+				t.Fatalf("fallos registrados = %d, se esperaba 1", len(store.failures))
+			}
+			failure := store.failures[0]
+			if failure.deadLetter != tt.deadLetter {
+				t.Errorf("deadLetter = %v, se esperaba %v", failure.deadLetter, tt.deadLetter)
+			}
+			if failure.cause != smtpErr.Error() {
+				t.Errorf("causa = %q, se esperaba %q", failure.cause, smtpErr.Error())
+			}
+			if wait := failure.retryAt.Sub(start); wait < tt.backoff || wait > tt.backoff+time.Second {
+				t.Errorf("siguiente intento en %v, se esperaba %v", wait, tt.backoff)
+			}
+			if email.Intentos != tt.intentos+1 {
+				t.Errorf("intentos = %d, se esperaba %d", email.Intentos, tt.intentos+1)
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+// backend/internal/services/email_service_test.go
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// mimePart parte de un mensaje ya decodificada; parts tiene las partes de un multipart
+type mimePart struct {
+	mediaType   string
+	params      map[string]string
+	disposition string
+	body        []byte
+	parts       []mimePart
+}
+
+// deliveredEmail mensaje entregado al MemoryMailer ya interpretado
+type deliveredEmail struct {
+	to      []string
+	subject string
+	root    mimePart
+}
+
+func newTestEmailService(t *testing.T) (*EmailService, *MemoryMailer) {
+	t.Helper()
+	t.Setenv("FROM_EMAIL", "citas@clinicawenka.com")
+	t.Setenv("FRONTEND_URL", "http://localhost:3000")
+
+	mailer := NewMemoryMailer()
+	return NewEmailService(nil, mailer), mailer
+}
+
+func testAppointment(idioma string) *models.AppointmentWithDetails {
+	return &models.AppointmentWithDetails{
+		ID:                 42,
+		EspecialistaID:     3,
+		NombrePaciente:     "Ana López",
+		EmailPaciente:      "ana@example.com",
+		NombreEspecialista: "Dr. Ruiz",
+		EmailEspecialista:  "ruiz@clinicawenka.com",
+		Tratamiento:        "Limpieza dental",
+		FechaHora:          time.Date(2030, 3, 14, 10, 0, 0, 0, time.UTC),
+		DuracionMinutos:    45,
+		Estado:             models.EstadoConfirmada,
+		IdiomaPaciente:     idioma,
+		IdiomaEspecialista: idioma,
+	}
+}
+
+// deliver entrega el email con el mailer en memoria y retorna el mensaje interpretado
+func deliver(t *testing.T, service *EmailService, mailer *MemoryMailer, email *models.EmailSaliente) deliveredEmail {
+	t.Helper()
+	if email == nil {
+		t.Fatal("no se generó el email")
+	}
+
+	mailer.Reset()
+	if err := service.Deliver(email); err != nil {
+		t.Fatalf("error al entregar: %v", err)
+	}
+
+	messages := mailer.MessagesTo(email.Destinatario)
+	if len(messages) != 1 {
+		t.Fatalf("mensajes a %s = %d, se esperaba 1", email.Destinatario, len(messages))
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(messages[0].Message))
+	if err != nil {
+		t.Fatalf("mensaje inválido: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("asunto inválido: %v", err)
+	}
+
+	root := readPart(t, msg.Header.Get("Content-Type"), "", msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	return deliveredEmail{to: messages[0].To, subject: subject, root: root}
+}
+
+func readPart(t *testing.T, contentType, disposition, encoding string, body io.Reader) mimePart {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("Content-Type inválido %q: %v", contentType, err)
+	}
+
+	part := mimePart{mediaType: mediaType, params: params, disposition: disposition}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("error al leer parte: %v", err)
+		}
+		if strings.EqualFold(encoding, "base64") {
+			data, err = base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "").Replace(string(data)))
+			if err != nil {
+				t.Fatalf("base64 inválido: %v", err)
+			}
+		}
+		part.body = data
+		return part
+	}
+
+	// multipart.Reader decodifica el quoted-printable y quita esa cabecera
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		child, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("error al leer %s: %v", mediaType, err)
+		}
+		part.parts = append(part.parts, readPart(t, child.Header.Get("Content-Type"), child.Header.Get("Content-Disposition"),
+			child.Header.Get("Content-Transfer-Encoding"), child))
+	}
+	return part
+}
+
+// alternative retorna las partes de texto y HTML del cuerpo del email
+func (e deliveredEmail) alternative(t *testing.T) (text, html string) {
+	t.Helper()
+
+	body := e.root
+	if body.mediaType == "multipart/mixed" {
+		body = body.parts[0]
+	}
+	if body.mediaType != "multipart/alternative" || len(body.parts) != 2 {
+		t.Fatalf("cuerpo %s con %d partes, se esperaba multipart/alternative con texto y HTML", body.mediaType, len(body.parts))
+	}
+	if body.parts[0].mediaType != "text/plain" || body.parts[1].mediaType != "text/html" {
+		t.Fatalf("partes %s y %s, se esperaba text/plain y luego text/html", body.parts[0].mediaType, body.parts[1].mediaType)
+	}
+	return string(body.parts[0].body), string(body.parts[1].body)
+}
+
+// invite retorna el .ics adjunto al email
+func (e deliveredEmail) invite(t *testing.T) (mimePart, string) {
+	t.Helper()
+
+	if e.root.mediaType != "multipart/mixed" || len(e.root.parts) != 2 {
+		t.Fatalf("mensaje %s con %d partes, se esperaba multipart/mixed con cuerpo e invitación", e.root.mediaType, len(e.root.parts))
+	}
+	attachment := e.root.parts[1]
+	if attachment.mediaType != "text/calendar" {
+		t.Fatalf("adjunto %s, se esperaba text/calendar", attachment.mediaType)
+	}
+	return attachment, string(attachment.body)
+}
+
+// icsProperty valor de una propiedad del .ics, con las líneas plegadas ya unidas
+func icsProperty(ics, name string) string {
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	for _, line := range strings.Split(unfolded, "\r\n") {
+		if strings.HasPrefix(line, name+":") {
+			return strings.TrimPrefix(line, name+":")
+		}
+	}
+	return ""
+}
+
+func TestDeliverMIMEStructure(t *testing.T) {
+	service, mailer := newTestEmailService(t)
+
+	t.Run("con invitación", func(t *testing.T) {
+		email, err := service.AppointmentConfirmationEmail(testAppointment(models.IdiomaEs))
+		if err != nil {
+			t.Fatalf("error al armar email: %v", err)
+		}
+
+		delivered := deliver(t, service, mailer, email)
+		if len(delivered.to) != 1 || delivered.to[0] != "ana@example.com" {
+			t.Errorf("destinatarios = %v", delivered.to)
+		}
+
+		text, html := delivered.alternative(t)
+		if !strings.Contains(text, "Ana López") || !strings.Contains(html, "Ana López") {
+			t.Error("el cuerpo no incluye el nombre del paciente")
+		}
+		if strings.Contains(text, "<") {
+			t.Error("la versión en texto contiene HTML")
+		}
+
+		attachment, ics := delivered.invite(t)
+		if attachment.params["method"] != calendarRequest || attachment.params["charset"] != "UTF-8" {
+			t.Errorf("parámetros del adjunto = %v", attachment.params)
+		}
+		if !strings.HasPrefix(attachment.disposition, "attachment") || !strings.Contains(attachment.disposition, calendarFileName) {
+			t.Errorf("Content-Disposition = %q", attachment.disposition)
+		}
+		if !strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(ics, "END:VCALENDAR\r\n") {
+			t.Error("el .ics no es un VCALENDAR completo con fin de línea CRLF")
+		}
+		for _, line := range strings.Split(ics, "\r\n") {
+			if len(line) > calendarLineOctets {
+				t.Errorf("línea del .ics de %d octetos: %q", len(line), line)
+			}
+		}
+		if got := icsProperty(ics, "DTSTART"); got != "20300314T100000Z" {
+			t.Errorf("DTSTART = %q", got)
+		}
+		if got := icsProperty(ics, "DTEND"); got != "20300314T104500Z" {
+			t.Errorf("DTEND = %q", got)
+		}
+	})
+
+	t.Run("sin adjuntos", func(t *testing.T) {
+		user := &models.User{ID: 1, Nombre: "Ana", Email: "ana@example.com", Idioma: models.IdiomaEs}
+		email, err := service.PasswordResetEmail(user, "token", time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("error al armar email: %v", err)
+		}
+
+		delivered := deliver(t, service, mailer, email)
+		if delivered.root.mediaType != "multipart/alternative" {
+			t.Fatalf("mensaje %s, se esperaba multipart/alternative", delivered.root.mediaType)
+		}
+		delivered.alternative(t)
+	})
+}
+
+// Confirmación, cambio de horario y cancelación actualizan el mismo evento del calendario
+func TestInviteUIDStableAcrossEmails(t *testing.T) {
+	service, mailer := newTestEmailService(t)
+	appointment := testAppointment(models.IdiomaEs)
+
+	confirmation, err := service.AppointmentConfirmationEmail(appointment)
+	if err != nil {
+		t.Fatalf("error al armar confirmación: %v", err)
+	}
+
+	rescheduled := testAppointment(models.IdiomaEs)
+	rescheduled.FechaHora = appointment.FechaHora.Add(48 * time.Hour)
+	reschedule, err := service.AppointmentRescheduleEmail(rescheduled, appointment.FechaHora, SpecialistLinks{ConfirmToken: "c", RespondToken: "r"})
+	if err != nil {
+		t.Fatalf("error al armar cambio de horario: %v", err)
+	}
+
+	cancellation, err := service.AppointmentCancelledEmail(rescheduled)
+	if err != nil {
+		t.Fatalf("error al armar cancelación: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		email   *models.EmailSaliente
+		to      string
+		method  string
+		status  string
+		dtstart string
+	}{
+		{"confirmación", confirmation, "ana@example.com", calendarRequest, "CONFIRMED", "20300314T100000Z"},
+		{"cambio de horario", reschedule, "ruiz@clinicawenka.com", calendarRequest, "CONFIRMED", "20300316T100000Z"},
+		{"cancelación", cancellation, "ana@example.com", calendarCancel, "CANCELLED", "20300316T100000Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivered := deliver(t, service, mailer, tt.email)
+			if len(delivered.to) != 1 || delivered.to[0] != tt.to {
+				t.Errorf("destinatarios = %v, se esperaba %s", delivered.to, tt.to)
+			}
+
+			_, ics := delivered.invite(t)
+			if got := icsProperty(ics, "UID"); got != "cita-42@clinicawenka.com" {
+				t.Errorf("UID = %q, se esperaba cita-42@clinicawenka.com", got)
+			}
+			if got := icsProperty(ics, "METHOD"); got != tt.method {
+				t.Errorf("METHOD = %q, se esperaba %q", got, tt.method)
+			}
+			if got := icsProperty(ics, "STATUS"); got != tt.status {
+				t.Errorf("STATUS = %q, se esperaba %q", got, tt.status)
+			}
+			if got := icsProperty(ics, "DTSTART"); got != tt.dtstart {
+				t.Errorf("DTSTART = %q, se esperaba %q", got, tt.dtstart)
+			}
+		})
+	}
+}
+
+func TestEmailLanguageSelection(t *testing.T) {
+	service, mailer := newTestEmailService(t)
+
+	tests := []struct {
+		name    string
+		idioma  string
+		subject string
+		text    string
+		summary string
+	}{
+		{"español", models.IdiomaEs, "Cita Confirmada - Clínica Wenka", "Estimado(a) Ana López", "Cita: Limpieza dental - Clínica Wenka"},
+		{"inglés", models.IdiomaEn, "Appointment Confirmed - Clínica Wenka", "Dear Ana López", "Appointment: Limpieza dental - Clínica Wenka"},
+		{"sin idioma usa español", "", "Cita Confirmada - Clínica Wenka", "Estimado(a) Ana López", "Cita: Limpieza dental - Clínica Wenka"},
+		{"idioma desconocido usa español", "fr", "Cita Confirmada - Clínica Wenka", "Estimado(a) Ana López", "Cita: Limpieza dental - Clínica Wenka"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := service.AppointmentConfirmationEmail(testAppointment(tt.idioma))
+			if err != nil {
+				t.Fatalf("error al armar email: %v", err)
+			}
+
+			delivered := deliver(t, service, mailer, email)
+			if delivered.subject != tt.subject {
+				t.Errorf("asunto = %q, se esperaba %q", delivered.subject, tt.subject)
+			}
+
+			text, html := delivered.alternative(t)
+			// En HTML el nombre va dentro de <strong>
+			greeting := strings.SplitN(tt.text, " ", 2)[0]
+			if !strings.Contains(text, tt.text) || !strings.Contains(html, greeting) {
+				t.Errorf("el cuerpo no contiene %q", tt.text)
+			}
+
+			_, ics := delivered.invite(t)
+			if got := icsProperty(ics, "SUMMARY"); got != tt.summary {
+				t.Errorf("SUMMARY = %q, se esperaba %q", got, tt.summary)
+			}
+		})
+	}
+}
+
+// El motivo lo escribe el especialista: en HTML se escapa, en texto plano se conserva
+func TestDeclinedEmailEscapesMotivo(t *testing.T) {
+	service, mailer := newTestEmailService(t)
+	motivo := `<script>alert("x")</script> & <b>viaje</b>`
+
+	tests := []struct {
+		name   string
+		idioma string
+		label  string
+	}{
+		{"español", models.IdiomaEs, "Motivo:"},
+		{"inglés", models.IdiomaEn, "Reason:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := service.AppointmentDeclinedEmail(testAppointment(tt.idioma), "  "+motivo+"  ")
+			if err != nil {
+				t.Fatalf("error al armar email: %v", err)
+			}
+
+			delivered := deliver(t, service, mailer, email)
+			text, html := delivered.alternative(t)
+
+			if strings.Contains(html, "<script>") || strings.Contains(html, "<b>viaje</b>") {
+				t.Error("el HTML contiene el motivo sin escapar")
+			}
+			if !strings.Contains(html, "&lt;script&gt;") || !strings.Contains(html, "&amp;") {
+				t.Error("el HTML no contiene el motivo escapado")
+			}
+			if !strings.Contains(html, tt.label) {
+				t.Errorf("el HTML no contiene %q", tt.label)
+			}
+			if !strings.Contains(text, motivo) {
+				t.Error("el texto plano no contiene el motivo tal cual")
+			}
+
+			_, ics := delivered.invite(t)
+			if got := icsProperty(ics, "METHOD"); got != calendarCancel {
+				t.Errorf("METHOD = %q, se esperaba %q", got, calendarCancel)
+			}
+		})
+	}
+}
+
+func TestDeliverSkipsEmailWithoutRecipient(t *testing.T) {
+	service, _ := newTestEmailService(t)
+	appointment := testAppointment(models.IdiomaEs)
+	appointment.EmailPaciente = ""
+
+	email, err := service.AppointmentConfirmationEmail(appointment)
+	if err != nil {
+		t.Fatalf("error al armar email: %v", err)
+	}
+	if email != nil {
+		t.Errorf("se generó un email sin destinatario: %+v", email)
+	}
+}
+
+// Los enlaces del email abren páginas del frontend; solo ellas llaman a la API por POST
+func TestEmailLinksOpenFrontendPages(t *testing.T) {
+	service, mailer := newTestEmailService(t)
+	appointment := testAppointment(models.IdiomaEs)
+
+	notification, err := service.AppointmentNotificationEmail(appointment, SpecialistLinks{ConfirmToken: "c", RespondToken: "r"})
+	if err != nil {
+		t.Fatalf("error al armar aviso al especialista: %v", err)
+	}
+
+	proposals, err := service.AppointmentProposalsEmail(appointment, "", []ProposalLink{
+		{PropuestaID: 7, FechaHora: appointment.FechaHora.Add(24 * time.Hour), Token: "p"},
+	})
+	if err != nil {
+		t.Fatalf("error al armar horarios propuestos: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		email *models.EmailSaliente
+		links []string
+	}{
+		{"aviso al especialista", notification, []string{
+			"http://localhost:3000/confirm-appointment/confirm/42?token=c",
+			"http://localhost:3000/confirm-appointment/respond/42?token=r",
+		}},
+		{"horarios propuestos", proposals, []string{
+			"http://localhost:3000/confirm-appointment/respond/42?proposal=7&token=p",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, _ := deliver(t, service, mailer, tt.email).alternative(t)
+			for _, link := range tt.links {
+				if !strings.Contains(text, link) {
+					t.Errorf("el email no contiene el enlace %s", link)
+				}
+			}
+			if strings.Contains(text, "/api/") {
+				t.Error("el email enlaza directamente a la API")
+			}
+		})
+	}
+}
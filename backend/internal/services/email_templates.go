@@ -0,0 +1,222 @@
+// backend/internal/services/email_templates.go
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+)
+
+// Plantillas de los emails. Cada email tiene por idioma un .html que define "title",
+// "subtitle" y "content" (y opcionalmente "icon") para layout.html, y un .txt que define
+// "subject" y "text" para layout.txt
+//
+//go:embed templates
+var templateFS embed.FS
+
+// Nombres de las plantillas de email
+const (
+	tplAppointmentConfirmation = "appointment_confirmation"
+	tplAppointmentSpecialist   = "appointment_specialist"
+	tplAppointmentProposals    = "appointment_proposals"
+	tplAppointmentDeclined     = "appointment_declined"
+	tplAppointmentCancelled    = "appointment_cancelled"
+	tplAppointmentReminder     = "appointment_reminder"
+	tplPasswordReset           = "password_reset"
+	tplEmailVerification       = "email_verification"
+	tplAccountLocked           = "account_locked"
+)
+
+var emailTemplateNames = []string{
+	tplAppointmentConfirmation,
+	tplAppointmentSpecialist,
+	tplAppointmentProposals,
+	tplAppointmentDeclined,
+	tplAppointmentCancelled,
+	tplAppointmentReminder,
+	tplPasswordReset,
+	tplEmailVerification,
+	tplAccountLocked,
+}
+
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// emailTemplates plantillas por idioma y nombre. Se cargan al iniciar: una plantilla
+// inválida es un error de programación y detiene el servidor
+var emailTemplates = loadEmailTemplates()
+
+// emailData datos disponibles en las plantillas; cada email llena solo los que usa
+type emailData struct {
+	Lang              string
+	FrontendURL       string
+	Appointment       *models.AppointmentWithDetails
+	User              *models.User
+	Motivo            string
+	ConfirmURL        string
+	RespondURL        string
+	ActionURL         string
+	ExpiraEn          time.Time
+	IP                string
+	Anticipacion      time.Duration
+	PreviousFechaHora *time.Time
+	Proposals         []proposalView
+}
+
+// proposalView horario propuesto con su enlace para aceptarlo
+type proposalView struct {
+	FechaHora time.Time
+	AcceptURL string
+}
+
+func loadEmailTemplates() map[string]map[string]*emailTemplate {
+	templates := make(map[string]map[string]*emailTemplate)
+
+	for _, lang := range models.Idiomas {
+		funcs := templateFuncs(lang)
+		templates[lang] = make(map[string]*emailTemplate)
+
+		for _, name := range emailTemplateNames {
+			html := htmltemplate.Must(htmltemplate.New("layout.html").
+				Funcs(htmltemplate.FuncMap(funcs)).
+				ParseFS(templateFS, "templates/layout.html", "templates/"+lang+"/footer.html", "templates/"+lang+"/"+name+".html"))
+
+			text := texttemplate.Must(texttemplate.New("layout.txt").
+				Funcs(funcs).
+				ParseFS(templateFS, "templates/layout.txt", "templates/"+lang+"/footer.txt", "templates/"+lang+"/"+name+".txt"))
+
+			templates[lang][name] = &emailTemplate{html: html, text: text}
+		}
+	}
+
+	return templates
+}
+
+// render arma un email de la bandeja de salida con la plantilla en el idioma indicado.
+// Sin destinatario retorna nil y no se encola nada
+func (s *EmailService) render(to, lang, name string, data *emailData) (*models.EmailSaliente, error) {
+	if to == "" {
+		return nil, nil
+	}
+
+	if !isIdioma(lang) {
+		lang = models.IdiomaEs
+	}
+
+	tpl, ok := emailTemplates[lang][name]
+	if !ok {
+		return nil, fmt.Errorf("plantilla de email %s no encontrada", name)
+	}
+
+	data.Lang = lang
+	data.FrontendURL = s.frontendURL
+
+	var subject, text, body bytes.Buffer
+	if err := tpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("error al generar asunto del email %s: %v", name, err)
+	}
+	if err := tpl.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("error al generar texto del email %s: %v", name, err)
+	}
+	if err := tpl.html.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("error al generar email %s: %v", name, err)
+	}
+
+	return &models.EmailSaliente{
+		Destinatario: to,
+		Asunto:       strings.TrimSpace(subject.String()),
+		Cuerpo:       body.String(),
+		CuerpoTexto:  text.String(),
+	}, nil
+}
+
+// isIdioma indica si el idioma tiene plantillas de email
+func isIdioma(lang string) bool {
+	for _, idioma := range models.Idiomas {
+		if lang == idioma {
+			return true
+		}
+	}
+	return false
+}
+
+// templateFuncs funciones de formato de las plantillas en cada idioma
+func templateFuncs(lang string) texttemplate.FuncMap {
+	dateLayout := "02/01/2006"
+	if lang == models.IdiomaEn {
+		dateLayout = "January 2, 2006"
+	}
+
+	return texttemplate.FuncMap{
+		"date": func(t time.Time) string { return t.Format(dateLayout) },
+		"time": func(t time.Time) string { return t.Format("15:04") },
+		"trim": strings.TrimSpace,
+		"estado": func(estado string) string {
+			if label, ok := estadoLabels[lang][estado]; ok {
+				return label
+			}
+			return estado
+		},
+		"anticipacion": func(anticipacion time.Duration) string {
+			return describeAnticipacion(lang, anticipacion)
+		},
+	}
+}
+
+var estadoLabels = map[string]map[string]string{
+	models.IdiomaEs: {
+		models.EstadoProgramada: "Programada",
+		models.EstadoConfirmada: "Confirmada",
+		models.EstadoCancelada:  "Cancelada",
+		models.EstadoCompletada: "Completada",
+		models.EstadoEnCurso:    "En Curso",
+		models.EstadoNoAsistio:  "No Asistió",
+	},
+	models.IdiomaEn: {
+		models.EstadoProgramada: "Scheduled",
+		models.EstadoConfirmada: "Confirmed",
+		models.EstadoCancelada:  "Cancelled",
+		models.EstadoCompletada: "Completed",
+		models.EstadoEnCurso:    "In Progress",
+		models.EstadoNoAsistio:  "No-show",
+	},
+}
+
+// describeAnticipacion expresa la anticipación del recordatorio: "mañana", "en 2 horas"...
+func describeAnticipacion(lang string, anticipacion time.Duration) string {
+	if lang == models.IdiomaEn {
+		switch {
+		case anticipacion == 24*time.Hour:
+			return "tomorrow"
+		case anticipacion > 24*time.Hour:
+			return fmt.Sprintf("in %d days", int(anticipacion.Hours()/24))
+		case anticipacion == time.Hour:
+			return "in one hour"
+		case anticipacion > time.Hour:
+			return fmt.Sprintf("in %d hours", int(anticipacion.Hours()))
+		default:
+			return fmt.Sprintf("in %d minutes", int(anticipacion.Minutes()))
+		}
+	}
+
+	switch {
+	case anticipacion == 24*time.Hour:
+		return "mañana"
+	case anticipacion > 24*time.Hour:
+		return fmt.Sprintf("en %d días", int(anticipacion.Hours()/24))
+	case anticipacion == time.Hour:
+		return "en una hora"
+	case anticipacion > time.Hour:
+		return fmt.Sprintf("en %d horas", int(anticipacion.Hours()))
+	default:
+		return fmt.Sprintf("en %d minutos", int(anticipacion.Minutes()))
+	}
+}
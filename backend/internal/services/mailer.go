@@ -0,0 +1,85 @@
+// backend/internal/services/mailer.go
+package services
+
+import "sync"
+
+// Mailer transporte que entrega un mensaje MIME ya armado
+type Mailer interface {
+	Send(from string, to []string, message []byte) error
+}
+
+// CapturedMessage mensaje retenido por MemoryMailer
+type CapturedMessage struct {
+	From    string
+	To      []string
+	Message []byte
+}
+
+// MemoryMailer guarda los mensajes en memoria en lugar de entregarlos, para que las pruebas
+// revisen su contenido
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []CapturedMessage
+	err      error
+}
+
+// NewMemoryMailer crea un transporte en memoria vacío
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send retiene una copia del mensaje
+func (m *MemoryMailer) Send(from string, to []string, message []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+
+	m.messages = append(m.messages, CapturedMessage{
+		From:    from,
+		To:      append([]string(nil), to...),
+		Message: append([]byte(nil), message...),
+	})
+	return nil
+}
+
+// FailWith hace que los envíos siguientes fallen con err, como un servidor SMTP caído; con nil
+// vuelven a aceptarse
+func (m *MemoryMailer) FailWith(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
+
+// Messages retorna los mensajes retenidos en el orden en que se enviaron
+func (m *MemoryMailer) Messages() []CapturedMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]CapturedMessage(nil), m.messages...)
+}
+
+// MessagesTo retorna los mensajes enviados a un destinatario
+func (m *MemoryMailer) MessagesTo(to string) []CapturedMessage {
+	var found []CapturedMessage
+	for _, message := range m.Messages() {
+		for _, rcpt := range message.To {
+			if rcpt == to {
+				found = append(found, message)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// Reset descarta los mensajes retenidos
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
@@ -0,0 +1,57 @@
+// backend/internal/services/mailer_maildir.go
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MaildirMailer guarda cada mensaje como un archivo en un Maildir en lugar de entregarlo. Se
+// usa en desarrollo: los mensajes se abren con cualquier cliente de correo o lector de .eml
+type MaildirMailer struct {
+	dir      string
+	hostname string
+	counter  atomic.Uint64
+}
+
+// NewMaildirMailer crea el transporte y las carpetas tmp, new y cur del Maildir si no existen
+func NewMaildirMailer(dir string) (*MaildirMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("error al crear el Maildir %s: %v", dir, err)
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+
+	return &MaildirMailer{
+		dir:      dir,
+		hostname: strings.NewReplacer("/", "_", ":", "_").Replace(hostname),
+	}, nil
+}
+
+// Send escribe el mensaje en tmp y lo mueve a new, así un lector nunca ve un archivo a medias
+func (m *MaildirMailer) Send(from string, to []string, message []byte) error {
+	name := fmt.Sprintf("%d.P%dQ%d.%s", time.Now().UnixNano(), os.Getpid(), m.counter.Add(1), m.hostname)
+	tmpPath := filepath.Join(m.dir, "tmp", name)
+
+	// El sobre SMTP no queda en las cabeceras; se registra como lo haría un servidor de entrega
+	envelope := fmt.Sprintf("Return-Path: <%s>\r\nDelivered-To: %s\r\n", from, strings.Join(to, ", "))
+
+	if err := os.WriteFile(tmpPath, append([]byte(envelope), message...), 0o644); err != nil {
+		return fmt.Errorf("error al guardar email: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(m.dir, "new", name)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error al guardar email: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// backend/internal/services/mailer_smtp.go
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// Seguridad de la conexión SMTP
+const (
+	SMTPStartTLS = "starttls" // conexión en claro que se eleva a TLS; obligatorio (puerto 587)
+	SMTPTLS      = "tls"      // TLS implícito desde la conexión (puerto 465)
+	SMTPNone     = "none"     // sin cifrado, solo para relays locales
+)
+
+const smtpTimeout = 30 * time.Second
+
+// SMTPMailer entrega los mensajes a un servidor SMTP
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	security string
+}
+
+// NewSMTPMailer crea el transporte SMTP. Sin usuario no se autentica
+func NewSMTPMailer(host, port, username, password, security string) (*SMTPMailer, error) {
+	switch security {
+	case SMTPStartTLS, SMTPTLS, SMTPNone:
+	default:
+		return nil, fmt.Errorf("seguridad SMTP inválida. Opciones: %s, %s, %s", SMTPStartTLS, SMTPTLS, SMTPNone)
+	}
+
+	return &SMTPMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		security: security,
+	}, nil
+}
+
+// Send abre una conexión, entrega el mensaje y la cierra
+func (m *SMTPMailer) Send(from string, to []string, message []byte) error {
+	client, err := m.dial()
+	if err != nil {
+		return fmt.Errorf("error al conectar con el servidor SMTP: %v", err)
+	}
+	defer client.Close()
+
+	if m.security == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("el servidor SMTP %s no admite STARTTLS", m.host)
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("error al iniciar TLS: %v", err)
+		}
+	}
+
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("error al autenticar en el servidor SMTP: %v", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("error al enviar email: %v", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("error al enviar email a %s: %v", rcpt, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error al enviar email: %v", err)
+	}
+	if _, err := writer.Write(message); err != nil {
+		return fmt.Errorf("error al enviar email: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("error al enviar email: %v", err)
+	}
+
+	return client.Quit()
+}
+
+// dial abre la conexión con TLS implícito o en claro según la seguridad configurada. El
+// plazo cubre toda la entrega para que un servidor colgado no detenga la bandeja de salida
+func (m *SMTPMailer) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.host, m.port)
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if m.security == SMTPTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: m.host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,190 @@
+// backend/internal/services/patient_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/repositories"
+)
+
+// Errores de la gestión de expedientes
+var (
+	ErrPacienteNotFound  = errors.New("paciente no encontrado")
+	ErrTitularNotRemoved = errors.New("el expediente del titular no puede eliminarse; elimina la cuenta desde tu perfil")
+)
+
+type PatientService struct {
+	patientRepo *repositories.PatientRepository
+}
+
+// NewPatientService crea una nueva instancia del servicio
+func NewPatientService(patientRepo *repositories.PatientRepository) *PatientService {
+	return &PatientService{
+		patientRepo: patientRepo,
+	}
+}
+
+// ListForUser obtiene el expediente del titular y los de sus dependientes
+func (s *PatientService) ListForUser(userID int) ([]models.Paciente, error) {
+	if _, err := s.patientRepo.EnsureTitular(userID); err != nil {
+		return nil, err
+	}
+
+	return s.patientRepo.FindByUsuario(userID)
+}
+
+// CreateDependant registra un dependiente (hijo, padre...) administrado por la cuenta
+func (s *PatientService) CreateDependant(userID int, req *models.PacienteRequest) (*models.Paciente, error) {
+	if err := validatePacienteRequest(req, false); err != nil {
+		return nil, err
+	}
+
+	fechaNacimiento, _ := time.Parse("2006-01-02", req.FechaNacimiento)
+
+	email, err := s.dependantEmail(req.Email, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	paciente := &models.Paciente{
+		Nombre:          strings.TrimSpace(req.Nombre),
+		ApellidoPaterno: strings.TrimSpace(req.ApellidoPaterno),
+		ApellidoMaterno: strings.TrimSpace(req.ApellidoMaterno),
+		Email:           email,
+		Telefono:        strings.TrimSpace(req.Telefono),
+		FechaNacimiento: fechaNacimiento,
+		Sexo:            req.Sexo,
+		UsuarioID:       &userID,
+		Parentesco:      req.Parentesco,
+		Activo:          true,
+	}
+
+	if err := s.patientRepo.Create(paciente); err != nil {
+		return nil, err
+	}
+
+	return paciente, nil
+}
+
+// UpdatePatient edita un expediente de la cuenta. Del titular solo cambian apellido materno,
+// fecha de nacimiento y sexo: nombre, teléfono y email se editan en el perfil de la cuenta
+func (s *PatientService) UpdatePatient(userID, id int, req *models.PacienteRequest) (*models.Paciente, error) {
+	paciente, err := s.findOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	titular := paciente.Parentesco == models.ParentescoTitular
+	if err := validatePacienteRequest(req, titular); err != nil {
+		return nil, err
+	}
+
+	fechaNacimiento, _ := time.Parse("2006-01-02", req.FechaNacimiento)
+	updates := map[string]interface{}{
+		"apellido_materno": strings.TrimSpace(req.ApellidoMaterno),
+		"fecha_nacimiento": fechaNacimiento,
+		"sexo":             req.Sexo,
+	}
+
+	if !titular {
+		email, err := s.dependantEmail(req.Email, paciente.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		updates["nombre"] = strings.TrimSpace(req.Nombre)
+		updates["apellido_paterno"] = strings.TrimSpace(req.ApellidoPaterno)
+		updates["telefono"] = strings.TrimSpace(req.Telefono)
+		updates["email"] = email
+		updates["parentesco"] = req.Parentesco
+	}
+
+	if err := s.patientRepo.Update(paciente.ID, updates); err != nil {
+		return nil, err
+	}
+
+	return s.patientRepo.FindByID(paciente.ID)
+}
+
+// RemoveDependant quita un dependiente de la cuenta. El expediente solo se desactiva para
+// conservar el historial de citas de la clínica
+func (s *PatientService) RemoveDependant(userID, id int) error {
+	paciente, err := s.findOwned(userID, id)
+	if err != nil {
+		return err
+	}
+
+	if paciente.Parentesco == models.ParentescoTitular {
+		return ErrTitularNotRemoved
+	}
+
+	return s.patientRepo.Deactivate(paciente.ID)
+}
+
+// findOwned obtiene un expediente activo que administra la cuenta
+func (s *PatientService) findOwned(userID, id int) (*models.Paciente, error) {
+	paciente, err := s.patientRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if paciente == nil || !paciente.Activo || paciente.UsuarioID == nil || *paciente.UsuarioID != userID {
+		return nil, ErrPacienteNotFound
+	}
+
+	return paciente, nil
+}
+
+// dependantEmail normaliza el email opcional de un dependiente; vacío se guarda como NULL
+func (s *PatientService) dependantEmail(email string, pacienteID int) (*string, error) {
+	email = normalizeEmail(email)
+	if email == "" {
+		return nil, nil
+	}
+
+	if !strings.Contains(email, "@") {
+		return nil, errors.New("email inválido")
+	}
+
+	taken, err := s.patientRepo.EmailTaken(email, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if taken {
+		return nil, errors.New("el email ya pertenece a otro paciente")
+	}
+
+	return &email, nil
+}
+
+// validatePacienteRequest valida los datos de un expediente. Del titular no se validan
+// nombre ni parentesco porque no se toman de la petición
+func validatePacienteRequest(req *models.PacienteRequest, titular bool) error {
+	if !titular && (strings.TrimSpace(req.Nombre) == "" || strings.TrimSpace(req.ApellidoPaterno) == "") {
+		return errors.New("nombre y apellido paterno son requeridos")
+	}
+
+	fechaNacimiento, err := time.Parse("2006-01-02", req.FechaNacimiento)
+	if err != nil {
+		return errors.New("formato de fecha de nacimiento inválido. Use YYYY-MM-DD")
+	}
+
+	if fechaNacimiento.After(time.Now()) {
+		return errors.New("la fecha de nacimiento no puede ser futura")
+	}
+
+	if req.Sexo != "M" && req.Sexo != "F" && req.Sexo != "O" {
+		return errors.New("sexo inválido. Opciones: M, F, O")
+	}
+
+	if !titular && !containsString(models.ParentescosDependiente, req.Parentesco) {
+		return fmt.Errorf("parentesco inválido. Opciones: %s", strings.Join(models.ParentescosDependiente, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+// backend/internal/services/reminder_scheduler.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/repositories"
+)
+
+// Parámetros del envío de recordatorios
+const (
+	reminderBatchSize  = 100
+	reminderClaimStale = 10 * time.Minute // un reclamo sin enviar tras este tiempo se reintenta
+)
+
+// reminderStore recordatorios enviados y reclamados. Lo implementa ReminderRepository; las
+// pruebas lo reemplazan por uno en memoria
+type reminderStore interface {
+	FindDue(anticipacion time.Duration, now time.Time, staleAfter time.Duration, limit int) ([]repositories.DueReminder, error)
+	Claim(citaID int, anticipacion time.Duration, fechaCita time.Time, staleAfter time.Duration) (time.Time, bool, error)
+	MarkSent(citaID int, anticipacion time.Duration, fechaCita, claimedAt time.Time, emails ...*models.EmailSaliente) (bool, error)
+}
+
+// ReminderScheduler envía recordatorios de las citas programadas y confirmadas con las
+// anticipaciones configuradas. Cada envío se reclama en la base de datos antes de hacerse,
+// por lo que varias réplicas o un reinicio no duplican recordatorios
+type ReminderScheduler struct {
+	reminderRepo    reminderStore
+	appointmentRepo appointmentStore
+	emailService    *EmailService
+	offsets         []time.Duration
+	interval        time.Duration
+}
+
+// NewReminderScheduler crea el programador de recordatorios
+func NewReminderScheduler(reminderRepo *repositories.ReminderRepository, appointmentRepo *repositories.AppointmentRepository, emailService *EmailService, offsets []time.Duration, interval time.Duration) *ReminderScheduler {
+	// De la anticipación menor a la mayor: si varios recordatorios vencieron a la vez (p. ej.
+	// tras una caída) solo se envía el más cercano a la cita
+	sorted := append([]time.Duration(nil), offsets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return &ReminderScheduler{
+		reminderRepo:    reminderRepo,
+		appointmentRepo: appointmentRepo,
+		emailService:    emailService,
+		offsets:         sorted,
+		interval:        interval,
+	}
+}
+
+// Start revisa los recordatorios pendientes cada intervalo hasta que se cancele el contexto
+func (s *ReminderScheduler) Start(ctx context.Context) {
+	if len(s.offsets) == 0 || s.interval <= 0 {
+		fmt.Println("Recordatorios de citas desactivados")
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce envía los recordatorios que vencieron hasta el momento indicado
+func (s *ReminderScheduler) RunOnce(now time.Time) {
+	for _, offset := range s.offsets {
+		due, err := s.reminderRepo.FindDue(offset, now, reminderClaimStale, reminderBatchSize)
+		if err != nil {
+			fmt.Printf("Error al buscar recordatorios: %v\n", err)
+			return
+		}
+
+		for _, reminder := range due {
+			if err := s.send(reminder, offset); err != nil {
+				fmt.Printf("Error al enviar recordatorio de la cita %d: %v\n", reminder.CitaID, err)
+			}
+		}
+	}
+}
+
+// send reclama un recordatorio y encola su email. Si algo falla el reclamo queda sin marcar y
+// se reintenta cuando se considera abandonado; la entrega la reintenta la bandeja de salida
+func (s *ReminderScheduler) send(reminder repositories.DueReminder, offset time.Duration) error {
+	claimedAt, claimed, err := s.reminderRepo.Claim(reminder.CitaID, offset, reminder.FechaHora, reminderClaimStale)
+	if err != nil || !claimed {
+		return err
+	}
+
+	details, err := s.appointmentRepo.FindByID(reminder.CitaID)
+	if err != nil || details == nil {
+		return err
+	}
+
+	// Sin destinatario no se encola nada, pero el recordatorio queda marcado para no reintentarlo
+	if details.EmailPaciente == "" {
+		fmt.Printf("La cita %d no tiene email de contacto; se omite el recordatorio\n", reminder.CitaID)
+	}
+
+	message, err := s.emailService.AppointmentReminderEmail(details, offset)
+	if err != nil {
+		return err
+	}
+
+	marked, err := s.reminderRepo.MarkSent(reminder.CitaID, offset, reminder.FechaHora, claimedAt, message)
+	if err != nil {
+		return err
+	}
+
+	if !marked {
+		fmt.Printf("Otro proceso retomó el recordatorio de la cita %d; no se encola de nuevo\n", reminder.CitaID)
+	}
+
+	return nil
+}
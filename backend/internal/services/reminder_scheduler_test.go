@@ -0,0 +1,184 @@
+// backend/internal/services/reminder_scheduler_test.go
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/repositories"
+)
+
+// reminderKey identifica un recordatorio: cita y anticipación en minutos
+type reminderKey struct {
+	citaID  int
+	minutos int
+}
+
+// dueQuery parámetros con los que el programador buscó recordatorios pendientes
+type dueQuery struct {
+	anticipacion time.Duration
+	now          time.Time
+	staleAfter   time.Duration
+}
+
+type reminderRecord struct {
+	reclamadoEn time.Time
+	enviadoEn   *time.Time
+}
+
+// memoryReminders reminderStore en memoria que reproduce las condiciones de
+// ReminderRepository. Con takeOver otra réplica retoma el reclamo antes de MarkSent
+type memoryReminders struct {
+	citas    map[int]time.Time // cita_id -> fecha_hora de las citas activas
+	records  map[reminderKey]*reminderRecord
+	queries  []dueQuery
+	queued   []reminderKey
+	takeOver bool
+}
+
+func newMemoryReminders(citas map[int]time.Time) *memoryReminders {
+	return &memoryReminders{citas: citas, records: map[reminderKey]*reminderRecord{}}
+}
+
+// active indica si el recordatorio ya se envió o lo reclamó alguien hace menos de staleAfter
+func (r *reminderRecord) active(now time.Time, staleAfter time.Duration) bool {
+	return r.enviadoEn != nil || !r.reclamadoEn.Before(now.Add(-staleAfter))
+}
+
+func (m *memoryReminders) FindDue(anticipacion time.Duration, now time.Time, staleAfter time.Duration, limit int) ([]repositories.DueReminder, error) {
+	m.queries = append(m.queries, dueQuery{anticipacion, now, staleAfter})
+	minutos := int(anticipacion.Minutes())
+
+	var due []repositories.DueReminder
+	for citaID, fechaHora := range m.citas {
+		if !fechaHora.After(now) || fechaHora.After(now.Add(anticipacion)) {
+			continue
+		}
+
+		// Un recordatorio igual o más cercano ya enviado o reclamado cubre a este
+		covered := false
+		for key, record := range m.records {
+			if key.citaID == citaID && key.minutos <= minutos && record.active(now, staleAfter) {
+				covered = true
+			}
+		}
+		if !covered {
+			due = append(due, repositories.DueReminder{CitaID: citaID, FechaHora: fechaHora})
+		}
+	}
+	return due, nil
+}
+
+func (m *memoryReminders) Claim(citaID int, anticipacion time.Duration, fechaCita time.Time, staleAfter time.Duration) (time.Time, bool, error) {
+	now := time.Now().Truncate(time.Second)
+	key := reminderKey{citaID, int(anticipacion.Minutes())}
+
+	if record, ok := m.records[key]; ok && record.active(now, staleAfter) {
+		return time.Time{}, false, nil
+	}
+
+	m.records[key] = &reminderRecord{reclamadoEn: now}
+	return now, true, nil
+}
+
+func (m *memoryReminders) MarkSent(citaID int, anticipacion time.Duration, fechaCita, claimedAt time.Time, emails ...*models.EmailSaliente) (bool, error) {
+	key := reminderKey{citaID, int(anticipacion.Minutes())}
+	record := m.records[key]
+
+	if m.takeOver {
+		record.reclamadoEn = claimedAt.Add(time.Second)
+	}
+
+	if record.enviadoEn != nil || !record.reclamadoEn.Equal(claimedAt) {
+		return false, nil
+	}
+
+	now := time.Now()
+	record.enviadoEn = &now
+	for range emails {
+		m.queued = append(m.queued, key)
+	}
+	return true, nil
+}
+
+func TestNewReminderSchedulerSortsOffsets(t *testing.T) {
+	offsets := []time.Duration{24 * time.Hour, time.Hour, 2 * time.Hour}
+	scheduler := NewReminderScheduler(nil, nil, nil, offsets, time.Minute)
+
+	want := []time.Duration{time.Hour, 2 * time.Hour, 24 * time.Hour}
+	if !reflect.DeepEqual(scheduler.offsets, want) {
+		t.Errorf("offsets = %v, se esperaba %v", scheduler.offsets, want)
+	}
+	if offsets[0] != 24*time.Hour {
+		t.Error("NewReminderScheduler modificó las anticipaciones recibidas")
+	}
+}
+
+func TestReminderRunOnce(t *testing.T) {
+	now := time.Now()
+	stale := now.Add(-2 * reminderClaimStale).Truncate(time.Second)
+	recent := now.Add(-time.Minute).Truncate(time.Second)
+	sent := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		fechaCita time.Time
+		records   map[reminderKey]*reminderRecord
+		takeOver  bool
+		runs      int
+		want      []reminderKey
+	}{
+		{"recordatorio del día anterior", now.Add(20 * time.Hour), nil, false, 1, []reminderKey{{1, 1440}}},
+		{"tras una caída solo el más cercano", now.Add(30 * time.Minute), nil, false, 1, []reminderKey{{1, 60}}},
+		{"no se repite en la siguiente ronda", now.Add(30 * time.Minute), nil, false, 2, []reminderKey{{1, 60}}},
+		{"fuera de la ventana", now.Add(30 * time.Hour), nil, false, 1, nil},
+		{"cita ya pasada", now.Add(-time.Minute), nil, false, 1, nil},
+		{"el más cercano ya se envió", now.Add(30 * time.Minute),
+			map[reminderKey]*reminderRecord{{1, 60}: {reclamadoEn: stale, enviadoEn: &sent}}, false, 1, nil},
+		{"reclamo reciente de otra réplica", now.Add(20 * time.Hour),
+			map[reminderKey]*reminderRecord{{1, 1440}: {reclamadoEn: recent}}, false, 1, nil},
+		{"reclamo abandonado se retoma", now.Add(20 * time.Hour),
+			map[reminderKey]*reminderRecord{{1, 1440}: {reclamadoEn: stale}}, false, 1, []reminderKey{{1, 1440}}},
+		{"otra réplica retoma el reclamo antes de marcarlo", now.Add(20 * time.Hour), nil, true, 1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reminders := newMemoryReminders(map[int]time.Time{1: tt.fechaCita})
+			for key, record := range tt.records {
+				reminders.records[key] = record
+			}
+			reminders.takeOver = tt.takeOver
+
+			details := testAppointment(models.IdiomaEs)
+			details.ID = 1
+			details.FechaHora = tt.fechaCita
+
+			emailService, _ := newTestEmailService(t)
+			scheduler := NewReminderScheduler(nil, nil, emailService, []time.Duration{24 * time.Hour, time.Hour}, time.Minute)
+			scheduler.reminderRepo = reminders
+			scheduler.appointmentRepo = &memoryAppointments{detalles: map[int]*models.AppointmentWithDetails{1: details}}
+
+			for i := 0; i < tt.runs; i++ {
+				scheduler.RunOnce(now)
+			}
+
+			if !reflect.DeepEqual(reminders.queued, tt.want) {
+				t.Errorf("recordatorios encolados = %v, se esperaba %v", reminders.queued, tt.want)
+			}
+
+			// Cada ronda busca de la anticipación menor a la mayor hasta el momento indicado
+			var wantQueries []dueQuery
+			for i := 0; i < tt.runs; i++ {
+				wantQueries = append(wantQueries,
+					dueQuery{time.Hour, now, reminderClaimStale},
+					dueQuery{24 * time.Hour, now, reminderClaimStale})
+			}
+			if !reflect.DeepEqual(reminders.queries, wantQueries) {
+				t.Errorf("búsquedas = %v, se esperaba %v", reminders.queries, wantQueries)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// backend/internal/services/schedule_service.go
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wenka/backend/internal/models"
+	"github.com/wenka/backend/internal/repositories"
+)
+
+type ScheduleService struct {
+	scheduleRepo *repositories.ScheduleRepository
+}
+
+// NewScheduleService crea una nueva instancia del servicio
+func NewScheduleService(scheduleRepo *repositories.ScheduleRepository) *ScheduleService {
+	return &ScheduleService{
+		scheduleRepo: scheduleRepo,
+	}
+}
+
+// ListTimeOff obtiene los bloqueos de agenda, opcionalmente filtrados por especialista y fechas
+func (s *ScheduleService) ListTimeOff(especialistaID int, from, to string) ([]models.BloqueoAgenda, error) {
+	var fromDate, toDate *time.Time
+
+	if from != "" {
+		parsed, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, fmt.Errorf("formato de fecha inválido. Use YYYY-MM-DD")
+		}
+		fromDate = &parsed
+	}
+
+	if to != "" {
+		parsed, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, fmt.Errorf("formato de fecha inválido. Use YYYY-MM-DD")
+		}
+		parsed = parsed.AddDate(0, 0, 1)
+		toDate = &parsed
+	}
+
+	bloqueos, err := s.scheduleRepo.ListBlocks(especialistaID, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if bloqueos == nil {
+		return []models.BloqueoAgenda{}, nil
+	}
+
+	return bloqueos, nil
+}
+
+// CreateTimeOff registra un nuevo bloqueo de agenda
+func (s *ScheduleService) CreateTimeOff(req *models.BloqueoAgendaRequest) (*models.BloqueoAgenda, error) {
+	bloqueo := &models.BloqueoAgenda{}
+	if err := s.applyTimeOffRequest(bloqueo, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.CreateBlock(bloqueo); err != nil {
+		return nil, err
+	}
+
+	return bloqueo, nil
+}
+
+// UpdateTimeOff modifica un bloqueo de agenda existente
+func (s *ScheduleService) UpdateTimeOff(id int, req *models.BloqueoAgendaRequest) (*models.BloqueoAgenda, error) {
+	bloqueo, err := s.scheduleRepo.FindBlockByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if bloqueo == nil {
+		return nil, fmt.Errorf("bloqueo no encontrado")
+	}
+
+	if err := s.applyTimeOffRequest(bloqueo, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.UpdateBlock(bloqueo); err != nil {
+		return nil, err
+	}
+
+	return bloqueo, nil
+}
+
+// DeleteTimeOff elimina un bloqueo de agenda
+func (s *ScheduleService) DeleteTimeOff(id int) error {
+	return s.scheduleRepo.DeleteBlock(id)
+}
+
+// applyTimeOffRequest valida la solicitud y copia sus valores al bloqueo
+func (s *ScheduleService) applyTimeOffRequest(bloqueo *models.BloqueoAgenda, req *models.BloqueoAgendaRequest) error {
+	if req.Tipo == "" {
+		return fmt.Errorf("el tipo de bloqueo es requerido")
+	}
+
+	if !containsString(models.TiposBloqueo, req.Tipo) {
+		return fmt.Errorf("tipo de bloqueo inválido: %s", req.Tipo)
+	}
+
+	if req.FechaInicio == "" || req.FechaFin == "" {
+		return fmt.Errorf("las fechas de inicio y fin son requeridas")
+	}
+
+	inicio, err := parseBlockBoundary(req.FechaInicio, false)
+	if err != nil {
+		return err
+	}
+
+	fin, err := parseBlockBoundary(req.FechaFin, true)
+	if err != nil {
+		return err
+	}
+
+	if !fin.After(inicio) {
+		return fmt.Errorf("rango de fechas inválido: la fecha final debe ser posterior a la inicial")
+	}
+
+	if req.EspecialistaID != nil {
+		exists, err := s.scheduleRepo.EspecialistaExists(*req.EspecialistaID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("especialista no encontrado")
+		}
+	}
+
+	bloqueo.EspecialistaID = req.EspecialistaID
+	bloqueo.Tipo = req.Tipo
+	bloqueo.FechaInicio = inicio
+	bloqueo.FechaFin = fin
+	bloqueo.Motivo = req.Motivo
+
+	return nil
+}
+
+// parseBlockBoundary interpreta "YYYY-MM-DD HH:MM" o "YYYY-MM-DD". Una fecha sin hora
+// usada como fin abarca el día completo
+func parseBlockBoundary(value string, isEnd bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02 15:04", value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("formato de fecha inválido. Use YYYY-MM-DD o YYYY-MM-DD HH:MM")
+	}
+
+	if isEnd {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+// backend/internal/utils/client_ip.go
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies redes de los proxies reversos cuyo X-Forwarded-For se acepta
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies interpreta una lista de IPs o rangos CIDR ("10.0.0.0/8", "127.0.0.1")
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("proxy de confianza inválido: %s", value)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("proxy de confianza inválido: %s", value)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// Contains indica si la IP pertenece a alguno de los proxies de confianza
+func (p TrustedProxies) Contains(ip net.IP) bool {
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP obtiene la IP del cliente a partir de la conexión. X-Forwarded-For solo se
+// considera si la conexión viene de un proxy de confianza: se recorre de derecha a izquierda
+// y se toma la primera dirección que no sea de un proxy, porque las de la izquierda las
+// escribe el propio cliente y se pueden falsificar
+func ClientIP(r *http.Request, trusted TrustedProxies) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	remote := net.ParseIP(host)
+	if remote == nil || !trusted.Contains(remote) {
+		return host
+	}
+
+	client := remote
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+
+		client = ip
+		if !trusted.Contains(ip) {
+			break
+		}
+	}
+
+	return client.String()
+}
@@ -0,0 +1,63 @@
+// backend/internal/utils/email_token.go
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Audiencia de los tokens de verificación de email; evita mezclarlos con otros enlaces
+const emailTokenAudience = "wenka-verificacion-email"
+
+// EmailVerificationClaims datos firmados en el enlace de verificación de email
+type EmailVerificationClaims struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"` // si el usuario cambia de email el enlace deja de servir
+	jwt.RegisteredClaims
+}
+
+// GenerateEmailVerificationToken firma el token del enlace de verificación de email
+func GenerateEmailVerificationToken(userID int, email string, expiresAt time.Time, secret string) (string, error) {
+	claims := EmailVerificationClaims{
+		UserID: userID,
+		Email:  email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{emailTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ValidateEmailVerificationToken verifica la firma, la audiencia y la expiración del token
+func ValidateEmailVerificationToken(tokenString, secret string) (*EmailVerificationClaims, error) {
+	if tokenString == "" {
+		return nil, ErrLinkTokenInvalid
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &EmailVerificationClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	}, jwt.WithAudience(emailTokenAudience), jwt.WithExpirationRequired())
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrLinkTokenExpired
+		}
+		return nil, ErrLinkTokenInvalid
+	}
+
+	claims, ok := token.Claims.(*EmailVerificationClaims)
+	if !ok || !token.Valid || claims.UserID == 0 {
+		return nil, ErrLinkTokenInvalid
+	}
+
+	return claims, nil
+}
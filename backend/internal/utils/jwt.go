@@ -2,6 +2,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -9,40 +11,65 @@ import (
 )
 
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	Rol       string `json:"rol"`
+	SessionID string `json:"sid"` // familia de refresh tokens; cerrar sesión la revoca
 	jwt.RegisteredClaims
 }
 
-// GenerateToken genera un token JWT para el usuario
-func GenerateToken(userID int, email, secret string) (string, error) {
+// GenerateToken genera un token de acceso JWT de corta duración ligado a una sesión
+func GenerateToken(userID int, email, rol, sessionID string, ttl time.Duration, keys *KeySet) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		Rol:       rol,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.sign(claims)
 }
 
-// ValidateToken valida y parsea un token JWT
-func ValidateToken(tokenString, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
+// GenerateRefreshToken genera un refresh token opaco y el hash que se guarda en la base de datos
+func GenerateRefreshToken() (string, string, error) {
+	return GenerateOpaqueToken()
+}
+
+// GenerateOpaqueToken genera un token aleatorio de un solo uso y su hash
+func GenerateOpaqueToken() (string, string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+// GenerateSessionID genera el identificador de una familia de refresh tokens
+func GenerateSessionID() (string, error) {
+	return randomHex(16)
+}
+
+// HashToken calcula el SHA-256 de un token opaco; solo el hash se guarda en la base de datos
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateToken valida y parsea un token JWT con la llave indicada por su kid
+func ValidateToken(tokenString string, keys *KeySet) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.keyFunc)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Los tokens con audiencia son de propósito específico (segundo factor)
+	// y nunca sirven como token de sesión
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 
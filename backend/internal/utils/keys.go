@@ -0,0 +1,275 @@
+// backend/internal/utils/keys.go
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// verificationKey llave pública aceptada para validar tokens, con el algoritmo que le corresponde
+type verificationKey struct {
+	kid    string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// KeySet llave privada con la que se firman los tokens y llaves públicas con las que se
+// validan. Durante una rotación conviven la llave nueva y las anteriores, identificadas por kid
+type KeySet struct {
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    crypto.Signer
+	keys          map[string]verificationKey
+	order         []string // orden estable para publicar el JWKS
+}
+
+// LoadKeySet carga la llave de firma y las llaves de verificación adicionales (PEM).
+// Las adicionales pueden ser públicas o privadas; de las privadas solo se usa su parte pública
+func LoadKeySet(privateKeyPath string, verificationPaths []string) (*KeySet, error) {
+	signer, err := readPrivateKey(privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := signingMethodFor(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", privateKeyPath, err)
+	}
+
+	ks := &KeySet{
+		signingMethod: method,
+		signingKey:    signer,
+		keys:          make(map[string]verificationKey),
+	}
+
+	ks.signingKID, err = ks.addVerificationKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range verificationPaths {
+		public, err := readPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := ks.addVerificationKey(public); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	return ks, nil
+}
+
+// GenerateDevKey crea una llave Ed25519 en la ruta indicada. Solo para desarrollo
+func GenerateDevKey(path string) error {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error al generar llave: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return fmt.Errorf("error al codificar llave: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error al crear directorio de llaves: %v", err)
+	}
+
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+}
+
+// sign firma los claims con la llave activa e incluye su kid en el encabezado
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signingMethod, claims)
+	token.Header["kid"] = ks.signingKID
+	return token.SignedString(ks.signingKey)
+}
+
+// keyFunc busca la llave de verificación por kid y exige el algoritmo de esa llave,
+// para que un token no pueda elegir otro algoritmo (p. ej. HS256 con la llave pública)
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("llave desconocida: %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
+
+// JWK llave pública en formato JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS conjunto de llaves públicas que otros servicios usan para validar los tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publica todas las llaves de verificación activas
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(ks.order))}
+
+	for _, kid := range ks.order {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+
+		switch public := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func (ks *KeySet) addVerificationKey(public crypto.PublicKey) (string, error) {
+	method, err := signingMethodFor(public)
+	if err != nil {
+		return "", err
+	}
+
+	kid, err := keyID(public)
+	if err != nil {
+		return "", err
+	}
+
+	if _, exists := ks.keys[kid]; !exists {
+		ks.keys[kid] = verificationKey{kid: kid, method: method, public: public}
+		ks.order = append(ks.order, kid)
+	}
+
+	return kid, nil
+}
+
+// signingMethodFor elige el algoritmo según el tipo de llave: EdDSA para Ed25519 y RS256 para RSA
+func signingMethodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch public := public.(type) {
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return nil, errors.New("las llaves RSA deben ser de al menos 2048 bits")
+		}
+		return jwt.SigningMethodRS256, nil
+	}
+	return nil, errors.New("tipo de llave no soportado; usa Ed25519 o RSA")
+}
+
+// keyID deriva el kid del hash de la llave pública, así no depende del nombre del archivo
+func keyID(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", fmt.Errorf("error al codificar llave pública: %v", err)
+	}
+
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: se esperaba una llave privada PEM, se encontró %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: error al leer llave privada: %v", path, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: tipo de llave no soportado", path)
+	}
+	return signer, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(block.Type, "PRIVATE KEY") {
+		signer, err := readPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: error al leer llave pública: %v", path, err)
+		}
+		return public, nil
+	case "RSA PUBLIC KEY":
+		public, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: error al leer llave pública: %v", path, err)
+		}
+		return public, nil
+	}
+
+	return nil, fmt.Errorf("%s: se esperaba una llave PEM, se encontró %q", path, block.Type)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer llave %s: %v", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no contiene una llave PEM", path)
+	}
+	return block, nil
+}
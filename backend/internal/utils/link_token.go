@@ -0,0 +1,102 @@
+// backend/internal/utils/link_token.go
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Audiencia de los tokens de enlaces de email; evita que se acepten como token de sesión
+const linkTokenAudience = "wenka-enlace-cita"
+
+var (
+	ErrLinkTokenInvalid = errors.New("enlace inválido")
+	ErrLinkTokenExpired = errors.New("enlace expirado")
+)
+
+// LinkClaims datos firmados dentro de un enlace de acción sobre una cita (p. ej. confirmar)
+type LinkClaims struct {
+	CitaID         int    `json:"cita_id"`
+	EspecialistaID int    `json:"especialista_id"`
+	Accion         string `json:"accion"`
+	PropuestaID    int    `json:"propuesta_id,omitempty"` // solo en enlaces para aceptar un horario propuesto
+	FechaHora      int64  `json:"fecha_hora"`             // horario de la cita al emitir el enlace; reagendar lo invalida
+	jwt.RegisteredClaims
+}
+
+// GenerateLinkToken firma un token de un solo uso para una acción sobre una cita.
+// propuestaID es 0 salvo en enlaces para aceptar un horario propuesto.
+// Retorna el token y su identificador único (jti) para registrar su uso
+func GenerateLinkToken(citaID, especialistaID, propuestaID int, accion string, fechaHora, expiresAt time.Time, secret string) (string, string, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := LinkClaims{
+		CitaID:         citaID,
+		EspecialistaID: especialistaID,
+		Accion:         accion,
+		PropuestaID:    propuestaID,
+		FechaHora:      fechaHora.Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Audience:  jwt.ClaimStrings{linkTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, jti, nil
+}
+
+// ValidateLinkToken verifica la firma, la audiencia y la expiración de un token de enlace
+func ValidateLinkToken(tokenString, secret string) (*LinkClaims, error) {
+	if tokenString == "" {
+		return nil, ErrLinkTokenInvalid
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &LinkClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	}, jwt.WithAudience(linkTokenAudience), jwt.WithExpirationRequired())
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrLinkTokenExpired
+		}
+		return nil, ErrLinkTokenInvalid
+	}
+
+	claims, ok := token.Claims.(*LinkClaims)
+	if !ok || !token.Valid || claims.ID == "" {
+		return nil, ErrLinkTokenInvalid
+	}
+
+	return claims, nil
+}
+
+// GenerateSecret genera un secreto aleatorio de 256 bits para firmar enlaces
+func GenerateSecret() (string, error) {
+	return randomHex(32)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error al generar valor aleatorio: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,54 @@
+// backend/internal/utils/mfa_token.go
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Audiencia del token parcial que entrega la contraseña; no sirve como token de sesión
+const mfaTokenAudience = "wenka-segundo-factor"
+
+var ErrMFATokenInvalid = errors.New("la verificación en dos pasos expiró; inicia sesión de nuevo")
+
+// MFAClaims datos del token parcial entre la contraseña y el segundo factor
+type MFAClaims struct {
+	UserID int `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateMFAToken firma el token parcial que se canjea por la sesión con el segundo factor
+func GenerateMFAToken(userID int, expiresAt time.Time, keys *KeySet) (string, error) {
+	claims := MFAClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{mfaTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return keys.sign(claims)
+}
+
+// ValidateMFAToken verifica la firma, la audiencia y la expiración del token parcial
+func ValidateMFAToken(tokenString string, keys *KeySet) (*MFAClaims, error) {
+	if tokenString == "" {
+		return nil, ErrMFATokenInvalid
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &MFAClaims{}, keys.keyFunc,
+		jwt.WithAudience(mfaTokenAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrMFATokenInvalid
+	}
+
+	claims, ok := token.Claims.(*MFAClaims)
+	if !ok || !token.Valid || claims.UserID == 0 {
+		return nil, ErrMFATokenInvalid
+	}
+
+	return claims, nil
+}
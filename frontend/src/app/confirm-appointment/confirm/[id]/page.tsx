@@ -23,20 +23,25 @@ export default function ConfirmAppointmentPage() {
           return;
         }
 
-        // Primero obtener los detalles de la cita
-        const appointment = await appointmentService.getAppointmentById(id);
-        setAppointmentDetails(appointment);
-
-        // Si ya está confirmada, mostrar mensaje
-        if (appointment.estado === 'confirmada') {
-          setStatus('success');
-          setMessage('Esta cita ya ha sido confirmada previamente');
+        // El enlace del email trae el token firmado; la cita se confirma al enviarlo por POST
+        const token = new URLSearchParams(window.location.search).get('token');
+        if (!token) {
+          setStatus('error');
+          setMessage('El enlace de confirmación no es válido');
           return;
         }
 
-        // Confirmar la cita
-        await appointmentService.confirmAppointment(id);
-        
+        await appointmentService.confirmAppointment(id, token);
+
+        // Los detalles solo se muestran si la sesión actual tiene acceso a la cita
+        // (paciente dueño o personal de la clínica)
+        try {
+          const appointment = await appointmentService.getAppointmentById(id);
+          setAppointmentDetails(appointment);
+        } catch {
+          setAppointmentDetails(null);
+        }
+
         setStatus('success');
         setMessage('¡Cita confirmada exitosamente!');
       } catch (error) {
@@ -192,6 +197,20 @@ export default function ConfirmAppointmentPage() {
                 </button>
               </div>
             )}
+
+            {!appointmentDetails && (
+              <div className="p-8 text-center">
+                <p className="text-gray-600 mb-6">
+                  Se notificó al paciente por email
+                </p>
+                <button
+                  onClick={() => router.push('/')}
+                  className="px-8 py-3 bg-gradient-to-r from-blue-600 to-purple-600 text-white rounded-xl font-semibold hover:shadow-lg transition-all"
+                >
+                  Volver al Inicio
+                </button>
+              </div>
+            )}
           </div>
         )}
 
@@ -0,0 +1,261 @@
+// app/confirm-appointment/respond/[id]/page.tsx
+'use client';
+
+import { useEffect, useState } from 'react';
+import { useParams, useRouter } from 'next/navigation';
+import { appointmentService } from '@/src/services/appointmentService';
+import type { AvailableSlot } from '@/src/types';
+
+const MAX_PROPOSALS = 3;
+
+export default function RespondAppointmentPage() {
+  const params = useParams();
+  const router = useRouter();
+  const [token, setToken] = useState('');
+  // ?proposal=... llega en el enlace del paciente para aceptar un horario propuesto
+  const [propuestaId, setPropuestaId] = useState<number | null>(null);
+  const [accion, setAccion] = useState<'proponer' | 'rechazar'>('proponer');
+  const [motivo, setMotivo] = useState('');
+  const [horarios, setHorarios] = useState<AvailableSlot[]>([{ fecha_cita: '', hora_cita: '' }]);
+  const [status, setStatus] = useState<'form' | 'sending' | 'success' | 'error'>('form');
+  const [message, setMessage] = useState('');
+
+  useEffect(() => {
+    const searchParams = new URLSearchParams(window.location.search);
+    const linkToken = searchParams.get('token');
+    if (!linkToken) {
+      setStatus('error');
+      setMessage('El enlace no es válido');
+      return;
+    }
+    setToken(linkToken);
+
+    const proposal = searchParams.get('proposal');
+    if (proposal) {
+      const parsed = parseInt(proposal);
+      if (isNaN(parsed)) {
+        setStatus('error');
+        setMessage('El enlace no es válido');
+        return;
+      }
+      setPropuestaId(parsed);
+    }
+  }, []);
+
+  const updateHorario = (index: number, field: keyof AvailableSlot, value: string) => {
+    setHorarios(prev => prev.map((h, i) => (i === index ? { ...h, [field]: value } : h)));
+  };
+
+  const addHorario = () => {
+    if (horarios.length < MAX_PROPOSALS) {
+      setHorarios(prev => [...prev, { fecha_cita: '', hora_cita: '' }]);
+    }
+  };
+
+  const removeHorario = (index: number) => {
+    setHorarios(prev => prev.filter((_, i) => i !== index));
+  };
+
+  // El horario se acepta con un clic del paciente y no al abrir la página, para que los
+  // antivirus del correo que visitan los enlaces no lo acepten por él
+  const handleAccept = async () => {
+    const id = parseInt(params.id as string);
+    if (isNaN(id) || propuestaId === null) {
+      setStatus('error');
+      setMessage('ID de cita inválido');
+      return;
+    }
+
+    setStatus('sending');
+    try {
+      const result = await appointmentService.acceptProposal(id, propuestaId, token);
+      setStatus('success');
+      setMessage(result);
+    } catch (error) {
+      setStatus('error');
+      setMessage(error instanceof Error ? error.message : 'Error al aceptar el horario propuesto');
+    }
+  };
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+
+    const id = parseInt(params.id as string);
+    if (isNaN(id)) {
+      setStatus('error');
+      setMessage('ID de cita inválido');
+      return;
+    }
+
+    const propuestos = horarios.filter(h => h.fecha_cita && h.hora_cita);
+    if (accion === 'proponer' && propuestos.length === 0) {
+      setMessage('Agrega al menos un horario');
+      return;
+    }
+
+    setStatus('sending');
+    try {
+      const result = await appointmentService.respondToAppointment(id, {
+        token,
+        accion,
+        motivo,
+        horarios: accion === 'proponer' ? propuestos : undefined,
+      });
+      setStatus('success');
+      setMessage(result);
+    } catch (error) {
+      setStatus('form');
+      setMessage(error instanceof Error ? error.message : 'Error al responder la cita');
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-blue-50 via-white to-purple-50 flex items-center justify-center p-4">
+      <div className="max-w-2xl w-full">
+        {(status === 'form' || status === 'sending') && propuestaId !== null && (
+          <div className="bg-white rounded-2xl shadow-2xl overflow-hidden">
+            <div className="bg-gradient-to-r from-blue-600 to-purple-600 p-8 text-center">
+              <h1 className="text-3xl font-bold text-white mb-2">
+                Horario Propuesto
+              </h1>
+              <p className="text-blue-50 text-lg">
+                Tu cita se reagendará al horario que elegiste en el email y quedará confirmada
+              </p>
+            </div>
+
+            <div className="p-8">
+              <button
+                type="button"
+                onClick={handleAccept}
+                disabled={status === 'sending'}
+                className="w-full py-4 bg-gradient-to-r from-blue-600 to-purple-600 text-white rounded-xl font-semibold hover:shadow-lg transition-all disabled:opacity-50"
+              >
+                {status === 'sending' ? 'Enviando...' : 'Aceptar este horario'}
+              </button>
+            </div>
+          </div>
+        )}
+
+        {(status === 'form' || status === 'sending') && propuestaId === null && (
+          <form onSubmit={handleSubmit} className="bg-white rounded-2xl shadow-2xl overflow-hidden">
+            <div className="bg-gradient-to-r from-blue-600 to-purple-600 p-8 text-center">
+              <h1 className="text-3xl font-bold text-white mb-2">
+                Responder Cita
+              </h1>
+              <p className="text-blue-50 text-lg">
+                Propón otro horario al paciente o rechaza la cita
+              </p>
+            </div>
+
+            <div className="p-8 space-y-6">
+              <div className="grid grid-cols-2 gap-4">
+                <button
+                  type="button"
+                  onClick={() => setAccion('proponer')}
+                  className={`py-3 rounded-xl font-semibold border-2 transition-all ${accion === 'proponer' ? 'border-blue-600 bg-blue-50 text-blue-700' : 'border-gray-200 text-gray-600'}`}
+                >
+                  Proponer otro horario
+                </button>
+                <button
+                  type="button"
+                  onClick={() => setAccion('rechazar')}
+                  className={`py-3 rounded-xl font-semibold border-2 transition-all ${accion === 'rechazar' ? 'border-red-500 bg-red-50 text-red-700' : 'border-gray-200 text-gray-600'}`}
+                >
+                  Rechazar cita
+                </button>
+              </div>
+
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-2">
+                  Motivo (se enviará al paciente)
+                </label>
+                <textarea
+                  value={motivo}
+                  onChange={e => setMotivo(e.target.value)}
+                  rows={3}
+                  className="w-full px-4 py-3 border border-gray-300 rounded-xl focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+                />
+              </div>
+
+              {accion === 'proponer' && (
+                <div className="space-y-3">
+                  <p className="text-sm font-medium text-gray-700">
+                    Horarios alternativos (máximo {MAX_PROPOSALS})
+                  </p>
+                  {horarios.map((horario, index) => (
+                    <div key={index} className="flex gap-3 items-center">
+                      <input
+                        type="date"
+                        value={horario.fecha_cita}
+                        onChange={e => updateHorario(index, 'fecha_cita', e.target.value)}
+                        className="flex-1 px-4 py-3 border border-gray-300 rounded-xl"
+                      />
+                      <input
+                        type="time"
+                        value={horario.hora_cita}
+                        onChange={e => updateHorario(index, 'hora_cita', e.target.value)}
+                        className="flex-1 px-4 py-3 border border-gray-300 rounded-xl"
+                      />
+                      {horarios.length > 1 && (
+                        <button
+                          type="button"
+                          onClick={() => removeHorario(index)}
+                          className="px-3 py-2 text-red-600 hover:bg-red-50 rounded-lg"
+                        >
+                          Quitar
+                        </button>
+                      )}
+                    </div>
+                  ))}
+                  {horarios.length < MAX_PROPOSALS && (
+                    <button
+                      type="button"
+                      onClick={addHorario}
+                      className="text-blue-600 font-semibold hover:underline"
+                    >
+                      + Agregar horario
+                    </button>
+                  )}
+                </div>
+              )}
+
+              {message && (
+                <p className="text-red-600 text-sm">{message}</p>
+              )}
+
+              <button
+                type="submit"
+                disabled={status === 'sending'}
+                className="w-full py-4 bg-gradient-to-r from-blue-600 to-purple-600 text-white rounded-xl font-semibold hover:shadow-lg transition-all disabled:opacity-50"
+              >
+                {status === 'sending' ? 'Enviando...' : accion === 'proponer' ? 'Enviar horarios al paciente' : 'Rechazar cita'}
+              </button>
+            </div>
+          </form>
+        )}
+
+        {(status === 'success' || status === 'error') && (
+          <div className="bg-white rounded-2xl shadow-2xl overflow-hidden">
+            <div className={`p-8 text-center bg-gradient-to-r ${status === 'success' ? 'from-green-500 to-emerald-600' : 'from-red-500 to-pink-600'}`}>
+              <h1 className="text-3xl font-bold text-white mb-2">
+                {status === 'error' ? 'Error' : propuestaId !== null ? 'Horario Aceptado' : 'Respuesta Enviada'}
+              </h1>
+              <p className="text-white text-lg">
+                {message}
+              </p>
+            </div>
+
+            <div className="p-8 text-center">
+              <button
+                onClick={() => router.push('/')}
+                className="px-8 py-3 bg-gradient-to-r from-blue-600 to-purple-600 text-white rounded-xl font-semibold hover:shadow-lg transition-all"
+              >
+                Volver al Inicio
+              </button>
+            </div>
+          </div>
+        )}
+      </div>
+    </div>
+  );
+}
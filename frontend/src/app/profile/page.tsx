@@ -0,0 +1,261 @@
+// app/profile/page.tsx
+'use client';
+
+import { useEffect, useState } from 'react';
+import { useRouter } from 'next/navigation';
+import { useAuth } from '@/src/contexts/AuthContext';
+import DependantsManager from '@/src/components/profile/DependantsManager';
+import type { Idioma, UpdateProfileData } from '@/src/types';
+
+const inputClass =
+  'w-full px-4 py-3 border-2 border-gray-200 rounded-xl focus:border-blue-500 outline-none';
+const buttonClass =
+  'px-6 py-3 bg-gradient-to-r from-blue-600 to-purple-600 text-white rounded-xl font-semibold hover:shadow-lg transition-all disabled:opacity-50';
+
+const errorMessage = (err: unknown) =>
+  err instanceof Error ? err.message : 'Ocurrió un error inesperado';
+
+// Perfil del usuario: datos personales, contraseña y eliminación de la cuenta
+export default function ProfilePage() {
+  const router = useRouter();
+  const { user, isLoading, updateProfile, changePassword, deleteAccount } = useAuth();
+
+  const [profile, setProfile] = useState({ nombre: '', apellido: '', telefono: '', email: '', idioma: 'es' as Idioma });
+  const [profilePassword, setProfilePassword] = useState('');
+  const [profileMessage, setProfileMessage] = useState('');
+
+  const [passwords, setPasswords] = useState({ actual: '', nuevo: '', confirmacion: '' });
+  const [passwordMessage, setPasswordMessage] = useState('');
+
+  const [deletePassword, setDeletePassword] = useState('');
+  const [deleteMessage, setDeleteMessage] = useState('');
+
+  const [isSaving, setIsSaving] = useState(false);
+
+  useEffect(() => {
+    if (!isLoading && !user) {
+      router.push('/');
+    }
+    if (user) {
+      setProfile({
+        nombre: user.nombre,
+        apellido: user.apellido,
+        telefono: user.telefono || '',
+        email: user.email,
+        idioma: user.idioma || 'es',
+      });
+    }
+  }, [user, isLoading, router]);
+
+  if (!user) {
+    return null;
+  }
+
+  const emailChanged = profile.email.trim().toLowerCase() !== user.email.toLowerCase();
+
+  const handleProfile = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setProfileMessage('');
+    setIsSaving(true);
+
+    const data: UpdateProfileData = {
+      nombre: profile.nombre,
+      apellido: profile.apellido,
+      telefono: profile.telefono,
+      idioma: profile.idioma,
+    };
+    if (emailChanged) {
+      data.email = profile.email;
+      data.password_actual = profilePassword;
+    }
+
+    try {
+      await updateProfile(data);
+      setProfilePassword('');
+      setProfileMessage(
+        emailChanged
+          ? 'Perfil actualizado. Te enviamos un email para verificar tu nueva dirección.'
+          : 'Perfil actualizado',
+      );
+    } catch (err) {
+      setProfileMessage(errorMessage(err));
+    } finally {
+      setIsSaving(false);
+    }
+  };
+
+  const handlePassword = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setPasswordMessage('');
+
+    if (passwords.nuevo !== passwords.confirmacion) {
+      setPasswordMessage('Las contraseñas no coinciden');
+      return;
+    }
+
+    setIsSaving(true);
+    try {
+      setPasswordMessage(await changePassword(passwords.actual, passwords.nuevo));
+      setPasswords({ actual: '', nuevo: '', confirmacion: '' });
+    } catch (err) {
+      setPasswordMessage(errorMessage(err));
+    } finally {
+      setIsSaving(false);
+    }
+  };
+
+  const handleDelete = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!confirm('¿Seguro que deseas eliminar tu cuenta? Esta acción no se puede deshacer.')) {
+      return;
+    }
+
+    setDeleteMessage('');
+    setIsSaving(true);
+    try {
+      await deleteAccount(deletePassword);
+      router.push('/');
+    } catch (err) {
+      setDeleteMessage(errorMessage(err));
+      setIsSaving(false);
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-blue-50 via-white to-purple-50 py-12 px-4">
+      <div className="max-w-2xl mx-auto space-y-8">
+        <div className="flex items-center justify-between">
+          <h1 className="text-3xl font-bold text-gray-800">Mi Perfil</h1>
+          <button
+            onClick={() => router.push('/dashboard')}
+            className="text-blue-600 hover:underline font-medium"
+          >
+            Volver al dashboard
+          </button>
+        </div>
+
+        <form onSubmit={handleProfile} className="bg-white rounded-2xl shadow-xl p-8 space-y-4">
+          <h2 className="text-xl font-semibold text-gray-800">Datos personales</h2>
+          <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+            <input
+              value={profile.nombre}
+              onChange={e => setProfile({ ...profile, nombre: e.target.value })}
+              required
+              placeholder="Nombre"
+              className={inputClass}
+            />
+            <input
+              value={profile.apellido}
+              onChange={e => setProfile({ ...profile, apellido: e.target.value })}
+              required
+              placeholder="Apellido"
+              className={inputClass}
+            />
+          </div>
+          <input
+            type="tel"
+            value={profile.telefono}
+            onChange={e => setProfile({ ...profile, telefono: e.target.value })}
+            placeholder="Teléfono"
+            className={inputClass}
+          />
+          <input
+            type="email"
+            value={profile.email}
+            onChange={e => setProfile({ ...profile, email: e.target.value })}
+            required
+            placeholder="tu@email.com"
+            className={inputClass}
+          />
+          <label className="block text-sm text-gray-700">
+            Idioma de los emails
+            <select
+              value={profile.idioma}
+              onChange={e => setProfile({ ...profile, idioma: e.target.value as Idioma })}
+              className={inputClass}
+            >
+              <option value="es">Español</option>
+              <option value="en">English</option>
+            </select>
+          </label>
+          {emailChanged && (
+            <input
+              type="password"
+              value={profilePassword}
+              onChange={e => setProfilePassword(e.target.value)}
+              required
+              placeholder="Contraseña actual para cambiar el email"
+              className={inputClass}
+            />
+          )}
+          {profileMessage && <p className="text-sm text-gray-700">{profileMessage}</p>}
+          <button type="submit" disabled={isSaving} className={buttonClass}>
+            Guardar cambios
+          </button>
+        </form>
+
+        <form onSubmit={handlePassword} className="bg-white rounded-2xl shadow-xl p-8 space-y-4">
+          <h2 className="text-xl font-semibold text-gray-800">Cambiar contraseña</h2>
+          <input
+            type="password"
+            value={passwords.actual}
+            onChange={e => setPasswords({ ...passwords, actual: e.target.value })}
+            required
+            placeholder="Contraseña actual"
+            className={inputClass}
+          />
+          <input
+            type="password"
+            value={passwords.nuevo}
+            onChange={e => setPasswords({ ...passwords, nuevo: e.target.value })}
+            required
+            minLength={6}
+            placeholder="Nueva contraseña"
+            className={inputClass}
+          />
+          <input
+            type="password"
+            value={passwords.confirmacion}
+            onChange={e => setPasswords({ ...passwords, confirmacion: e.target.value })}
+            required
+            minLength={6}
+            placeholder="Confirma la nueva contraseña"
+            className={inputClass}
+          />
+          {passwordMessage && <p className="text-sm text-gray-700">{passwordMessage}</p>}
+          <button type="submit" disabled={isSaving} className={buttonClass}>
+            Cambiar contraseña
+          </button>
+        </form>
+
+        {user.rol === 'paciente' && user.email_verificado && <DependantsManager />}
+
+        {user.rol === 'paciente' && (
+          <form onSubmit={handleDelete} className="bg-white rounded-2xl shadow-xl p-8 space-y-4 border-2 border-red-100">
+            <h2 className="text-xl font-semibold text-red-700">Eliminar cuenta</h2>
+            <p className="text-sm text-gray-600">
+              Se borrarán tus datos personales y los de tus familiares. El historial de citas de la
+              clínica se conserva sin información que los identifique.
+            </p>
+            <input
+              type="password"
+              value={deletePassword}
+              onChange={e => setDeletePassword(e.target.value)}
+              required
+              placeholder="Contraseña"
+              className={inputClass}
+            />
+            {deleteMessage && <p className="text-sm text-red-600">{deleteMessage}</p>}
+            <button
+              type="submit"
+              disabled={isSaving}
+              className="px-6 py-3 bg-red-600 text-white rounded-xl font-semibold hover:bg-red-700 transition-all disabled:opacity-50"
+            >
+              Eliminar mi cuenta
+            </button>
+          </form>
+        )}
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,132 @@
+// app/reset-password/page.tsx
+'use client';
+
+import { useEffect, useState } from 'react';
+import { useRouter } from 'next/navigation';
+import { API_ENDPOINTS } from '@/src/lib/api';
+
+// Sin token se pide el email; con el token del enlace se fija la nueva contraseña
+export default function ResetPasswordPage() {
+  const router = useRouter();
+  const [token, setToken] = useState('');
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [status, setStatus] = useState<'form' | 'sending' | 'success'>('form');
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState('');
+
+  useEffect(() => {
+    const searchParams = new URLSearchParams(window.location.search);
+    setToken(searchParams.get('token') || '');
+  }, []);
+
+  const postJSON = async (url: string, body: object) => {
+    const response = await fetch(url, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(body),
+    });
+
+    const data = await response.json().catch(() => ({}));
+    if (!response.ok) {
+      throw new Error(data.error || 'Ocurrió un error inesperado');
+    }
+    return data.message as string;
+  };
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+
+    if (token && password !== confirmPassword) {
+      setError('Las contraseñas no coinciden');
+      return;
+    }
+
+    setStatus('sending');
+    try {
+      const result = token
+        ? await postJSON(API_ENDPOINTS.auth.resetPassword, { token, password })
+        : await postJSON(API_ENDPOINTS.auth.forgotPassword, { email });
+      setMessage(result);
+      setStatus('success');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Ocurrió un error inesperado');
+      setStatus('form');
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-blue-50 via-white to-purple-50 flex items-center justify-center p-4">
+      <div className="max-w-md w-full bg-white rounded-2xl shadow-2xl overflow-hidden">
+        <div className="bg-gradient-to-r from-blue-600 to-purple-600 p-8 text-center">
+          <h1 className="text-3xl font-bold text-white mb-2">
+            {token ? 'Nueva Contraseña' : 'Recuperar Cuenta'}
+          </h1>
+          <p className="text-blue-50">
+            {token
+              ? 'Elige una nueva contraseña para tu cuenta'
+              : 'Te enviaremos un enlace para restablecer tu contraseña'}
+          </p>
+        </div>
+
+        {status === 'success' ? (
+          <div className="p-8 text-center">
+            <p className="text-gray-700 mb-6">{message}</p>
+            <button
+              onClick={() => router.push('/')}
+              className="px-8 py-3 bg-gradient-to-r from-blue-600 to-purple-600 text-white rounded-xl font-semibold hover:shadow-lg transition-all"
+            >
+              Volver al Inicio
+            </button>
+          </div>
+        ) : (
+          <form onSubmit={handleSubmit} className="p-8 space-y-5">
+            {token ? (
+              <>
+                <input
+                  type="password"
+                  value={password}
+                  onChange={e => setPassword(e.target.value)}
+                  required
+                  minLength={6}
+                  placeholder="Nueva contraseña"
+                  className="w-full px-4 py-3 border-2 border-gray-200 rounded-xl focus:border-blue-500 outline-none"
+                />
+                <input
+                  type="password"
+                  value={confirmPassword}
+                  onChange={e => setConfirmPassword(e.target.value)}
+                  required
+                  minLength={6}
+                  placeholder="Confirma la contraseña"
+                  className="w-full px-4 py-3 border-2 border-gray-200 rounded-xl focus:border-blue-500 outline-none"
+                />
+              </>
+            ) : (
+              <input
+                type="email"
+                value={email}
+                onChange={e => setEmail(e.target.value)}
+                required
+                placeholder="tu@email.com"
+                className="w-full px-4 py-3 border-2 border-gray-200 rounded-xl focus:border-blue-500 outline-none"
+              />
+            )}
+
+            {error && <p className="text-red-600 text-sm">{error}</p>}
+
+            <button
+              type="submit"
+              disabled={status === 'sending'}
+              className="w-full py-4 bg-gradient-to-r from-blue-600 to-purple-600 text-white rounded-xl font-semibold hover:shadow-lg transition-all disabled:opacity-50"
+            >
+              {status === 'sending' ? 'Enviando...' : token ? 'Guardar contraseña' : 'Enviar enlace'}
+            </button>
+          </form>
+        )}
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,80 @@
+// app/verify-email/page.tsx
+'use client';
+
+import { useEffect, useState } from 'react';
+import { useRouter } from 'next/navigation';
+import { API_ENDPOINTS } from '@/src/lib/api';
+
+export default function VerifyEmailPage() {
+  const router = useRouter();
+  const [status, setStatus] = useState<'loading' | 'success' | 'error'>('loading');
+  const [message, setMessage] = useState('');
+
+  useEffect(() => {
+    const verifyEmail = async () => {
+      const token = new URLSearchParams(window.location.search).get('token');
+      if (!token) {
+        setStatus('error');
+        setMessage('El enlace de verificación no es válido');
+        return;
+      }
+
+      try {
+        const response = await fetch(API_ENDPOINTS.auth.verifyEmail, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ token }),
+        });
+
+        const data = await response.json().catch(() => ({}));
+        if (!response.ok) {
+          throw new Error(data.error || 'No se pudo verificar el email');
+        }
+
+        setStatus('success');
+        setMessage(data.message);
+      } catch (error) {
+        setStatus('error');
+        setMessage(error instanceof Error ? error.message : 'No se pudo verificar el email');
+      }
+    };
+
+    verifyEmail();
+  }, []);
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-blue-50 via-white to-purple-50 flex items-center justify-center p-4">
+      <div className="max-w-md w-full bg-white rounded-2xl shadow-2xl overflow-hidden">
+        {status === 'loading' ? (
+          <div className="p-12 text-center">
+            <div className="w-20 h-20 border-4 border-blue-500 border-t-transparent rounded-full animate-spin mx-auto mb-6"></div>
+            <h2 className="text-2xl font-bold text-gray-800">Verificando email...</h2>
+          </div>
+        ) : (
+          <>
+            <div className={`p-8 text-center bg-gradient-to-r ${status === 'success' ? 'from-green-500 to-emerald-600' : 'from-red-500 to-pink-600'}`}>
+              <h1 className="text-3xl font-bold text-white mb-2">
+                {status === 'success' ? '¡Email Verificado!' : 'Error de Verificación'}
+              </h1>
+              <p className="text-white text-lg">{message}</p>
+            </div>
+
+            <div className="p-8 text-center">
+              {status === 'error' && (
+                <p className="text-gray-600 mb-6">
+                  Puedes solicitar un nuevo enlace desde tu panel
+                </p>
+              )}
+              <button
+                onClick={() => router.push(status === 'success' ? '/dashboard' : '/')}
+                className="px-8 py-3 bg-gradient-to-r from-blue-600 to-purple-600 text-white rounded-xl font-semibold hover:shadow-lg transition-all"
+              >
+                {status === 'success' ? 'Ir a mi Panel' : 'Volver al Inicio'}
+              </button>
+            </div>
+          </>
+        )}
+      </div>
+    </div>
+  );
+}
@@ -4,6 +4,8 @@
 import { useState } from 'react';
 import { useAuth } from '@/src/contexts/AuthContext';
 import { useRouter } from 'next/navigation';
+import TwoFactorStep from './TwoFactorStep';
+import type { MFAChallenge } from '@/src/types';
 
 interface LoginFormProps {
   onSwitchToRegister: () => void;
@@ -16,6 +18,7 @@ export default function LoginForm({ onSwitchToRegister, onClose }: LoginFormProp
   const [error, setError] = useState('');
   const [isLoading, setIsLoading] = useState(false);
   const [showPassword, setShowPassword] = useState(false);
+  const [challenge, setChallenge] = useState<MFAChallenge | null>(null);
   
   const { login } = useAuth();
   const router = useRouter();
@@ -26,16 +29,37 @@ export default function LoginForm({ onSwitchToRegister, onClose }: LoginFormProp
     setIsLoading(true);
 
     try {
-      await login(email, password);
+      const mfaChallenge = await login(email, password);
+      if (mfaChallenge) {
+        setChallenge(mfaChallenge);
+        return;
+      }
       onClose();
       router.push('/dashboard');
     } catch (err) {
-      setError('Email o contraseña incorrectos');
+      // El backend explica si la cuenta está bloqueada o si hay que esperar antes de reintentar
+      setError(err instanceof Error ? err.message : 'Email o contraseña incorrectos');
     } finally {
       setIsLoading(false);
     }
   };
 
+  if (challenge) {
+    return (
+      <TwoFactorStep
+        challenge={challenge}
+        onDone={() => {
+          onClose();
+          router.push('/dashboard');
+        }}
+        onCancel={() => {
+          setChallenge(null);
+          setPassword('');
+        }}
+      />
+    );
+  }
+
   return (
     <div className="w-full max-w-md mx-auto p-8">
       {/* Header con animación */}
@@ -132,6 +156,10 @@ export default function LoginForm({ onSwitchToRegister, onClose }: LoginFormProp
         <div className="flex justify-end">
           <button
             type="button"
+            onClick={() => {
+              onClose();
+              router.push('/reset-password');
+            }}
             className="text-sm text-blue-600 hover:text-blue-700 font-medium hover:underline"
           >
             ¿Olvidaste tu contraseña?
@@ -0,0 +1,142 @@
+// components/auth/TwoFactorStep.tsx
+'use client';
+
+import { useEffect, useState } from 'react';
+import { useAuth } from '@/src/contexts/AuthContext';
+import type { MFAChallenge, MFASetup } from '@/src/types';
+
+interface TwoFactorStepProps {
+  challenge: MFAChallenge;
+  onDone: () => void;
+  onCancel: () => void;
+}
+
+// Segundo paso del login: pide el código de la app o, si la cuenta de personal aún no
+// tiene 2FA, guía la activación y muestra los códigos de recuperación
+export default function TwoFactorStep({ challenge, onDone, onCancel }: TwoFactorStepProps) {
+  const { verifyMFA, setupMFA, enableMFA } = useAuth();
+  const [code, setCode] = useState('');
+  const [useRecovery, setUseRecovery] = useState(false);
+  const [setup, setSetup] = useState<MFASetup | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const [error, setError] = useState('');
+  const [isLoading, setIsLoading] = useState(false);
+
+  useEffect(() => {
+    if (!challenge.mfa_setup_required) return;
+
+    setupMFA(challenge.mfa_token)
+      .then(setSetup)
+      .catch(err => setError(err instanceof Error ? err.message : 'No se pudo iniciar la configuración'));
+  }, [challenge]);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+    setIsLoading(true);
+
+    try {
+      if (challenge.mfa_setup_required) {
+        setRecoveryCodes(await enableMFA(code, challenge.mfa_token));
+      } else {
+        await verifyMFA(challenge.mfa_token, useRecovery ? '' : code, useRecovery ? code : undefined);
+        onDone();
+      }
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Código incorrecto');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  if (recoveryCodes.length > 0) {
+    return (
+      <div className="w-full max-w-md mx-auto p-8">
+        <h2 className="text-2xl font-bold mb-2 text-gradient">Guarda tus códigos de recuperación</h2>
+        <p className="text-gray-500 mb-6">
+          Cada código sirve una sola vez si pierdes acceso a tu app de autenticación. No volverán a mostrarse.
+        </p>
+        <div className="grid grid-cols-2 gap-2 p-4 bg-gray-50 rounded-xl font-mono text-sm mb-6">
+          {recoveryCodes.map(recoveryCode => (
+            <span key={recoveryCode}>{recoveryCode}</span>
+          ))}
+        </div>
+        <button
+          onClick={onDone}
+          className="w-full bg-linear-to-r from-blue-600 via-cyan-500 to-blue-600 text-white py-3.5 rounded-xl font-semibold shadow-lg"
+        >
+          Ya los guardé, continuar
+        </button>
+      </div>
+    );
+  }
+
+  return (
+    <div className="w-full max-w-md mx-auto p-8">
+      <div className="text-center mb-6">
+        <h2 className="text-3xl font-bold mb-2 text-gradient">Verificación en dos pasos</h2>
+        <p className="text-gray-500">
+          {challenge.mfa_setup_required
+            ? 'Tu cuenta de personal requiere una app de autenticación (Google Authenticator, Authy...)'
+            : useRecovery
+              ? 'Ingresa uno de tus códigos de recuperación'
+              : 'Ingresa el código de 6 dígitos de tu app de autenticación'}
+        </p>
+      </div>
+
+      {challenge.mfa_setup_required && setup && (
+        <div className="mb-6 p-4 bg-blue-50 rounded-xl text-sm text-blue-900 space-y-2">
+          <p>Agrega la cuenta en tu app con esta clave y escribe el código que genere:</p>
+          <p className="font-mono break-all bg-white p-2 rounded">{setup.secret}</p>
+          <a href={setup.otpauth_uri} className="text-blue-600 hover:underline font-medium">
+            Abrir en la app de autenticación
+          </a>
+        </div>
+      )}
+
+      {error && (
+        <div className="mb-6 p-4 bg-red-50 border-l-4 border-red-500 rounded-r-lg">
+          <p className="text-red-700 text-sm font-medium">{error}</p>
+        </div>
+      )}
+
+      <form onSubmit={handleSubmit} className="space-y-5">
+        <input
+          type="text"
+          inputMode={useRecovery ? 'text' : 'numeric'}
+          autoComplete="one-time-code"
+          value={code}
+          onChange={e => setCode(e.target.value)}
+          required
+          placeholder={useRecovery ? 'xxxxx-xxxxx' : '123456'}
+          className="w-full px-4 py-3 border-2 border-gray-200 rounded-xl text-center text-xl tracking-widest focus:ring-4 focus:ring-blue-100 focus:border-blue-500 outline-none"
+        />
+
+        <button
+          type="submit"
+          disabled={isLoading || (challenge.mfa_setup_required && !setup)}
+          className="w-full bg-linear-to-r from-blue-600 via-cyan-500 to-blue-600 text-white py-3.5 rounded-xl font-semibold shadow-lg disabled:opacity-50"
+        >
+          {isLoading ? 'Verificando...' : challenge.mfa_setup_required ? 'Activar y continuar' : 'Verificar'}
+        </button>
+      </form>
+
+      <div className="flex justify-between mt-6 text-sm">
+        <button onClick={onCancel} className="text-gray-500 hover:underline">
+          Volver
+        </button>
+        {!challenge.mfa_setup_required && (
+          <button
+            onClick={() => {
+              setUseRecovery(!useRecovery);
+              setCode('');
+            }}
+            className="text-blue-600 hover:underline font-medium"
+          >
+            {useRecovery ? 'Usar código de la app' : 'Usar código de recuperación'}
+          </button>
+        )}
+      </div>
+    </div>
+  );
+}
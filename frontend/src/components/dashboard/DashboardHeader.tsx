@@ -1,8 +1,10 @@
 // components/dashboard/DashboardHeader.tsx
 'use client';
 
+import { useState } from 'react';
 import { useAuth } from '@/src/contexts/AuthContext';
 import { useRouter } from 'next/navigation';
+import { API_ENDPOINTS, fetchWithAuth, handleApiError } from '@/src/lib/api';
 import type { Appointment } from '@/src/types';
 
 interface DashboardHeaderProps {
@@ -11,11 +13,28 @@ interface DashboardHeaderProps {
 }
 
 export default function DashboardHeader({ appointments = [], onRefresh }: DashboardHeaderProps) {
-  const { user, logout } = useAuth();
+  const { user, logout, logoutAll } = useAuth();
   const router = useRouter();
+  const [verificationMessage, setVerificationMessage] = useState('');
 
-  const handleLogout = () => {
-    logout();
+  const handleLogout = async () => {
+    await logout();
+    router.push('/');
+  };
+
+  const handleResendVerification = async () => {
+    setVerificationMessage('');
+    try {
+      const response = await fetchWithAuth(API_ENDPOINTS.auth.resendVerification, { method: 'POST' });
+      const data = await response.json();
+      setVerificationMessage(data.message || data.error);
+    } catch (error) {
+      setVerificationMessage(handleApiError(error));
+    }
+  };
+
+  const handleLogoutAll = async () => {
+    await logoutAll();
     router.push('/');
   };
 
@@ -149,27 +168,55 @@ export default function DashboardHeader({ appointments = [], onRefresh }: Dashbo
             </p>
           </div>
           
-          <button
-            onClick={handleLogout}
-            className="group bg-white/10 hover:bg-white/20 backdrop-blur-xl px-6 py-3 rounded-lg transition-all duration-300 flex items-center gap-3 border border-white/20 hover:border-white/40 shadow-lg hover:shadow-2xl transform hover:scale-105"
-          >
-            <svg
-              className="w-5 h-5 group-hover:rotate-12 transition-transform"
-              fill="none"
-              stroke="currentColor"
-              viewBox="0 0 24 24"
+          <div className="flex flex-col items-start md:items-end gap-2">
+            <button
+              onClick={handleLogout}
+              className="group bg-white/10 hover:bg-white/20 backdrop-blur-xl px-6 py-3 rounded-lg transition-all duration-300 flex items-center gap-3 border border-white/20 hover:border-white/40 shadow-lg hover:shadow-2xl transform hover:scale-105"
+            >
+              <svg
+                className="w-5 h-5 group-hover:rotate-12 transition-transform"
+                fill="none"
+                stroke="currentColor"
+                viewBox="0 0 24 24"
+              >
+                <path
+                  strokeLinecap="round"
+                  strokeLinejoin="round"
+                  strokeWidth={2}
+                  d="M17 16l4-4m0 0l-4-4m4 4H7m6 4v1a3 3 0 01-3 3H6a3 3 0 01-3-3V7a3 3 0 013-3h4a3 3 0 013 3v1"
+                />
+              </svg>
+              <span className="font-medium">Cerrar Sesión</span>
+            </button>
+            <button
+              onClick={() => router.push('/profile')}
+              className="text-sm text-cyan-50 hover:text-white hover:underline transition-colors"
             >
-              <path
-                strokeLinecap="round"
-                strokeLinejoin="round"
-                strokeWidth={2}
-                d="M17 16l4-4m0 0l-4-4m4 4H7m6 4v1a3 3 0 01-3 3H6a3 3 0 01-3-3V7a3 3 0 013-3h4a3 3 0 013 3v1"
-              />
-            </svg>
-            <span className="font-medium">Cerrar Sesión</span>
-          </button>
+              Mi perfil
+            </button>
+            <button
+              onClick={handleLogoutAll}
+              className="text-sm text-cyan-50 hover:text-white hover:underline transition-colors"
+            >
+              Cerrar sesión en todos los dispositivos
+            </button>
+          </div>
         </div>
 
+        {user && !user.email_verificado && (
+          <div className="mb-8 p-4 bg-amber-400/20 border border-amber-200/40 rounded-xl flex flex-col md:flex-row md:items-center justify-between gap-3">
+            <p className="text-amber-50">
+              {verificationMessage || 'Verifica tu email para poder agendar citas. Revisa tu bandeja de entrada.'}
+            </p>
+            <button
+              onClick={handleResendVerification}
+              className="text-sm font-semibold text-white hover:underline whitespace-nowrap"
+            >
+              Reenviar email
+            </button>
+          </div>
+        )}
+
         <div className="grid grid-cols-1 md:grid-cols-3 gap-6">
           {statItems.map((stat, idx) => (
             <div key={idx} className="group relative bg-white/10 backdrop-blur-xl rounded-2xl p-6 border border-white/20 hover:bg-white/20 transition-all duration-300 hover:scale-105 hover:shadow-2xl">
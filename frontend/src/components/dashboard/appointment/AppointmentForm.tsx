@@ -1,10 +1,11 @@
 // components/dashboard/appointment/AppointmentForm.tsx
 'use client';
 
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 import { useAuth } from '@/src/contexts/AuthContext';
 import { services } from '@/src/constants/services';
-import type { CreateAppointmentRequest } from '@/src/types';
+import { patientService } from '@/src/services/patientService';
+import type { CreateAppointmentRequest, Paciente } from '@/src/types';
 
 interface AppointmentFormProps {
   selectedDate: Date;
@@ -30,6 +31,18 @@ export default function AppointmentForm({
     mensaje: '',
   });
 
+  // Un paciente agenda para sí o para sus dependientes; el personal captura los datos
+  const isPatient = user?.rol === 'paciente';
+  const [patients, setPatients] = useState<Paciente[]>([]);
+
+  useEffect(() => {
+    if (!isPatient) return;
+    patientService
+      .getPatients()
+      .then(setPatients)
+      .catch(err => console.error('Error al obtener pacientes:', err));
+  }, [isPatient]);
+
   const handleChange = (
     e: React.ChangeEvent<HTMLInputElement | HTMLSelectElement | HTMLTextAreaElement>
   ) => {
@@ -37,6 +50,11 @@ export default function AppointmentForm({
     setFormData(prev => ({ ...prev, [name]: value }));
   };
 
+  const handlePatientChange = (e: React.ChangeEvent<HTMLSelectElement>) => {
+    const id = Number(e.target.value);
+    setFormData(prev => ({ ...prev, paciente_id: id || undefined }));
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     await onSubmit(formData);
@@ -64,50 +82,77 @@ export default function AppointmentForm({
       )}
 
       <div className="grid sm:grid-cols-2 gap-5">
-        <div>
-          <label className="block text-sm font-semibold text-slate-700 mb-2">
-            Nombre Completo
-          </label>
-          <input
-            type="text"
-            name="nombre_paciente"
-            value={formData.nombre_paciente}
-            onChange={handleChange}
-            required
-            className="w-full px-4 py-3 bg-white border border-slate-300 rounded-xl focus:ring-2 focus:ring-blue-500/20 focus:border-blue-500 transition-all outline-none text-slate-700 placeholder:text-slate-400"
-            placeholder="Juan Pérez"
-          />
-        </div>
+        {isPatient ? (
+          <div className="sm:col-span-2">
+            <label className="block text-sm font-semibold text-slate-700 mb-2">
+              ¿Para quién es la cita?
+            </label>
+            <select
+              value={formData.paciente_id || ''}
+              onChange={handlePatientChange}
+              className="w-full px-4 py-3 bg-white border border-slate-300 rounded-xl focus:ring-2 focus:ring-blue-500/20 focus:border-blue-500 transition-all outline-none text-slate-700"
+            >
+              {patients.length === 0 && <option value="">Para mí</option>}
+              {patients.map(patient => (
+                <option key={patient.id} value={patient.parentesco === 'titular' ? '' : patient.id}>
+                  {patient.parentesco === 'titular'
+                    ? 'Para mí'
+                    : `${patient.nombre} ${patient.apellido_paterno} (${patient.parentesco})`}
+                </option>
+              ))}
+            </select>
+            <p className="mt-2 text-xs text-slate-500">
+              Registra a tus familiares desde tu perfil para agendar sus citas.
+            </p>
+          </div>
+        ) : (
+          <>
+            <div>
+              <label className="block text-sm font-semibold text-slate-700 mb-2">
+                Nombre Completo
+              </label>
+              <input
+                type="text"
+                name="nombre_paciente"
+                value={formData.nombre_paciente}
+                onChange={handleChange}
+                required
+                className="w-full px-4 py-3 bg-white border border-slate-300 rounded-xl focus:ring-2 focus:ring-blue-500/20 focus:border-blue-500 transition-all outline-none text-slate-700 placeholder:text-slate-400"
+                placeholder="Juan Pérez"
+              />
+            </div>
 
-        <div>
-          <label className="block text-sm font-semibold text-slate-700 mb-2">
-            Correo Electrónico
-          </label>
-          <input
-            type="email"
-            name="email"
-            value={formData.email}
-            onChange={handleChange}
-            required
-            className="w-full px-4 py-3 bg-white border border-slate-300 rounded-xl focus:ring-2 focus:ring-blue-500/20 focus:border-blue-500 transition-all outline-none text-slate-700 placeholder:text-slate-400"
-            placeholder="tu@email.com"
-          />
-        </div>
+            <div>
+              <label className="block text-sm font-semibold text-slate-700 mb-2">
+                Correo Electrónico
+              </label>
+              <input
+                type="email"
+                name="email"
+                value={formData.email}
+                onChange={handleChange}
+                required
+                className="w-full px-4 py-3 bg-white border border-slate-300 rounded-xl focus:ring-2 focus:ring-blue-500/20 focus:border-blue-500 transition-all outline-none text-slate-700 placeholder:text-slate-400"
+                placeholder="tu@email.com"
+              />
+            </div>
 
-        <div>
-          <label className="block text-sm font-semibold text-slate-700 mb-2">
-            Teléfono
-          </label>
-          <input
-            type="tel"
-            name="telefono"
-            value={formData.telefono}
-            onChange={handleChange}
-            required
-            className="w-full px-4 py-3 bg-white border border-slate-300 rounded-xl focus:ring-2 focus:ring-blue-500/20 focus:border-blue-500 transition-all outline-none text-slate-700 placeholder:text-slate-400"
-            placeholder="555-123-4567"
-          />
-        </div>
+            <div>
+              <label className="block text-sm font-semibold text-slate-700 mb-2">
+                Teléfono
+              </label>
+              <input
+                type="tel"
+                name="telefono"
+                value={formData.telefono}
+                onChange={handleChange}
+                required
+                className="w-full px-4 py-3 bg-white border border-slate-300 rounded-xl focus:ring-2 focus:ring-blue-500/20 focus:border-blue-500 transition-all outline-none text-slate-700 placeholder:text-slate-400"
+                placeholder="555-123-4567"
+              />
+            </div>
+          </>
+        )}
 
         <div>
           <label className="block text-sm font-semibold text-slate-700 mb-2">
@@ -39,8 +39,8 @@ export default function Header() {
     router.push('/dashboard');
   };
 
-  const handleLogout = () => {
-    logout();
+  const handleLogout = async () => {
+    await logout();
     router.push('/');
   };
 
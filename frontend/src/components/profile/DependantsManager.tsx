@@ -0,0 +1,193 @@
+// components/profile/DependantsManager.tsx
+'use client';
+
+import { useCallback, useEffect, useState } from 'react';
+import { patientService } from '@/src/services/patientService';
+import type { Paciente, PacienteData, Parentesco } from '@/src/types';
+
+const inputClass =
+  'w-full px-4 py-3 border-2 border-gray-200 rounded-xl focus:border-blue-500 outline-none';
+
+const parentescos: { value: Parentesco; label: string }[] = [
+  { value: 'hijo', label: 'Hijo(a)' },
+  { value: 'padre', label: 'Padre' },
+  { value: 'madre', label: 'Madre' },
+  { value: 'pareja', label: 'Pareja' },
+  { value: 'otro', label: 'Otro' },
+];
+
+const emptyForm: PacienteData = {
+  nombre: '',
+  apellido_paterno: '',
+  apellido_materno: '',
+  fecha_nacimiento: '',
+  sexo: 'O',
+  parentesco: 'hijo',
+  telefono: '',
+  email: '',
+};
+
+// Familiares cuyas citas administra la cuenta; cada uno tiene su propio expediente
+export default function DependantsManager() {
+  const [patients, setPatients] = useState<Paciente[]>([]);
+  const [form, setForm] = useState<PacienteData>(emptyForm);
+  const [editingId, setEditingId] = useState<number | null>(null);
+  const [showForm, setShowForm] = useState(false);
+  const [message, setMessage] = useState('');
+  const [isSaving, setIsSaving] = useState(false);
+
+  const loadPatients = useCallback(async () => {
+    try {
+      const data = await patientService.getPatients();
+      setPatients(data.filter(patient => patient.parentesco !== 'titular'));
+    } catch (err) {
+      setMessage(err instanceof Error ? err.message : 'Error al obtener pacientes');
+    }
+  }, []);
+
+  useEffect(() => {
+    loadPatients();
+  }, [loadPatients]);
+
+  const handleChange = (e: React.ChangeEvent<HTMLInputElement | HTMLSelectElement>) => {
+    const { name, value } = e.target;
+    setForm(prev => ({ ...prev, [name]: value }));
+  };
+
+  const startEdit = (patient: Paciente) => {
+    setForm({
+      nombre: patient.nombre,
+      apellido_paterno: patient.apellido_paterno,
+      apellido_materno: patient.apellido_materno || '',
+      fecha_nacimiento: patient.fecha_nacimiento.split('T')[0],
+      sexo: patient.sexo,
+      parentesco: patient.parentesco,
+      telefono: patient.telefono || '',
+      email: patient.email || '',
+    });
+    setEditingId(patient.id);
+    setShowForm(true);
+    setMessage('');
+  };
+
+  const closeForm = () => {
+    setForm(emptyForm);
+    setEditingId(null);
+    setShowForm(false);
+  };
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setMessage('');
+    setIsSaving(true);
+    try {
+      if (editingId) {
+        await patientService.updatePatient(editingId, form);
+      } else {
+        await patientService.createPatient(form);
+      }
+      closeForm();
+      await loadPatients();
+    } catch (err) {
+      setMessage(err instanceof Error ? err.message : 'Error al guardar al paciente');
+    } finally {
+      setIsSaving(false);
+    }
+  };
+
+  const handleRemove = async (patient: Paciente) => {
+    if (!confirm(`¿Quitar a ${patient.nombre} de tu cuenta? Su historial de citas se conserva.`)) {
+      return;
+    }
+
+    setMessage('');
+    try {
+      await patientService.removePatient(patient.id);
+      await loadPatients();
+    } catch (err) {
+      setMessage(err instanceof Error ? err.message : 'Error al eliminar al paciente');
+    }
+  };
+
+  return (
+    <div className="bg-white rounded-2xl shadow-xl p-8 space-y-4">
+      <div className="flex items-center justify-between">
+        <h2 className="text-xl font-semibold text-gray-800">Familiares</h2>
+        {!showForm && (
+          <button onClick={() => setShowForm(true)} className="text-blue-600 hover:underline font-medium">
+            Agregar familiar
+          </button>
+        )}
+      </div>
+      <p className="text-sm text-gray-600">
+        Agenda citas para tus hijos o familiares sin mezclar sus datos con los tuyos.
+      </p>
+
+      {patients.length > 0 && (
+        <ul className="divide-y divide-gray-100">
+          {patients.map(patient => (
+            <li key={patient.id} className="py-3 flex items-center justify-between gap-4">
+              <div>
+                <p className="font-medium text-gray-800">
+                  {patient.nombre} {patient.apellido_paterno} {patient.apellido_materno}
+                </p>
+                <p className="text-sm text-gray-500 capitalize">{patient.parentesco}</p>
+              </div>
+              <div className="flex gap-4 text-sm">
+                <button onClick={() => startEdit(patient)} className="text-blue-600 hover:underline">
+                  Editar
+                </button>
+                <button onClick={() => handleRemove(patient)} className="text-red-600 hover:underline">
+                  Quitar
+                </button>
+              </div>
+            </li>
+          ))}
+        </ul>
+      )}
+
+      {showForm && (
+        <form onSubmit={handleSubmit} className="space-y-4 pt-2">
+          <div className="grid grid-cols-1 md:grid-cols-3 gap-4">
+            <input name="nombre" value={form.nombre} onChange={handleChange} required placeholder="Nombre" className={inputClass} />
+            <input name="apellido_paterno" value={form.apellido_paterno} onChange={handleChange} required placeholder="Apellido paterno" className={inputClass} />
+            <input name="apellido_materno" value={form.apellido_materno} onChange={handleChange} placeholder="Apellido materno" className={inputClass} />
+          </div>
+          <div className="grid grid-cols-1 md:grid-cols-3 gap-4">
+            <input type="date" name="fecha_nacimiento" value={form.fecha_nacimiento} onChange={handleChange} required className={inputClass} />
+            <select name="sexo" value={form.sexo} onChange={handleChange} className={inputClass}>
+              <option value="F">Femenino</option>
+              <option value="M">Masculino</option>
+              <option value="O">Otro</option>
+            </select>
+            <select name="parentesco" value={form.parentesco} onChange={handleChange} className={inputClass}>
+              {parentescos.map(parentesco => (
+                <option key={parentesco.value} value={parentesco.value}>
+                  {parentesco.label}
+                </option>
+              ))}
+            </select>
+          </div>
+          <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+            <input type="tel" name="telefono" value={form.telefono} onChange={handleChange} placeholder="Teléfono (opcional)" className={inputClass} />
+            <input type="email" name="email" value={form.email} onChange={handleChange} placeholder="Email (opcional)" className={inputClass} />
+          </div>
+          <div className="flex gap-4">
+            <button
+              type="submit"
+              disabled={isSaving}
+              className="px-6 py-3 bg-gradient-to-r from-blue-600 to-purple-600 text-white rounded-xl font-semibold hover:shadow-lg transition-all disabled:opacity-50"
+            >
+              {editingId ? 'Guardar cambios' : 'Agregar'}
+            </button>
+            <button type="button" onClick={closeForm} className="px-6 py-3 text-gray-600 hover:underline">
+              Cancelar
+            </button>
+          </div>
+        </form>
+      )}
+
+      {message && <p className="text-sm text-red-600">{message}</p>}
+    </div>
+  );
+}
@@ -2,16 +2,38 @@
 'use client';
 
 import { createContext, useContext, useState, useEffect, ReactNode } from 'react';
-import type { User, RegisterData } from '@/src/types';
-import { API_ENDPOINTS, fetchWithAuth, handleApiError } from '@/src/lib/api';
+import type {
+  User,
+  RegisterData,
+  AuthResponse,
+  MFAChallenge,
+  MFASetup,
+  MFAEnableResponse,
+  UpdateProfileData,
+} from '@/src/types';
+import {
+  API_ENDPOINTS,
+  fetchWithAuth,
+  handleApiError,
+  clearTokens,
+  TOKEN_KEY,
+  REFRESH_TOKEN_KEY,
+} from '@/src/lib/api';
 
 interface AuthContextType {
   user: User | null;
   isAuthenticated: boolean;
   isLoading: boolean;
-  login: (email: string, password: string) => Promise<void>;
+  login: (email: string, password: string) => Promise<MFAChallenge | null>;
+  verifyMFA: (mfaToken: string, code: string, recoveryCode?: string) => Promise<void>;
+  setupMFA: (mfaToken?: string) => Promise<MFASetup>;
+  enableMFA: (code: string, mfaToken?: string) => Promise<string[]>;
   register: (data: RegisterData) => Promise<void>;
-  logout: () => void;
+  logout: () => Promise<void>;
+  logoutAll: () => Promise<void>;
+  updateProfile: (data: UpdateProfileData) => Promise<void>;
+  changePassword: (currentPassword: string, newPassword: string) => Promise<string>;
+  deleteAccount: (password: string) => Promise<void>;
 }
 
 const AuthContext = createContext<AuthContextType | undefined>(undefined);
@@ -23,7 +45,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   useEffect(() => {
     // Verificar si hay un token guardado al cargar
     const checkAuth = async () => {
-      const token = localStorage.getItem('clinica_token');
+      const token = localStorage.getItem(TOKEN_KEY);
       if (token) {
         try {
           const response = await fetchWithAuth(API_ENDPOINTS.auth.me);
@@ -32,11 +54,11 @@ export function AuthProvider({ children }: { children: ReactNode }) {
             const userData = await response.json();
             setUser(userData);
           } else {
-            localStorage.removeItem('clinica_token');
+            clearTokens();
           }
         } catch (error) {
           console.error('Error checking auth:', error);
-          localStorage.removeItem('clinica_token');
+          clearTokens();
         }
       }
       setIsLoading(false);
@@ -45,6 +67,13 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     checkAuth();
   }, []);
 
+  const startSession = (data: AuthResponse) => {
+    localStorage.setItem(TOKEN_KEY, data.token);
+    localStorage.setItem(REFRESH_TOKEN_KEY, data.refresh_token);
+    setUser(data.user);
+  };
+
+  // Con 2FA el login no abre sesión: retorna el reto que se completa con verifyMFA
   const login = async (email: string, password: string) => {
     try {
       const response = await fetch(API_ENDPOINTS.auth.login, {
@@ -61,14 +90,60 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       }
 
       const data = await response.json();
-      localStorage.setItem('clinica_token', data.token);
-      setUser(data.user);
+      if (data.mfa_required) {
+        return data as MFAChallenge;
+      }
+
+      startSession(data);
+      return null;
     } catch (error) {
       console.error('Login error:', error);
       throw error;
     }
   };
 
+  // Peticiones de 2FA: con sesión usan el token de acceso; durante el login, el mfa_token
+  const postMFA = async (url: string, body: object, withSession: boolean) => {
+    const options = { method: 'POST', body: JSON.stringify(body) };
+    const response = withSession
+      ? await fetchWithAuth(url, options)
+      : await fetch(url, { ...options, headers: { 'Content-Type': 'application/json' } });
+
+    const data = await response.json();
+    if (!response.ok) {
+      throw new Error(data.error || 'Error en la verificación en dos pasos');
+    }
+    return data;
+  };
+
+  const verifyMFA = async (mfaToken: string, code: string, recoveryCode?: string) => {
+    const data = await postMFA(
+      API_ENDPOINTS.auth.mfaVerify,
+      recoveryCode ? { mfa_token: mfaToken, recovery_code: recoveryCode } : { mfa_token: mfaToken, code },
+      false,
+    );
+    startSession(data);
+  };
+
+  const setupMFA = async (mfaToken?: string): Promise<MFASetup> => {
+    return postMFA(API_ENDPOINTS.auth.mfaSetup, { mfa_token: mfaToken }, !mfaToken);
+  };
+
+  const enableMFA = async (code: string, mfaToken?: string) => {
+    const data: MFAEnableResponse = await postMFA(
+      API_ENDPOINTS.auth.mfaEnable,
+      { mfa_token: mfaToken, code },
+      !mfaToken,
+    );
+
+    if (data.session) {
+      startSession(data.session);
+    } else if (user) {
+      setUser({ ...user, totp_activo: true });
+    }
+    return data.recovery_codes;
+  };
+
   const register = async (registerData: RegisterData) => {
     try {
       const response = await fetch(API_ENDPOINTS.auth.register, {
@@ -76,7 +151,11 @@ export function AuthProvider({ children }: { children: ReactNode }) {
         headers: {
           'Content-Type': 'application/json',
         },
-        body: JSON.stringify(registerData),
+        // Los emails se envían en el idioma del navegador; se puede cambiar desde el perfil
+        body: JSON.stringify({
+          idioma: navigator.language.toLowerCase().startsWith('en') ? 'en' : 'es',
+          ...registerData,
+        }),
       });
 
       if (!response.ok) {
@@ -85,16 +164,58 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       }
 
       const data = await response.json();
-      localStorage.setItem('clinica_token', data.token);
-      setUser(data.user);
+      startSession(data);
     } catch (error) {
       console.error('Register error:', error);
       throw error;
     }
   };
 
-  const logout = () => {
-    localStorage.removeItem('clinica_token');
+  // Cierra la sesión en el servidor para que el refresh token deje de servir
+  const endSession = async (url: string) => {
+    try {
+      await fetchWithAuth(url, { method: 'POST' });
+    } catch (error) {
+      console.error('Logout error:', error);
+    } finally {
+      clearTokens();
+      setUser(null);
+    }
+  };
+
+  const logout = () => endSession(API_ENDPOINTS.auth.logout);
+
+  // Cierra la sesión en todos los dispositivos
+  const logoutAll = () => endSession(API_ENDPOINTS.auth.logoutAll);
+
+  // Peticiones de la cuenta con sesión; lanzan el mensaje de error del servidor
+  const sendAccount = async (url: string, method: string, body: object) => {
+    const response = await fetchWithAuth(url, { method, body: JSON.stringify(body) });
+    const data = await response.json().catch(() => ({}));
+    if (!response.ok) {
+      throw new Error(data.error || 'Error al actualizar la cuenta');
+    }
+    return data;
+  };
+
+  // Si cambia el email, el usuario vuelve a quedar sin verificar
+  const updateProfile = async (profileData: UpdateProfileData) => {
+    const data: User = await sendAccount(API_ENDPOINTS.auth.me, 'PATCH', profileData);
+    setUser(data);
+  };
+
+  // Las demás sesiones se cierran en el servidor; la actual sigue abierta
+  const changePassword = async (currentPassword: string, newPassword: string) => {
+    const data = await sendAccount(API_ENDPOINTS.auth.changePassword, 'POST', {
+      password_actual: currentPassword,
+      password_nuevo: newPassword,
+    });
+    return data.message as string;
+  };
+
+  const deleteAccount = async (password: string) => {
+    await sendAccount(API_ENDPOINTS.auth.me, 'DELETE', { password });
+    clearTokens();
     setUser(null);
   };
 
@@ -105,8 +226,15 @@ export function AuthProvider({ children }: { children: ReactNode }) {
         isAuthenticated: !!user,
         isLoading,
         login,
+        verifyMFA,
+        setupMFA,
+        enableMFA,
         register,
         logout,
+        logoutAll,
+        updateProfile,
+        changePassword,
+        deleteAccount,
       }}
     >
       {children}
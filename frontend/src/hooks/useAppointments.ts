@@ -60,10 +60,10 @@ export function useAppointments() {
     }
   }, [fetchAppointments]);
 
-  const confirmAppointment = useCallback(async (id: number) => {
+  const confirmAppointment = useCallback(async (id: number, token: string) => {
     setError('');
     try {
-      await appointmentService.confirmAppointment(id);
+      await appointmentService.confirmAppointment(id, token);
       // Actualizar el estado local inmediatamente
       setAppointments(prev =>
         prev.map(apt =>
@@ -8,6 +8,19 @@ export const API_ENDPOINTS = {
     register: `${API_BASE_URL}/api/auth/register`,
     login: `${API_BASE_URL}/api/auth/login`,
     me: `${API_BASE_URL}/api/auth/me`,
+    changePassword: `${API_BASE_URL}/api/auth/change-password`,
+    refresh: `${API_BASE_URL}/api/auth/refresh`,
+    logout: `${API_BASE_URL}/api/auth/logout`,
+    logoutAll: `${API_BASE_URL}/api/auth/logout-all`,
+    sessions: `${API_BASE_URL}/api/auth/sessions`,
+    forgotPassword: `${API_BASE_URL}/api/auth/forgot-password`,
+    resetPassword: `${API_BASE_URL}/api/auth/reset-password`,
+    verifyEmail: `${API_BASE_URL}/api/auth/verify-email`,
+    resendVerification: `${API_BASE_URL}/api/auth/resend-verification`,
+    mfaVerify: `${API_BASE_URL}/api/auth/2fa/verify`,
+    mfaSetup: `${API_BASE_URL}/api/auth/2fa/setup`,
+    mfaEnable: `${API_BASE_URL}/api/auth/2fa/enable`,
+    mfaDisable: `${API_BASE_URL}/api/auth/2fa/disable`,
   },
   // Appointments endpoints
   appointments: {
@@ -16,13 +29,69 @@ export const API_ENDPOINTS = {
     getById: (id: number) => `${API_BASE_URL}/api/appointments/${id}`,
     confirm: (id: number) => `${API_BASE_URL}/api/appointments/${id}/confirm`,
     cancel: (id: number) => `${API_BASE_URL}/api/appointments/${id}`,
+    reschedule: (id: number) => `${API_BASE_URL}/api/appointments/${id}/reschedule`,
+    respond: (id: number) => `${API_BASE_URL}/api/appointments/${id}/respond`,
+    acceptProposal: (id: number, propuestaId: number) => `${API_BASE_URL}/api/appointments/${id}/proposals/${propuestaId}/accept`,
+  },
+  // Expedientes de la cuenta (titular y dependientes)
+  patients: {
+    list: `${API_BASE_URL}/api/patients`,
+    create: `${API_BASE_URL}/api/patients`,
+    update: (id: number) => `${API_BASE_URL}/api/patients/${id}`,
+    remove: (id: number) => `${API_BASE_URL}/api/patients/${id}`,
+  },
+  // Availability endpoints
+  availability: (servicio: string, from: string, to?: string) => {
+    const params = new URLSearchParams({ servicio, from });
+    if (to) params.set('to', to);
+    return `${API_BASE_URL}/api/availability?${params.toString()}`;
   },
   // Health check
   health: `${API_BASE_URL}/health`,
 };
 
-export const fetchWithAuth = async (url: string, options: RequestInit = {}) => {
-  const token = localStorage.getItem('clinica_token');
+export const TOKEN_KEY = 'clinica_token';
+export const REFRESH_TOKEN_KEY = 'clinica_refresh_token';
+
+export const clearTokens = () => {
+  localStorage.removeItem(TOKEN_KEY);
+  localStorage.removeItem(REFRESH_TOKEN_KEY);
+};
+
+// Peticiones de refresh en curso compartidas, para no rotar el mismo token dos veces
+let refreshPromise: Promise<boolean> | null = null;
+
+const refreshSession = (): Promise<boolean> => {
+  if (!refreshPromise) {
+    refreshPromise = (async () => {
+      const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY);
+      if (!refreshToken) return false;
+
+      try {
+        const response = await fetch(API_ENDPOINTS.auth.refresh, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ refresh_token: refreshToken }),
+        });
+
+        if (!response.ok) return false;
+
+        const data = await response.json();
+        localStorage.setItem(TOKEN_KEY, data.token);
+        localStorage.setItem(REFRESH_TOKEN_KEY, data.refresh_token);
+        return true;
+      } catch {
+        return false;
+      }
+    })().finally(() => {
+      refreshPromise = null;
+    });
+  }
+  return refreshPromise;
+};
+
+const sendWithToken = (url: string, options: RequestInit) => {
+  const token = localStorage.getItem(TOKEN_KEY);
   
   const headers: Record<string, string> = {
     'Content-Type': 'application/json',
@@ -40,16 +109,25 @@ export const fetchWithAuth = async (url: string, options: RequestInit = {}) => {
     headers['Authorization'] = `Bearer ${token}`;
   }
 
-  const response = await fetch(url, {
+  return fetch(url, {
     ...options,
     headers,
     mode: 'cors',
     credentials: 'include',
   });
+};
+
+export const fetchWithAuth = async (url: string, options: RequestInit = {}) => {
+  let response = await sendWithToken(url, options);
+
+  // El access token dura poco: intentar renovarlo una vez y repetir la petición
+  if (response.status === 401 && (await refreshSession())) {
+    response = await sendWithToken(url, options);
+  }
 
-  // If unauthorized, clear token and redirect
+  // If still unauthorized, clear tokens and redirect
   if (response.status === 401) {
-    localStorage.removeItem('clinica_token');
+    clearTokens();
     if (typeof window !== 'undefined') {
       window.location.href = '/';
     }
@@ -1,7 +1,7 @@
 // services/appointmentService.ts
 
 import { API_ENDPOINTS, fetchWithAuth, parseApiResponse, handleApiError } from '@/src/lib/api';
-import type { Appointment, CreateAppointmentRequest, ApiError } from '@/src/types';
+import type { Appointment, CreateAppointmentRequest, RespondAppointmentRequest, SpecialistAvailability, ApiError } from '@/src/types';
 
 class AppointmentService {
   async getAppointments(): Promise<Appointment[]> {
@@ -53,10 +53,11 @@ class AppointmentService {
     }
   }
 
-  async confirmAppointment(id: number): Promise<void> {
+  async confirmAppointment(id: number, token: string): Promise<void> {
     try {
       const response = await fetchWithAuth(API_ENDPOINTS.appointments.confirm(id), {
         method: 'POST',
+        body: JSON.stringify({ token }),
       });
 
       if (!response.ok) {
@@ -68,6 +69,76 @@ class AppointmentService {
     }
   }
 
+  async respondToAppointment(id: number, request: RespondAppointmentRequest): Promise<string> {
+    try {
+      const response = await fetchWithAuth(API_ENDPOINTS.appointments.respond(id), {
+        method: 'POST',
+        body: JSON.stringify(request),
+      });
+
+      if (!response.ok) {
+        const error = await parseApiResponse(response);
+        throw new Error(error?.error || 'Error al responder la cita');
+      }
+
+      const data = await response.json();
+      return data.message;
+    } catch (error) {
+      throw new Error(handleApiError(error));
+    }
+  }
+
+  async acceptProposal(id: number, propuestaId: number, token: string): Promise<string> {
+    try {
+      const response = await fetchWithAuth(API_ENDPOINTS.appointments.acceptProposal(id, propuestaId), {
+        method: 'POST',
+        body: JSON.stringify({ token }),
+      });
+
+      if (!response.ok) {
+        const error = await parseApiResponse(response);
+        throw new Error(error?.error || 'Error al aceptar el horario propuesto');
+      }
+
+      const data = await response.json();
+      return data.message;
+    } catch (error) {
+      throw new Error(handleApiError(error));
+    }
+  }
+
+  async getAvailability(servicio: string, from: string, to?: string): Promise<SpecialistAvailability[]> {
+    try {
+      const response = await fetchWithAuth(API_ENDPOINTS.availability(servicio, from, to));
+
+      if (!response.ok) {
+        const error = await parseApiResponse(response);
+        throw new Error(error?.error || 'Error al obtener horarios disponibles');
+      }
+
+      const data = await response.json();
+      return Array.isArray(data) ? data : [];
+    } catch (error) {
+      throw new Error(handleApiError(error));
+    }
+  }
+
+  async rescheduleAppointment(id: number, fechaCita: string, horaCita: string): Promise<void> {
+    try {
+      const response = await fetchWithAuth(API_ENDPOINTS.appointments.reschedule(id), {
+        method: 'PATCH',
+        body: JSON.stringify({ fecha_cita: fechaCita, hora_cita: horaCita }),
+      });
+
+      if (!response.ok) {
+        const error = await parseApiResponse(response);
+        throw new Error(error?.error || 'Error al reagendar la cita');
+      }
+    } catch (error) {
+      throw new Error(handleApiError(error));
+    }
+  }
+
   async cancelAppointment(id: number): Promise<void> {
     try {
       const response = await fetchWithAuth(API_ENDPOINTS.appointments.cancel(id), {
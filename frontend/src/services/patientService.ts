@@ -0,0 +1,76 @@
+// services/patientService.ts
+
+import { API_ENDPOINTS, fetchWithAuth, parseApiResponse, handleApiError } from '@/src/lib/api';
+import type { Paciente, PacienteData } from '@/src/types';
+
+// Expedientes de la cuenta: el del titular y los de sus dependientes
+class PatientService {
+  async getPatients(): Promise<Paciente[]> {
+    try {
+      const response = await fetchWithAuth(API_ENDPOINTS.patients.list);
+
+      if (!response.ok) {
+        const error = await parseApiResponse(response);
+        throw new Error(error?.error || 'Error al obtener pacientes');
+      }
+
+      const data = await response.json();
+      return Array.isArray(data) ? data : [];
+    } catch (error) {
+      throw new Error(handleApiError(error));
+    }
+  }
+
+  async createPatient(patient: PacienteData): Promise<Paciente> {
+    try {
+      const response = await fetchWithAuth(API_ENDPOINTS.patients.create, {
+        method: 'POST',
+        body: JSON.stringify(patient),
+      });
+
+      if (!response.ok) {
+        const error = await parseApiResponse(response);
+        throw new Error(error?.error || 'Error al registrar al paciente');
+      }
+
+      return await response.json();
+    } catch (error) {
+      throw new Error(handleApiError(error));
+    }
+  }
+
+  async updatePatient(id: number, patient: PacienteData): Promise<Paciente> {
+    try {
+      const response = await fetchWithAuth(API_ENDPOINTS.patients.update(id), {
+        method: 'PUT',
+        body: JSON.stringify(patient),
+      });
+
+      if (!response.ok) {
+        const error = await parseApiResponse(response);
+        throw new Error(error?.error || 'Error al actualizar al paciente');
+      }
+
+      return await response.json();
+    } catch (error) {
+      throw new Error(handleApiError(error));
+    }
+  }
+
+  async removePatient(id: number): Promise<void> {
+    try {
+      const response = await fetchWithAuth(API_ENDPOINTS.patients.remove(id), {
+        method: 'DELETE',
+      });
+
+      if (!response.ok) {
+        const error = await parseApiResponse(response);
+        throw new Error(error?.error || 'Error al eliminar al paciente');
+      }
+    } catch (error) {
+      throw new Error(handleApiError(error));
+    }
+  }
+}
+
+export const patientService = new PatientService();
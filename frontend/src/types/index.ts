@@ -6,18 +6,63 @@ export interface User {
   apellido: string;
   email: string;
   telefono?: string;
+  rol: UserRole;
+  especialista_id?: number;
+  paciente_id?: number;
+  email_verificado: boolean;
+  idioma: Idioma;
+  totp_activo: boolean;
   created_at?: string;
 }
 
+export type UserRole = 'paciente' | 'especialista' | 'recepcion' | 'admin';
+
+// Idioma en que el usuario recibe los emails
+export type Idioma = 'es' | 'en';
+
+export interface AuthResponse {
+  token: string;
+  refresh_token: string;
+  expires_in: number;
+  user: User;
+}
+
+export interface MFAChallenge {
+  mfa_required: true;
+  mfa_token: string;
+  mfa_setup_required: boolean;
+  expires_in: number;
+}
+
+export interface MFASetup {
+  secret: string;
+  otpauth_uri: string;
+}
+
+export interface MFAEnableResponse {
+  recovery_codes: string[];
+  session?: AuthResponse;
+}
+
+export interface Session {
+  id: string;
+  dispositivo: string;
+  ip: string;
+  expira_en: string;
+  ultimo_uso: string;
+}
+
 export interface RegisterData {
   nombre: string;
   apellido: string;
   email: string;
   password: string;
   telefono?: string;
+  idioma?: Idioma;
 }
 
 export interface CreateAppointmentRequest {
+  paciente_id?: number;
   nombre_paciente: string;
   telefono: string;
   email: string;
@@ -25,10 +70,13 @@ export interface CreateAppointmentRequest {
   fecha_cita: string;
   hora_cita: string;
   mensaje?: string;
+  especialista_id?: number;
+  tratamiento_id?: number;
 }
 
 export interface Appointment {
   id: number;
+  paciente_id: number;
   nombre_paciente: string;
   telefono: string;
   email: string;
@@ -55,6 +103,27 @@ export interface AppointmentDetails {
   created_at?: string;
 }
 
+export interface AvailableSlot {
+  fecha_cita: string;
+  hora_cita: string;
+}
+
+export interface RespondAppointmentRequest {
+  token: string;
+  accion: 'rechazar' | 'proponer';
+  motivo?: string;
+  horarios?: AvailableSlot[];
+}
+
+export interface SpecialistAvailability {
+  especialista_id: number;
+  nombre_especialista: string;
+  tratamiento_id: number;
+  tratamiento: string;
+  duracion_minutos: number;
+  horarios: AvailableSlot[];
+}
+
 export interface Service {
   id: string;
   name: string;
@@ -74,4 +143,38 @@ export interface ApiError {
 export interface ApiResponse<T> {
   data?: T;
   error?: string;
-}
\ No newline at end of file
+}
+export interface UpdateProfileData {
+  nombre?: string;
+  apellido?: string;
+  telefono?: string;
+  email?: string;
+  idioma?: Idioma;
+  password_actual?: string;
+}
+
+export type Parentesco = 'titular' | 'hijo' | 'padre' | 'madre' | 'pareja' | 'otro';
+
+export interface Paciente {
+  id: number;
+  nombre: string;
+  apellido_paterno: string;
+  apellido_materno: string;
+  email?: string;
+  telefono: string;
+  fecha_nacimiento: string;
+  sexo: 'M' | 'F' | 'O';
+  parentesco: Parentesco;
+  activo: boolean;
+}
+
+export interface PacienteData {
+  nombre: string;
+  apellido_paterno: string;
+  apellido_materno: string;
+  fecha_nacimiento: string;
+  sexo: 'M' | 'F' | 'O';
+  parentesco: Parentesco;
+  telefono: string;
+  email: string;
+}